@@ -0,0 +1,92 @@
+// 命令行导出 Beancount / hledger 日记账，读取与服务端相同的 config.yaml
+//
+//	go run ./cmd/export -user <uid> -format beancount -start 2024-01-01 -end 2024-12-31
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/calendar"
+	"github.com/leon37/FaceTaxLedger/internal/config"
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/database"
+	"github.com/leon37/FaceTaxLedger/internal/ledger"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+func main() {
+	userID := flag.String("user", "", "用户 ID (必填)")
+	format := flag.String("format", ledger.FormatBeancount, "导出格式: beancount / hledger")
+	category := flag.String("category", "", "只导出某个分类")
+	start := flag.String("start", "", "开始日期 2006-01-02")
+	end := flag.String("end", "", "结束日期 2006-01-02 (包含当天)")
+	out := flag.String("out", "", "输出文件，默认 facetax-<日期>.<扩展名>")
+	expenseRoot := flag.String("expense-root", "", "覆盖支出账户前缀，如 Expenses")
+	funding := flag.String("funding", "", "覆盖默认付款账户，如 Assets:Cash")
+	currency := flag.String("currency", "", "覆盖货币，如 CNY")
+	flag.Parse()
+
+	if *userID == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("无法加载配置: %v", err)
+	}
+	scheme := conf.Ledger.Scheme()
+	if *expenseRoot != "" {
+		scheme.ExpenseRoot = *expenseRoot
+	}
+	if *funding != "" {
+		scheme.DefaultFunding = *funding
+	}
+	if *currency != "" {
+		scheme.Currency = *currency
+	}
+
+	db := database.NewMySQLConnection(conf.Database.DSN)
+	users := service.NewUserService(repository.NewUserRepository(db), calendar.Location(conf.Server.TimeZone, nil))
+	svc := service.NewExportService(repository.NewExpenseRepo(db), scheme, users)
+
+	// 日期边界与服务端一致，按用户设置的时区计算
+	loc := svc.Location(context.Background(), *userID)
+	filter := repository.ExpenseFilter{UserID: *userID, Category: *category}
+	if *start != "" {
+		if filter.StartDate, err = time.ParseInLocation("2006-01-02", *start, loc); err != nil {
+			log.Fatalf("开始日期格式错误: %v", err)
+		}
+	}
+	if *end != "" {
+		t, err := time.ParseInLocation("2006-01-02", *end, loc)
+		if err != nil {
+			log.Fatalf("结束日期格式错误: %v", err)
+		}
+		filter.EndDate = t.AddDate(0, 0, 1) // 包含当天
+	}
+
+	// GORM 的 SQL 日志会打到 stdout，所以这里只写文件
+	if *out == "" {
+		ext := "beancount"
+		if *format == ledger.FormatHledger {
+			ext = "journal"
+		}
+		*out = fmt.Sprintf("facetax-%s.%s", time.Now().In(loc).Format("20060102"), ext)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("无法创建输出文件: %v", err)
+	}
+	defer f.Close()
+
+	if err := svc.ExportJournal(context.Background(), f, filter, *format, scheme); err != nil {
+		log.Fatalf("导出失败: %v", err)
+	}
+	log.Printf("已导出到 %s", *out)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/leon37/FaceTaxLedger/internal/api"
 	"github.com/leon37/FaceTaxLedger/internal/api/controller"
 	"github.com/leon37/FaceTaxLedger/internal/infrastructure/embedding"
@@ -9,14 +10,21 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/calendar"
 	"github.com/leon37/FaceTaxLedger/internal/config"
+	"github.com/leon37/FaceTaxLedger/internal/importer"
 	"github.com/leon37/FaceTaxLedger/internal/infrastructure/database"
 	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+	"github.com/leon37/FaceTaxLedger/internal/moderation"
+	"github.com/leon37/FaceTaxLedger/internal/notify"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
 	"github.com/leon37/FaceTaxLedger/internal/repository"
 	"github.com/leon37/FaceTaxLedger/internal/service"
+	"github.com/leon37/FaceTaxLedger/internal/sse"
 )
 
 // @title           FaceTax API
@@ -39,6 +47,11 @@ import (
 // @name Authorization
 // @description 请在输入框中输入 "Bearer <token>" (注意 Bearer 和 token 之间有空格)
 
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name X-Admin-Token
+// @description 配置项 server.admin_token 的值
+
 func main() {
 	// 1. 初始化 Logger
 	// 使用 JSONHandler 可以让日志以 JSON 格式输出，方便解析
@@ -60,7 +73,21 @@ func main() {
 	log.Println("配置加载成功")
 
 	// 2. Infra Initialization
-	llmClient := llm.NewDeepSeekClient(conf.DeepSeek.APIKey, conf.DeepSeek.BaseURL, conf.DeepSeek.Model)
+	llmClient, err := newLLMProvider(conf)
+	if err != nil {
+		log.Fatalf("Failed to init LLM provider: %v", err)
+	}
+	slog.Info("记账模型已就绪", "provider", llmClient.Name(), "toolCalls", llmClient.SupportsToolCalls())
+	var visionClient llm.VisionProvider
+	switch conf.Vision.Provider {
+	case "openai":
+		visionClient = llm.NewOpenAIVisionClient(conf.Vision.APIKey, conf.Vision.BaseURL, conf.Vision.Model)
+	case "fake":
+		// 本地联调用，不消耗 Token
+		visionClient = llm.NewFakeVisionClient()
+	default:
+		slog.Warn("未配置多模态模型，图片记账不可用", "provider", conf.Vision.Provider)
+	}
 	db := database.NewMySQLConnection(conf.Database.DSN) // 这里会自动建表
 
 	vecClient, err := vectordb.NewQdrantClient(conf.Qdrant.Host, conf.Qdrant.Port)
@@ -85,19 +112,139 @@ func main() {
 	// 3. Layer Wiring (依赖注入)
 	repo := repository.NewExpenseRepo(db)
 	memoryRepo := vectordb.NewQdrantRepository(vecClient)
-	svc := service.NewExpenseService(llmClient, embedder, repo, memoryRepo) // 注入 repo
+	userRepo := repository.NewUserRepository(db)
+	userSvc := service.NewUserService(userRepo, calendar.Location(conf.Server.TimeZone, nil))
+	auditRepo := repository.NewAnalysisAuditRepo(db)
+	// Prompt 模板：目录里的文件改动后自动重新加载
+	prompts := prompt.NewStore(conf.Prompt.Dir, conf.Prompt.Default)
+	go prompts.Watch(context.Background(), conf.Prompt.ReloadInterval)
+	promptSvc := service.NewPromptService(prompts, userRepo)
+	experimentSvc := service.NewExperimentService(repository.NewExperimentRepo(db), auditRepo, promptSvc, experimentProviders(conf))
+	roastSvc := service.NewRoastService(repository.NewRoastRepo(db), repo)
+	moderationSvc := service.NewModerationService(newModerationChecker(conf), repository.NewModerationLogRepo(db), service.ModerationConfig{
+		RegenerateAttempts: conf.Moderation.RegenerateAttempts,
+		Replacement:        conf.Moderation.Replacement,
+	})
+	svc := service.NewExpenseService(llmClient, visionClient, embedder, repo, memoryRepo, auditRepo, promptSvc, experimentSvc, roastSvc, moderationSvc, userSvc) // 注入 repo
 
 	// 4. Server Start
 	r := gin.Default()
-	expenseController := controller.NewExpenseController(svc)
+	// 流式记账在服务端作为任务运行，客户端断线后可以带 Last-Event-ID 重连补发
+	analyzeJobs := sse.NewHub(sse.Config{
+		Heartbeat:   conf.Server.SSE.Heartbeat,
+		ReplayTTL:   conf.Server.SSE.ReplayTTL,
+		ResumeGrace: conf.Server.SSE.ResumeGrace,
+	})
+	expenseController := controller.NewExpenseController(svc, analyzeJobs)
+	notificationSvc := service.NewNotificationService(notify.NewParser(), svc)
+	notificationController := controller.NewNotificationController(notificationSvc)
+	classifier, _ := llmClient.(llm.Classifier) // 不支持批量分类的后端会退化为规则 + 兜底分类
+	importSvc := service.NewImportService(repo, repository.NewImportRepo(db), memoryRepo, embedder, classifier, importer.NewCategoryMapper(), userSvc)
+	importSvc.ResumeUnfinished(context.Background()) // 继续上次重启前没跑完的导入批次
+	importController := controller.NewImportController(importSvc)
+	exportController := controller.NewExportController(service.NewExportService(repo, conf.Ledger.Scheme(), userSvc))
 
-	userRepo := repository.NewUserRepository(db)
 	authSvc := service.NewAuthService(userRepo)
 	authController := controller.NewAuthController(authSvc)
-	api.RegisterRoutes(r, authController, expenseController)
+	userController := controller.NewUserController(userSvc)
+	jobSvc := service.NewAnalysisJobService(repository.NewAnalysisJobRepo(db), svc, service.AnalysisJobConfig{
+		Workers:               conf.Jobs.Workers,
+		MaxAttempts:           conf.Jobs.MaxAttempts,
+		Backoff:               conf.Jobs.Backoff,
+		MaxBackoff:            conf.Jobs.MaxBackoff,
+		Timeout:               conf.Jobs.Timeout,
+		CallbackSecret:        conf.Jobs.CallbackSecret,
+		AllowPrivateCallbacks: conf.Jobs.AllowPrivateCallbacks,
+	})
+	jobSvc.Start(context.Background()) // 继续上次重启前没跑完的异步记账任务
+	jobController := controller.NewAnalysisJobController(jobSvc)
+	auditController := controller.NewAuditController(service.NewAuditService(auditRepo))
+	promptController := controller.NewPromptController(promptSvc)
+	experimentController := controller.NewExperimentController(experimentSvc)
+	roastController := controller.NewRoastController(roastSvc)
+	moderationController := controller.NewModerationController(moderationSvc)
+	api.RegisterRoutes(r, authController, expenseController, notificationController, importController, exportController, userController, jobController, auditController, promptController, experimentController, roastController, moderationController)
 
 	slog.Info("FaceTax Web Server 启动中", "port", conf.Server.Port)
 	if err := r.Run(conf.Server.Port); err != nil {
 		slog.Error("服务器启动失败", "error", err)
 	}
 }
+
+// newModerationChecker 吐槽的内容检查：先查屏蔽词，配置了分类器时再调用分类器
+// 分类器创建失败只告警，仍然用屏蔽词检查
+func newModerationChecker(conf *config.Config) *moderation.Checker {
+	classifiers := []moderation.Classifier{moderation.NewBlocklist(conf.Moderation.Blocklist)}
+	if typ := conf.Moderation.Classifier; typ != "" {
+		c, err := moderation.New(typ, moderation.Spec{
+			APIKey:  conf.Moderation.APIKey,
+			BaseURL: conf.Moderation.BaseURL,
+			Model:   conf.Moderation.Model,
+		})
+		if err != nil {
+			slog.Warn("内容检查分类器创建失败，只使用屏蔽词", "classifier", typ, "error", err)
+		} else {
+			classifiers = append(classifiers, c)
+		}
+	}
+	return moderation.NewChecker(classifiers...)
+}
+
+// newLLMProvider 按配置创建记账模型：
+// 配置了 llm.chain 时组成带熔断的降级链，否则按 llm.provider 选一个，都没配时兼容旧的 deepseek 配置
+func newLLMProvider(conf *config.Config) (llm.Provider, error) {
+	if len(conf.LLM.Chain) > 0 {
+		providers := make([]llm.Provider, 0, len(conf.LLM.Chain))
+		for _, name := range conf.LLM.Chain {
+			p, err := namedLLMProvider(conf, name)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, p)
+		}
+		b := conf.LLM.Breaker
+		return llm.NewFallbackProvider(llm.BreakerConfig{
+			FailureThreshold:  b.FailureThreshold,
+			Cooldown:          b.Cooldown,
+			FirstTokenTimeout: b.FirstTokenTimeout,
+		}, providers...), nil
+	}
+	if conf.LLM.Provider != "" {
+		return namedLLMProvider(conf, conf.LLM.Provider)
+	}
+	return llm.NewDeepSeekClient(conf.DeepSeek.APIKey, conf.DeepSeek.BaseURL, conf.DeepSeek.Model), nil
+}
+
+// namedLLMProvider 从 llm.providers 里按名字创建一个后端
+// "offline" 是内置的规则解析后端，无需配置即可放进 llm.chain 末尾兜底
+func namedLLMProvider(conf *config.Config, name string) (llm.Provider, error) {
+	mc, ok := conf.LLM.Providers[strings.ToLower(name)] // viper 会把 map 的键转成小写
+	if !ok && strings.EqualFold(name, "offline") {
+		return llm.NewOfflineProvider("offline"), nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("llm.providers 中没有名为 %s 的配置", name)
+	}
+	return llm.NewProvider(name, llm.ProviderSpec{
+		Type:      mc.Provider,
+		APIKey:    mc.APIKey,
+		BaseURL:   mc.BaseURL,
+		Model:     mc.Model,
+		ToolCalls: mc.ToolCalls,
+	})
+}
+
+// experimentProviders 把 llm.providers 里的每个后端单独创建一份，供 A/B 实验的分组指定
+// 创建失败的只是不能用于实验，不影响启动
+func experimentProviders(conf *config.Config) map[string]llm.Provider {
+	providers := map[string]llm.Provider{"offline": llm.NewOfflineProvider("offline")}
+	for name := range conf.LLM.Providers {
+		p, err := namedLLMProvider(conf, name)
+		if err != nil {
+			slog.Warn("模型后端无法用于实验", "provider", name, "error", err)
+			continue
+		}
+		providers[name] = p
+	}
+	return providers
+}
@@ -0,0 +1,392 @@
+package main
+
+// 验证异步记账任务：不依赖数据库和真实模型，任务表用内存实现
+// 1. 重启前处于 running 的任务重新排队并完成
+// 2. 模型第一次断流，退避后重试成功，回调收到带签名的结果
+// 3. 重试次数用完后标记失败，回调收到失败结果
+// 4. 落库后崩溃、重启后重跑的任务直接用已有账单，不重复记账
+// 5. 回调地址指向本机、内网、元数据地址时拒绝提交
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+	"gorm.io/gorm"
+)
+
+const secret = "test-secret"
+
+func main() {
+	fake := llm.NewFakeClient("fake")
+	fake.SetInterval(time.Millisecond)
+	expenses := &fakeExpenses{}
+	svc := service.NewExpenseService(fake, nil, fakeEmbedder{}, expenses, fakeMemory{}, nil, nil, nil, nil, nil, nil)
+
+	hooks := &hookServer{}
+	server := httptest.NewServer(hooks)
+	defer server.Close()
+
+	jobs := newMemoryJobs()
+	// 模拟重启前留下的 running 任务
+	jobs.Create(context.Background(), &model.AnalysisJob{
+		UserID: "test-user", Description: "午饭30", TimeZone: "Asia/Shanghai",
+		Status: model.AnalysisJobRunning, Attempts: 1, NextRunAt: time.Now(),
+	})
+	// 模拟落库后、写回状态前崩溃的任务
+	crashed := &model.AnalysisJob{
+		UserID: "test-user", Description: "奶茶15", TimeZone: "Asia/Shanghai",
+		Status: model.AnalysisJobRunning, Attempts: 1, NextRunAt: time.Now(),
+	}
+	jobs.Create(context.Background(), crashed)
+	// UTC 的 3 月 1 日傍晚已经是上海的 3 月 2 日
+	expenses.saved(&model.ExpenseEntity{
+		UserID: "test-user", Amount: 15, Category: "餐饮", AnalysisJobID: &crashed.ID,
+		CreatedAt: time.Date(2026, 3, 1, 17, 30, 0, 0, time.UTC),
+	})
+
+	jobSvc := service.NewAnalysisJobService(jobs, svc, service.AnalysisJobConfig{
+		Workers:        2,
+		MaxAttempts:    3,
+		Backoff:        50 * time.Millisecond,
+		PollInterval:   20 * time.Millisecond,
+		CallbackSecret: secret,
+		// 回调服务器在 127.0.0.1
+		AllowPrivateCallbacks: true,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobSvc.Start(ctx)
+
+	ok := true
+	ok = checkRecovered(jobs) && ok
+	ok = checkIdempotent(jobs, expenses, crashed.ID) && ok
+	ok = checkPrivateCallback(jobs, svc) && ok
+	ok = checkRetry(jobSvc, jobs, fake, hooks, server.URL) && ok
+	ok = checkExhausted(jobSvc, jobs, fake, hooks, server.URL) && ok
+	if !ok {
+		os.Exit(1)
+	}
+	fmt.Println("✅ 全部通过")
+}
+
+func checkRecovered(jobs *memoryJobs) bool {
+	fmt.Println("== 重启恢复 ==")
+	job := wait(jobs, 1)
+	if job.Status != model.AnalysisJobSucceeded || job.Attempts != 2 {
+		fmt.Printf("❌ 状态 %s，第 %d 次\n", job.Status, job.Attempts)
+		return false
+	}
+	fmt.Println("✅ running 任务重新排队并完成")
+	return true
+}
+
+func checkIdempotent(jobs *memoryJobs, expenses *fakeExpenses, id uint) bool {
+	fmt.Println("== 落库后崩溃 ==")
+	job := wait(jobs, id)
+	if job.Status != model.AnalysisJobSucceeded || job.ExpenseID == nil || *job.ExpenseID != 1 {
+		fmt.Printf("❌ 状态 %s，账单 %v\n", job.Status, job.ExpenseID)
+		return false
+	}
+	// 只有第一个任务调用了模型并落库
+	if n := expenses.created.Load(); n != 2 {
+		fmt.Printf("❌ 落库 %d 次，应为 2 次\n", n)
+		return false
+	}
+	fmt.Println("✅ 重跑时直接使用已有账单，没有重复记账")
+
+	var result service.AnalyzeResult
+	if err := json.Unmarshal(job.Result, &result); err != nil || result.ResolvedDate == nil {
+		fmt.Println("❌ 结果缺少日期判定:", string(job.Result))
+		return false
+	}
+	if result.ResolvedDate.Date != "2026-03-02" || result.ResolvedDate.TimeZone != "Asia/Shanghai" {
+		fmt.Printf("❌ 日期 %s (%s)，应为 2026-03-02 (Asia/Shanghai)\n", result.ResolvedDate.Date, result.ResolvedDate.TimeZone)
+		return false
+	}
+	fmt.Println("✅ 重跑的结果按任务时区给出日期")
+	return true
+}
+
+func checkPrivateCallback(jobs *memoryJobs, svc *service.ExpenseService) bool {
+	fmt.Println("== 内网回调地址 ==")
+	strict := service.NewAnalysisJobService(jobs, svc, service.AnalysisJobConfig{})
+	pass := true
+	for _, u := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.8/hook",
+		"http://192.168.1.1/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:10.0.0.1]/hook",
+		"http://100.64.0.1/hook",
+		"ftp://example.com/hook",
+	} {
+		if _, err := strict.Submit(context.Background(), "test-user", "午饭30", "", u); !errors.Is(err, service.ErrInvalidCallbackURL) {
+			fmt.Println("❌ 没有拒绝", u, err)
+			pass = false
+		}
+	}
+	if pass {
+		fmt.Println("✅ 本机、内网、元数据地址都被拒绝")
+	}
+	return pass
+}
+
+func checkRetry(jobSvc *service.AnalysisJobService, jobs *memoryJobs, fake *llm.FakeClient, hooks *hookServer, url string) bool {
+	fmt.Println("== 失败重试 ==")
+	fake.SetFailAfter(2)
+	submitted, err := jobSvc.Submit(context.Background(), "test-user", "打车花了50元", "Asia/Shanghai", url)
+	if err != nil {
+		fmt.Println("❌ 提交失败:", err)
+		return false
+	}
+	// 第一次失败后恢复正常，下一次重试应当成功
+	for jobs.snapshot(submitted.ID).Attempts < 1 || jobs.snapshot(submitted.ID).Status == model.AnalysisJobRunning {
+		time.Sleep(5 * time.Millisecond)
+	}
+	fake.SetFailAfter(0)
+
+	job := wait(jobs, submitted.ID)
+	pass := true
+	if job.Status != model.AnalysisJobSucceeded || job.Attempts != 2 || job.ExpenseID == nil {
+		fmt.Printf("❌ 状态 %s，第 %d 次，%s\n", job.Status, job.Attempts, job.Error)
+		pass = false
+	}
+	hook := hooks.waitFor(job.ID)
+	if hook == nil || hook.Status != model.AnalysisJobSucceeded {
+		fmt.Println("❌ 没有收到成功的回调")
+		pass = false
+	}
+	if pass {
+		fmt.Printf("✅ 第 %d 次成功，回调已签名并投递\n", job.Attempts)
+	}
+	return pass
+}
+
+func checkExhausted(jobSvc *service.AnalysisJobService, jobs *memoryJobs, fake *llm.FakeClient, hooks *hookServer, url string) bool {
+	fmt.Println("== 重试用完 ==")
+	fake.SetFailAfter(2)
+	submitted, err := jobSvc.Submit(context.Background(), "test-user", "咖啡18", "Asia/Shanghai", url)
+	if err != nil {
+		fmt.Println("❌ 提交失败:", err)
+		return false
+	}
+	job := wait(jobs, submitted.ID)
+	pass := true
+	if job.Status != model.AnalysisJobFailed || job.Attempts != 3 || job.ErrorCode != service.ReasonProviderError {
+		fmt.Printf("❌ 状态 %s，第 %d 次，原因码 %s\n", job.Status, job.Attempts, job.ErrorCode)
+		pass = false
+	}
+	hook := hooks.waitFor(job.ID)
+	if hook == nil || hook.Status != model.AnalysisJobFailed {
+		fmt.Println("❌ 没有收到失败的回调")
+		pass = false
+	}
+	if pass {
+		fmt.Printf("✅ %d 次后标记失败 (%s)，回调已投递\n", job.Attempts, job.ErrorCode)
+	}
+	return pass
+}
+
+// wait 等任务结束
+func wait(jobs *memoryJobs, id uint) model.AnalysisJob {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job := jobs.snapshot(id)
+		if job.Finished() || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// hookServer 接收回调并校验签名
+type hookServer struct {
+	mu       sync.Mutex
+	received map[uint]*model.AnalysisJob
+}
+
+func (h *hookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if r.Header.Get("X-FaceTax-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+		return
+	}
+	var job model.AnalysisJob
+	if err := json.Unmarshal(body, &job); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.mu.Lock()
+	if h.received == nil {
+		h.received = make(map[uint]*model.AnalysisJob)
+	}
+	h.received[job.ID] = &job
+	h.mu.Unlock()
+}
+
+func (h *hookServer) waitFor(id uint) *model.AnalysisJob {
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		h.mu.Lock()
+		job := h.received[id]
+		h.mu.Unlock()
+		if job != nil {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// memoryJobs 内存版任务表
+type memoryJobs struct {
+	mu     sync.Mutex
+	nextID uint
+	jobs   map[uint]model.AnalysisJob
+}
+
+func newMemoryJobs() *memoryJobs {
+	return &memoryJobs{jobs: make(map[uint]model.AnalysisJob)}
+}
+
+func (m *memoryJobs) snapshot(id uint) model.AnalysisJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs[id]
+}
+
+func (m *memoryJobs) Create(ctx context.Context, job *model.AnalysisJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	job.ID = m.nextID
+	m.jobs[job.ID] = *job
+	return nil
+}
+
+func (m *memoryJobs) Get(ctx context.Context, id uint) (*model.AnalysisJob, error) {
+	job := m.snapshot(id)
+	return &job, nil
+}
+
+func (m *memoryJobs) ClaimNext(ctx context.Context, now time.Time) (*model.AnalysisJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []model.AnalysisJob
+	for _, job := range m.jobs {
+		if job.Status == model.AnalysisJobPending && !job.NextRunAt.After(now) {
+			due = append(due, job)
+		}
+	}
+	if len(due) == 0 {
+		return nil, repository.ErrNoJobDue
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextRunAt.Before(due[j].NextRunAt) })
+	job := due[0]
+	job.Status = model.AnalysisJobRunning
+	job.Attempts++
+	m.jobs[job.ID] = job
+	return &job, nil
+}
+
+func (m *memoryJobs) UpdateProgress(ctx context.Context, job *model.AnalysisJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = *job
+	return nil
+}
+
+func (m *memoryJobs) RequeueRunning(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, job := range m.jobs {
+		if job.Status == model.AnalysisJobRunning {
+			job.Status = model.AnalysisJobPending
+			m.jobs[id] = job
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryJobs) ListUndelivered(ctx context.Context) ([]model.AnalysisJob, error) {
+	return nil, nil
+}
+
+// fakeEmbedder 固定返回一个短向量
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) GetVector(ctx context.Context, text string) ([]float32, error) {
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func (fakeEmbedder) GetVectors(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{0.1, 0.2, 0.3}
+	}
+	return vectors, nil
+}
+
+// fakeMemory 没有任何历史
+type fakeMemory struct{}
+
+func (fakeMemory) SaveMemory(ctx context.Context, uuid string, expenseID uint, description string, category string, vector []float32) error {
+	return nil
+}
+func (fakeMemory) SaveMemories(ctx context.Context, uuid string, items []repository.MemoryItem) error {
+	return nil
+}
+func (fakeMemory) SearchSimilar(ctx context.Context, uuid string, limit int, queryVector []float32) ([]repository.MemoryResult, error) {
+	return nil, nil
+}
+func (fakeMemory) Delete(ctx context.Context, id int64) error        { return nil }
+func (fakeMemory) DeleteMany(ctx context.Context, ids []int64) error { return nil }
+
+// fakeExpenses 记录落库次数和每个任务生成的账单
+type fakeExpenses struct {
+	repository.ExpenseRepo
+	created atomic.Int64
+	byJob   sync.Map
+}
+
+func (r *fakeExpenses) Create(ctx context.Context, expense *model.ExpenseEntity) error {
+	r.saved(expense)
+	return nil
+}
+
+func (r *fakeExpenses) saved(expense *model.ExpenseEntity) {
+	expense.ID = uint(r.created.Add(1))
+	if expense.AnalysisJobID != nil {
+		r.byJob.Store(*expense.AnalysisJobID, expense)
+	}
+}
+
+func (r *fakeExpenses) GetByAnalysisJob(ctx context.Context, jobID uint) (*model.ExpenseEntity, error) {
+	if expense, ok := r.byJob.Load(jobID); ok {
+		return expense.(*model.ExpenseEntity), nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/leon37/FaceTaxLedger/internal/config"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+	"github.com/leon37/FaceTaxLedger/internal/model"
 	// 记得替换 import 路径为你的实际项目路径
 )
 
@@ -23,7 +26,7 @@ func main() {
 	if apiKey == "" {
 		log.Fatal("请设置环境变量 DEEPSEEK_API_KEY")
 	}
-	llmClient := llm.NewDeepSeekClient(conf.DeepSeek.APIKey, conf.DeepSeek.BaseURL, conf.DeepSeek.Model)
+	llmClient := llm.NewDeepSeekClient(apiKey, conf.DeepSeek.BaseURL, conf.DeepSeek.Model)
 
 	// 2. 准备测试数据
 	ctx := context.Background()
@@ -65,13 +68,26 @@ func main() {
 		fmt.Printf("输入: %s\n", tc.Input)
 
 		start := time.Now()
-		result, err := llmClient.AnalyzeExpense(ctx, tc.Input, categories, historyLogs, tc.EnableRoast)
-		duration := time.Since(start)
-
+		stream, err := llmClient.AnalyzeExpense(ctx, tc.Input, categories, historyLogs, tc.EnableRoast)
 		if err != nil {
 			log.Printf("❌ 调用失败: %v\n", err)
 			continue
 		}
+		// 读完整个流再解析，中途断流时 Err 不为空
+		var fullJSON strings.Builder
+		for fragment := range stream.C {
+			fullJSON.WriteString(fragment)
+		}
+		duration := time.Since(start)
+		if err := stream.Err(); err != nil {
+			log.Printf("❌ 输出中断: %v\n", err)
+			continue
+		}
+		var result model.FaceTaxAnalysis
+		if err := json.Unmarshal([]byte(fullJSON.String()), &result); err != nil {
+			log.Printf("❌ 输出无法解析: %v\n原文: %s\n", err, fullJSON.String())
+			continue
+		}
 
 		fmt.Printf("✅ 调用成功 (耗时 %v)\n", duration)
 		fmt.Printf("提取金额: %.2f\n", result.Amount)
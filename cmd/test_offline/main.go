@@ -0,0 +1,55 @@
+package main
+
+// 验证离线规则解析器的金额识别：不依赖任何模型，逐条对比期望的金额
+// 覆盖 角的写法、阿拉伯数字带 万/千/百、数量和单价、名字里的数字、计量单位
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/offline"
+)
+
+var cases = []struct {
+	text   string
+	amount float64
+}{
+	{"午饭35块5", 35.5},
+	{"打车三十五块五", 35.5},
+	{"装修花了1万", 10000},
+	{"手机2千", 2000},
+	{"学费1.5万", 15000},
+	{"房租3千块", 3000},
+	{"请客吃饭5百元", 500},
+	{"19.9元3件", 19.9},
+	{"奶茶15元2杯", 15},
+	{"2杯咖啡各20", 40},
+	{"7-11买水 5", 5},
+	{"买了3千克苹果 花了20", 20},
+}
+
+func main() {
+	parser := offline.NewParser()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+	ok := true
+	for _, c := range cases {
+		result, err := parser.Parse(c.text, now, model.PredefinedCategories)
+		switch {
+		case err != nil:
+			fmt.Printf("❌ %s: %v\n", c.text, err)
+			ok = false
+		case math.Abs(result.Amount-c.amount) >= 0.005:
+			fmt.Printf("❌ %s: 识别为 %.2f，应为 %.2f\n", c.text, result.Amount, c.amount)
+			ok = false
+		default:
+			fmt.Printf("✅ %s -> %.2f\n", c.text, result.Amount)
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+	fmt.Println("✅ 全部通过")
+}
@@ -38,6 +38,8 @@ func main() {
 	scanner := bufio.NewScanner(resp.Body)
 
 	var fullBuffer strings.Builder
+	var comment strings.Builder
+	event := ""
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -50,19 +52,26 @@ func main() {
 		fmt.Printf("[收到原始数据] %s\n", line)
 
 		// 3. 解析 SSE 协议 (格式通常是 "event: xxx" 或 "data: xxx")
-		if strings.HasPrefix(line, "event: delta") {
-			// 下一行通常是 data
+		if strings.HasPrefix(line, "event:") {
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			if event == "done" {
+				fmt.Println("\n🏁 流传输结束 (Done Signal)")
+			}
 			continue
 		}
 
 		if strings.HasPrefix(line, "data:") {
-			content := strings.TrimPrefix(line, "data: ")
-			fmt.Printf("   └──> 解析内容: %s\n", content)
-			fullBuffer.WriteString(content)
-		}
-
-		if strings.HasPrefix(line, "event: done") {
-			fmt.Println("\n🏁 流传输结束 (Done Signal)")
+			content := strings.TrimPrefix(line, "data:")
+			switch {
+			case event == "delta":
+				fmt.Printf("   └──> 解析内容: %s\n", content)
+				fullBuffer.WriteString(content)
+			case event == "field:comment":
+				// 吐槽经过内容检查后推送一次
+				comment.WriteString(content)
+			case strings.HasPrefix(event, "field:"):
+				fmt.Printf("   └──> 字段 %s = %s\n", strings.TrimPrefix(event, "field:"), content)
+			}
 		}
 	}
 
@@ -72,4 +81,5 @@ func main() {
 
 	fmt.Println("--------------------------------")
 	fmt.Println("📝 最终拼接结果:", fullBuffer.String())
+	fmt.Println("💬 吐槽:", comment.String())
 }
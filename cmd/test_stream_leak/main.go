@@ -0,0 +1,508 @@
+package main
+
+// 验证记账 SSE 的流生命周期：不依赖数据库、向量库和真实模型，全部用假实现
+// 1. 客户端中途断开：模型那边的 goroutine 全部退出，不落库
+// 2. 模型中途断流：推送 error 事件 (provider_error)，不落库
+// 3. 正常输出：推送 done，落库一次
+// 4. 断线重连：带 Last-Event-ID 重连后从断点继续推送，期间有心跳，落库一次
+// 5. 同步接口：正常时返回保存后的账单；模型断流时返回 502 且不落库
+// 6. 真实的 OpenAI 兼容客户端 + 降级链，上游是本地的假接口：客户端断开后上游连接关闭，
+//    openai_compat 和 fallback 的生产 goroutine 都退出，goroutine 数回到断开前
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/controller"
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+	"github.com/leon37/FaceTaxLedger/internal/sse"
+)
+
+// fakeEmbedder 固定返回一个短向量
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) GetVector(ctx context.Context, text string) ([]float32, error) {
+	return []float32{0.1, 0.2, 0.3}, nil
+}
+
+func (fakeEmbedder) GetVectors(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{0.1, 0.2, 0.3}
+	}
+	return vectors, nil
+}
+
+// fakeMemory 没有任何历史
+type fakeMemory struct{}
+
+func (fakeMemory) SaveMemory(ctx context.Context, uuid string, expenseID uint, description string, category string, vector []float32) error {
+	return nil
+}
+func (fakeMemory) SaveMemories(ctx context.Context, uuid string, items []repository.MemoryItem) error {
+	return nil
+}
+func (fakeMemory) SearchSimilar(ctx context.Context, uuid string, limit int, queryVector []float32) ([]repository.MemoryResult, error) {
+	return nil, nil
+}
+func (fakeMemory) Delete(ctx context.Context, id int64) error        { return nil }
+func (fakeMemory) DeleteMany(ctx context.Context, ids []int64) error { return nil }
+
+// fakeExpenses 只记录落库次数，其余方法用不到
+type fakeExpenses struct {
+	repository.ExpenseRepo
+	created atomic.Int64
+}
+
+func (r *fakeExpenses) Create(ctx context.Context, expense *model.ExpenseEntity) error {
+	expense.ID = uint(r.created.Add(1))
+	return nil
+}
+
+func main() {
+	gin.SetMode(gin.ReleaseMode)
+
+	fake := llm.NewFakeClient("fake")
+	repo := &fakeExpenses{}
+	// 请求里显式带时区，不会去查用户资料，UserService 传 nil
+	svc := service.NewExpenseService(fake, nil, fakeEmbedder{}, repo, fakeMemory{}, nil, nil, nil, nil, nil, nil)
+	// 缩短等待重连的时间，断开场景不用等 30s
+	jobs := sse.NewHub(sse.Config{Heartbeat: 20 * time.Millisecond, ResumeGrace: 300 * time.Millisecond})
+	ctrl := controller.NewExpenseController(svc, jobs)
+
+	r := gin.New()
+	r.POST("/analyze", func(c *gin.Context) {
+		c.Set("userID", "test-user")
+		ctrl.Analyze(c)
+	})
+	r.POST("/analyze/sync", func(c *gin.Context) {
+		c.Set("userID", "test-user")
+		ctrl.AnalyzeSync(c)
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// 同样的接口，模型换成指向假上游的 OpenAI 兼容客户端，外面包一层降级链
+	up := &upstream{}
+	upstreamServer := httptest.NewServer(up)
+	defer upstreamServer.Close()
+	compat := llm.NewOpenAICompatibleClient("upstream", "test-key", upstreamServer.URL+"/v1", "test-model", true)
+	realSvc := service.NewExpenseService(llm.NewFallbackProvider(llm.BreakerConfig{}, compat), nil, fakeEmbedder{}, repo, fakeMemory{}, nil, nil, nil, nil, nil, nil)
+	realCtrl := controller.NewExpenseController(realSvc, jobs)
+	upstreamRoutes := gin.New()
+	upstreamRoutes.POST("/analyze", func(c *gin.Context) {
+		c.Set("userID", "test-user")
+		realCtrl.Analyze(c)
+	})
+	realServer := httptest.NewServer(upstreamRoutes)
+	defer realServer.Close()
+
+	ok := true
+	ok = checkDisconnect(server.URL, fake, repo) && ok
+	ok = checkProviderError(server.URL, fake, repo) && ok
+	ok = checkDone(server.URL, fake, repo) && ok
+	ok = checkResume(server.URL, fake, repo) && ok
+	ok = checkSync(server.URL, fake, repo) && ok
+	ok = checkUpstreamDisconnect(realServer.URL, up, repo) && ok
+	if !ok {
+		os.Exit(1)
+	}
+	fmt.Println("✅ 全部通过")
+}
+
+// checkDisconnect 收到第一个片段后断开，等待服务端收尾
+func checkDisconnect(url string, fake *llm.FakeClient, repo *fakeExpenses) bool {
+	fmt.Println("== 客户端中途断开 ==")
+	fake.SetFailAfter(0)
+	fake.SetInterval(50 * time.Millisecond)
+	created := repo.created.Load()
+	baseline := runtime.NumGoroutine()
+
+	const clients = 20
+	for i := 0; i < clients; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		resp, err := post(ctx, url, "")
+		if err != nil {
+			cancel()
+			fmt.Println("❌ 请求失败:", err)
+			return false
+		}
+		if _, err := firstEventID(resp); err != nil {
+			fmt.Println("❌ 没有收到任何片段:", err)
+		}
+		cancel()
+		resp.Body.Close()
+	}
+
+	// 等待重连超时后任务取消，生产端在下一次发送或等待时发现 ctx 取消后退出
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) && (fake.Active() > 0 || runtime.NumGoroutine() > baseline+2) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	pass := true
+	if n := fake.Active(); n != 0 {
+		fmt.Printf("❌ 仍有 %d 个模型 goroutine 在输出\n", n)
+		pass = false
+	}
+	// 留一点余量给 http 客户端的空闲连接
+	if n := runtime.NumGoroutine(); n > baseline+2 {
+		fmt.Printf("❌ goroutine 数 %d，断开前 %d\n", n, baseline)
+		pass = false
+	}
+	if repo.created.Load() != created {
+		fmt.Println("❌ 断开的请求被落库了")
+		pass = false
+	}
+	if pass {
+		fmt.Printf("✅ %d 个请求断开后 goroutine 全部退出\n", clients)
+	}
+	return pass
+}
+
+// checkProviderError 模型输出几个片段后断流
+func checkProviderError(url string, fake *llm.FakeClient, repo *fakeExpenses) bool {
+	fmt.Println("== 模型中途断流 ==")
+	fake.SetFailAfter(3)
+	fake.SetInterval(5 * time.Millisecond)
+	created := repo.created.Load()
+
+	events, err := collect(post(context.Background(), url, ""))
+	if err != nil {
+		fmt.Println("❌ 请求失败:", err)
+		return false
+	}
+	pass := true
+	if data, found := events["error"]; !found || !strings.Contains(data, service.ReasonProviderError) {
+		fmt.Printf("❌ 没有收到 provider_error，error 事件: %q\n", data)
+		pass = false
+	}
+	if _, found := events["done"]; found {
+		fmt.Println("❌ 断流后不应该推送 done")
+		pass = false
+	}
+	if repo.created.Load() != created {
+		fmt.Println("❌ 截断的输出被落库了")
+		pass = false
+	}
+	if pass {
+		fmt.Println("✅ 推送 error 事件且没有落库:", events["error"])
+	}
+	return pass
+}
+
+// checkDone 正常输出完
+func checkDone(url string, fake *llm.FakeClient, repo *fakeExpenses) bool {
+	fmt.Println("== 正常输出 ==")
+	fake.SetFailAfter(0)
+	fake.SetInterval(5 * time.Millisecond)
+	created := repo.created.Load()
+
+	events, err := collect(post(context.Background(), url, ""))
+	if err != nil {
+		fmt.Println("❌ 请求失败:", err)
+		return false
+	}
+	pass := true
+	if _, found := events["done"]; !found {
+		fmt.Printf("❌ 没有收到 done，error 事件: %q\n", events["error"])
+		pass = false
+	}
+	if n := repo.created.Load() - created; n != 1 {
+		fmt.Printf("❌ 落库 %d 次，应为 1 次\n", n)
+		pass = false
+	}
+	if pass {
+		fmt.Println("✅ 推送 done 并落库一次")
+	}
+	return pass
+}
+
+// checkResume 收到第一个事件后断开，再带 Last-Event-ID 重连
+func checkResume(url string, fake *llm.FakeClient, repo *fakeExpenses) bool {
+	fmt.Println("== 断线重连 ==")
+	fake.SetFailAfter(0)
+	fake.SetInterval(50 * time.Millisecond)
+	created := repo.created.Load()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := post(ctx, url, "")
+	if err != nil {
+		cancel()
+		fmt.Println("❌ 请求失败:", err)
+		return false
+	}
+	lastID, err := firstEventID(resp)
+	cancel()
+	resp.Body.Close()
+	if err != nil {
+		fmt.Println("❌ 没有收到任何事件:", err)
+		return false
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	events, err := collect(post(context.Background(), url, lastID))
+	if err != nil {
+		fmt.Println("❌ 重连失败:", err)
+		return false
+	}
+	pass := true
+	if events["first_id"] == lastID || events["first_id"] == "" {
+		fmt.Printf("❌ 重连后第一个事件 %q，断开前最后一个 %q\n", events["first_id"], lastID)
+		pass = false
+	}
+	if _, found := events["done"]; !found {
+		fmt.Printf("❌ 重连后没有收到 done，error 事件: %q\n", events["error"])
+		pass = false
+	}
+	if _, found := events["heartbeat"]; !found {
+		fmt.Println("❌ 没有收到心跳")
+		pass = false
+	}
+	if n := repo.created.Load() - created; n != 1 {
+		fmt.Printf("❌ 落库 %d 次，应为 1 次\n", n)
+		pass = false
+	}
+	if pass {
+		fmt.Printf("✅ 从 %s 之后继续推送 (%s)，收到 done 并落库一次\n", lastID, events["first_id"])
+	}
+	return pass
+}
+
+// checkSync 同步接口
+func checkSync(url string, fake *llm.FakeClient, repo *fakeExpenses) bool {
+	fmt.Println("== 同步接口 ==")
+	fake.SetInterval(5 * time.Millisecond)
+	pass := true
+	for _, failAfter := range []int{0, 3} {
+		fake.SetFailAfter(failAfter)
+		created := repo.created.Load()
+
+		body := strings.NewReader(`{"description":"打车花了50元","timezone":"Asia/Shanghai"}`)
+		resp, err := http.Post(url+"/analyze/sync", "application/json", body)
+		if err != nil {
+			fmt.Println("❌ 请求失败:", err)
+			return false
+		}
+		var result struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+			Data struct {
+				Expense *model.ExpenseEntity `json:"expense"`
+				Code    string               `json:"code"`
+			} `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			fmt.Println("❌ 响应不是 JSON:", err)
+			return false
+		}
+
+		n := repo.created.Load() - created
+		switch {
+		case failAfter == 0 && (resp.StatusCode != http.StatusOK || result.Data.Expense == nil || n != 1):
+			fmt.Printf("❌ 正常输出: HTTP %d，落库 %d 次，%s\n", resp.StatusCode, n, result.Msg)
+			pass = false
+		case failAfter > 0 && (resp.StatusCode != http.StatusBadGateway || result.Data.Code != service.ReasonProviderError || n != 0):
+			fmt.Printf("❌ 模型断流: HTTP %d，原因码 %q，落库 %d 次\n", resp.StatusCode, result.Data.Code, n)
+			pass = false
+		}
+	}
+	if pass {
+		fmt.Println("✅ 正常时返回账单，断流时返回 502 且没有落库")
+	}
+	return pass
+}
+
+// checkUpstreamDisconnect 经过 fallback + openai_compat 的请求中途断开
+func checkUpstreamDisconnect(url string, up *upstream, repo *fakeExpenses) bool {
+	fmt.Println("== 真实客户端中途断开 ==")
+	created := repo.created.Load()
+	// 之前场景的空闲连接不算泄漏
+	http.DefaultClient.CloseIdleConnections()
+	baseline := settledGoroutines()
+
+	const clients = 10
+	for i := 0; i < clients; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		resp, err := post(ctx, url, "")
+		if err != nil {
+			cancel()
+			fmt.Println("❌ 请求失败:", err)
+			return false
+		}
+		if _, err := firstEventID(resp); err != nil {
+			fmt.Println("❌ 没有收到任何片段:", err)
+		}
+		cancel()
+		resp.Body.Close()
+	}
+	if n := up.served.Load(); n != clients {
+		fmt.Printf("❌ 上游收到 %d 个请求，应为 %d 个\n", n, clients)
+		return false
+	}
+
+	// 等任务超过重连等待时间被取消，上游连接关闭，生产 goroutine 退出
+	http.DefaultClient.CloseIdleConnections()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && (up.active.Load() > 0 || producers() > 0 || runtime.NumGoroutine() > baseline+2) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	pass := true
+	if n := up.active.Load(); n != 0 {
+		fmt.Printf("❌ 仍有 %d 个上游连接没有关闭\n", n)
+		pass = false
+	}
+	if n := producers(); n != 0 {
+		fmt.Printf("❌ 仍有 %d 个 openai_compat / fallback 的生产 goroutine\n", n)
+		pass = false
+	}
+	if n := runtime.NumGoroutine(); n > baseline+2 {
+		fmt.Printf("❌ goroutine 数 %d，断开前 %d\n", n, baseline)
+		pass = false
+	}
+	if repo.created.Load() != created {
+		fmt.Println("❌ 断开的请求被落库了")
+		pass = false
+	}
+	if pass {
+		fmt.Printf("✅ %d 个请求断开后上游连接关闭，生产 goroutine 全部退出 (goroutine %d，断开前 %d)\n", clients, runtime.NumGoroutine(), baseline)
+	}
+	return pass
+}
+
+// settledGoroutines 等 goroutine 数稳定下来再取基线，避免把上一个场景的收尾算进去
+func settledGoroutines() int {
+	last := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		time.Sleep(20 * time.Millisecond)
+		n := runtime.NumGoroutine()
+		if n == last {
+			return n
+		}
+		last = n
+	}
+	return last
+}
+
+// producers 统计还在运行的模型生产 goroutine：openai_compat 读上游的循环和 fallback 的转发循环
+func producers() int {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	n := 0
+	for _, g := range strings.Split(string(buf), "\n\n") {
+		if strings.Contains(g, "(*OpenAICompatibleClient).AnalyzeExpense.func") || strings.Contains(g, "(*FallbackProvider).attempt.func") {
+			n++
+		}
+	}
+	return n
+}
+
+// upstream 假的 OpenAI 兼容接口：每 20ms 推送一段工具参数，一直推到客户端断开
+type upstream struct {
+	served atomic.Int64 // 收到的请求数
+	active atomic.Int64 // 还没结束的请求数
+}
+
+func (u *upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.served.Add(1)
+	u.active.Add(1)
+	defer u.active.Add(-1)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher := w.(http.Flusher)
+	fragments := []string{`{"amount":`, `50,"category":`, `"交通出行","date":`, `"2026-10-19","note":`, `"打车","comment":"`}
+	for i := 0; i < 500; i++ {
+		fragment := "哈"
+		if i < len(fragments) {
+			fragment = fragments[i]
+		}
+		chunk := `{"id":"chatcmpl-test","object":"chat.completion.chunk","created":0,"model":"test-model","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_test","type":"function","function":{"name":"book_expense","arguments":` + strconv.Quote(fragment) + `}}]}}]}`
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", chunk); err != nil {
+			return
+		}
+		flusher.Flush()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// post 发起记账请求，lastEventID 不为空时是断线重连
+func post(ctx context.Context, url string, lastEventID string) (*http.Response, error) {
+	body := strings.NewReader(`{"description":"打车花了50元","timezone":"Asia/Shanghai"}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/analyze", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// firstEventID 读到第一个事件的 id 为止
+func firstEventID(resp *http.Response) (string, error) {
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if id, ok := strings.CutPrefix(scanner.Text(), "id:"); ok {
+			return id, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("流已结束")
+}
+
+// collect 读完整个流，返回每种事件最后一次的 data
+// first_id 记录第一个事件的 id，heartbeat 记录是否收到过心跳
+func collect(resp *http.Response, err error) (map[string]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	events := make(map[string]string)
+	event := ""
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, ":"):
+			events["heartbeat"] = line
+		case strings.HasPrefix(line, "id:"):
+			if _, found := events["first_id"]; !found {
+				events["first_id"] = strings.TrimPrefix(line, "id:")
+			}
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			events[event] = strings.TrimPrefix(line, "data:")
+		}
+	}
+	return events, scanner.Err()
+}
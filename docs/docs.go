@@ -24,33 +24,62 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/login": {
-            "post": {
-                "description": "校验账号密码，颁发 JWT Token",
-                "consumes": [
-                    "application/json"
+        "/admin/audits": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "每次模型识别都会留一条记录：原始描述、模型后端和模型名、Prompt 版本、放进 Prompt 的历史消费、模型原始输出、耗时和 token 用量。\nexpense_id 为 0 的记录是没有落库的识别 (预览后没有确认，或输出解析失败、没有通过校验)。最新的在前。\ninjection 为描述命中的提示词注入特征 (ignore_instructions / role_override / prompt_leak / fake_tag / field_override)，injected=true 只看这类记录。",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "Admin"
                 ],
-                "summary": "用户登录",
+                "summary": "模型识别审计记录",
                 "parameters": [
                     {
-                        "description": "登录参数",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.LoginRequest"
-                        }
+                        "type": "string",
+                        "description": "用户 ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "账单 ID",
+                        "name": "expense_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "来源",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "只看疑似提示词注入",
+                        "name": "injected",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数",
+                        "name": "page_size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "包含 Token 和 UserID",
+                        "description": "OK",
                         "schema": {
                             "allOf": [
                                 {
@@ -60,7 +89,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/controller.LoginResponse"
+                                            "$ref": "#/definitions/controller.AuditListResponse"
                                         }
                                     }
                                 }
@@ -70,54 +99,96 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "创建新用户，密码加密存储",
-                "consumes": [
-                    "application/json"
+        "/admin/audits/detail": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "Admin"
                 ],
-                "summary": "用户注册",
+                "summary": "模型识别审计记录详情",
                 "parameters": [
                     {
-                        "description": "注册参数",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.RegisterRequest"
-                        }
+                        "type": "integer",
+                        "description": "记录 ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Code=0 成功",
-                        "schema": {
-                            "$ref": "#/definitions/response.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "参数错误",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.AnalysisAudit"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
                 }
             }
         },
-        "/expenses": {
+        "/admin/experiments": {
             "get": {
                 "security": [
                     {
-                        "BearerAuth": []
+                        "AdminToken": []
                     }
                 ],
-                "description": "按条件筛选账单",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "A/B 实验列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/model.Experiment"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "每个分组指定一个 Prompt 版本和/或 llm.providers 里的一个模型后端，留空的沿用平时的配置 (全部留空即对照组)。\n用户按 ID 哈希稳定分组，weight 是分流权重。管理员单独指定了 Prompt 版本的用户和图片记账不参与实验。创建后需要调用 start 才开始分流。",
                 "consumes": [
                     "application/json"
                 ],
@@ -125,23 +196,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Expense"
+                    "Admin"
                 ],
-                "summary": "获取记账列表",
+                "summary": "创建 A/B 实验",
                 "parameters": [
                     {
-                        "description": "筛选条件",
+                        "description": "实验配置",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controller.ListRequest"
+                            "$ref": "#/definitions/service.ExperimentInput"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "注意：这里加上了 controller. 前缀",
+                        "description": "OK",
                         "schema": {
                             "allOf": [
                                 {
@@ -151,7 +222,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/controller.ListResponse"
+                                            "$ref": "#/definitions/model.Experiment"
                                         }
                                     }
                                 }
@@ -161,33 +232,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/expenses/analyze": {
-            "post": {
+        "/admin/experiments/report": {
+            "get": {
                 "security": [
                     {
-                        "BearerAuth": []
+                        "AdminToken": []
                     }
                 ],
-                "description": "AI 自动提取金额、分类并生成吐槽。",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "按分组对比：模型调用次数、落库的账单数、其中被用户修改过分类/金额/备注的比例 (correction_rate)、吐槽被赞/被踩的条数、平均耗时和 token 用量。\n只统计第一次记账时的识别 (文字记账、批量记账、支付通知)，重新分析和吐槽重新生成的调用不计入。",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Expense"
+                    "Admin"
                 ],
-                "summary": "自然语言记账",
+                "summary": "A/B 实验报表",
                 "parameters": [
                     {
-                        "description": "记账内容",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.ExpenseAnalyzeRequest"
-                        }
+                        "type": "integer",
+                        "description": "实验 ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -202,7 +268,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/controller.ExpenseAnalyzeResponse"
+                                            "$ref": "#/definitions/service.ExperimentReport"
                                         }
                                     }
                                 }
@@ -212,14 +278,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/expenses/delete": {
+        "/admin/experiments/start": {
             "post": {
                 "security": [
                     {
-                        "BearerAuth": []
+                        "AdminToken": []
                     }
                 ],
-                "description": "删除已存在的账单信息，仅限本人操作",
+                "description": "同一时间只有一个实验在进行，其他进行中的实验会被停止。",
                 "consumes": [
                     "application/json"
                 ],
@@ -227,23 +293,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Expense"
+                    "Admin"
                 ],
-                "summary": "删除账本条目",
+                "summary": "开始 A/B 实验",
                 "parameters": [
                     {
-                        "description": "删除参数",
+                        "description": "实验 ID",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controller.DeleteRequest"
+                            "$ref": "#/definitions/controller.ExperimentIDRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功",
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
@@ -251,14 +317,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/expenses/update": {
+        "/admin/experiments/stop": {
             "post": {
                 "security": [
                     {
-                        "BearerAuth": []
+                        "AdminToken": []
                     }
                 ],
-                "description": "修改已存在的账单信息，仅限本人操作",
+                "description": "停止后的记账回到平时的配置，已有的数据保留在报表里。",
                 "consumes": [
                     "application/json"
                 ],
@@ -266,168 +332,3099 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Expense"
+                    "Admin"
                 ],
-                "summary": "更新账本条目",
+                "summary": "停止 A/B 实验",
                 "parameters": [
                     {
-                        "description": "更新参数",
+                        "description": "实验 ID",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controller.UpdateRequest"
+                            "$ref": "#/definitions/controller.ExperimentIDRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功",
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controller.DeleteRequest": {
-            "type": "object",
-            "required": [
-                "id"
-            ],
-            "properties": {
-                "id": {
-                    "type": "integer"
-                }
-            }
         },
-        "controller.ExpenseAnalyzeRequest": {
-            "type": "object",
-            "required": [
-                "description"
-            ],
-            "properties": {
-                "description": {
-                    "type": "string"
+        "/admin/moderation/logs": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "模型生成的吐槽落库前会经过屏蔽词和 (配置了的话) 外部分类器检查，没通过的先重新生成，仍没通过就换成兜底文案。\n每条没通过的吐槽一条记录：命中的类别和原因、给出结论的检查器、处理方式 (regenerate / replace) 和最终写进账单的吐槽。\n前端、账单和 done 事件拿到的都是检查后的吐槽，SSE 记账也不会提前推送原始吐槽。expense_id 为 0 的记录是批量预览或没有落库的识别。最新的在前。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "吐槽内容检查日志",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "用户 ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "类别",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.ModerationLogListResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
                 }
             }
         },
-        "controller.ExpenseAnalyzeResponse": {
-            "type": "object",
-            "properties": {
-                "amount": {
-                    "type": "number"
-                },
-                "category": {
-                    "type": "string"
-                },
-                "comment": {
-                    "type": "string"
-                },
-                "date": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "note": {
-                    "type": "string"
+        "/admin/prompts": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "代码内置的版本 (builtin-N) 总是可用；prompt.dir 目录里的 .tmpl 文件各是一个版本，文件改动后几秒内自动生效，不需要重启。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Prompt 模板版本列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.PromptVersions"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
                 }
             }
         },
-        "controller.ListRequest": {
-            "type": "object",
-            "properties": {
-                "category": {
-                    "type": "string"
+        "/admin/prompts/assign": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "之后这个用户的记账都使用指定的版本；version 为空时恢复为默认版本。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "给用户指定 Prompt 版本",
+                "parameters": [
+                    {
+                        "description": "用户和版本",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.AssignPromptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/prompts/detail": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "新版本可以复制内置版本的原文改写。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Prompt 模板原文",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "版本号",
+                        "name": "version",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.PromptDetailResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/roasts/stats": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "按人设和 Prompt 版本汇总吐槽条数、被赞和被踩的条数，like_rate = 赞 / (赞 + 踩)。persona 为空的是功能上线前的吐槽。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "吐槽反馈统计",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/repository.RoastStats"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "校验账号密码，颁发 JWT Token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "用户登录",
+                "parameters": [
+                    {
+                        "description": "登录参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "包含 Token 和 UserID",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.LoginResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "创建新用户，密码加密存储",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "用户注册",
+                "parameters": [
+                    {
+                        "description": "注册参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Code=0 成功",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "按条件筛选账单",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "获取记账列表",
+                "parameters": [
+                    {
+                        "description": "筛选条件",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "注意：这里加上了 controller. 前缀",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.ListResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/analyze": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "AI 自动提取金额、分类并生成吐槽。\nSSE 事件：field:amount、field:category、field:date、field:note 在对应字段的值确定后推送一次，data 为字段值。\n吐槽落库前要经过内容检查，没通过时会被重新生成或替换，所以 field:comment 在检查之后、done 之前推送一次，data 为最终的吐槽；不吐槽时没有这个事件。\ndelta 为完整的工具参数 JSON (兼容自己解析 JSON 的旧客户端)，同样在检查之后推送一次，其中的 comment 是检查后的吐槽。\n失败时推送 error 事件，data 为 {\"code\": \"...\", \"message\": \"...\"}，code 取值：timeout / canceled / provider_unavailable / provider_error / invalid_output / save_failed。出现 error 事件时账单没有保存。\ndone 事件中的 resolved_date 说明消费日期的来源：text 为描述里的日期表达 (如 \"昨天\"、\"除夕\")，llm 为模型推断，default 为当天。\n分析在服务端作为任务运行，响应头 X-Job-ID 为任务 ID，每个事件带 id (任务ID:序号)，空闲时定期推送 \": heartbeat\" 注释。\n断线后带 Last-Event-ID 请求头重新请求本接口 (或 GET /expenses/analyze/resume) 即可补发错过的事件和最终结果，此时不需要请求体；还没收到任何事件时用 \"任务ID:0\"。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "自然语言记账",
+                "parameters": [
+                    {
+                        "description": "记账内容",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.ExpenseAnalyzeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "断线重连时最后收到的事件 id",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.ExpenseAnalyzeResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/analyze/resume": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "补发 Last-Event-ID 之后的事件；任务还在运行时继续推送直到结束。任务结束后事件保留几分钟，过期返回 404。",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "记账流断线重连",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "最后收到的事件 id (任务ID:序号)",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "同 Last-Event-ID，不方便设置请求头时使用",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "与 /expenses/analyze 相同的事件流",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/analyze/sync": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "与 /expenses/analyze 相同的分析和落库流程，等模型输出完后一次性返回 JSON，适合快捷指令、脚本等不能处理 SSE 的调用方。\n返回保存后的账单、模型的分析结果和消费日期的判定过程。失败时 data 为 {\"code\": \"...\", \"message\": \"...\"}，code 取值同 SSE 的 error 事件，账单没有保存。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "自然语言记账 (非流式)",
+                "parameters": [
+                    {
+                        "description": "记账内容",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.ExpenseAnalyzeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.AnalyzeResult"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "模型输出无法解析",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.StreamFailure"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "502": {
+                        "description": "模型输出中断",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.StreamFailure"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "503": {
+                        "description": "模型后端都不可用",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.StreamFailure"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "504": {
+                        "description": "模型响应超时",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.StreamFailure"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/bulk/commit": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "提交预览中确认 (可以修改过) 的行，在一个事务里全部入账：任意一行不合法时整批都不保存。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "确认批量记账",
+                "parameters": [
+                    {
+                        "description": "确认的行",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.BulkCommitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.BulkCommitResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/bulk/preview": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "月底补账用：粘贴多行文本，每行一笔，逐行识别金额、分类和日期，不落库。\n单行识别失败时该行带 error_code / error，其他行不受影响。最多 50 行。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "批量记账预览",
+                "parameters": [
+                    {
+                        "description": "多行文本",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.BulkPreviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.BulkPreviewResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/comment/feedback": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "对账单的 comment 点赞或点踩。之后生成吐槽时，最近说过的和被踩的不会再重复，被赞的作为风格参考。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "吐槽反馈",
+                "parameters": [
+                    {
+                        "description": "账单和反馈",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.RoastFeedbackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/delete": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除已存在的账单信息，仅限本人操作",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "删除账本条目",
+                "parameters": [
+                    {
+                        "description": "删除参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.DeleteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/export/csv": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "按与列表接口相同的筛选条件导出全部账单 (不分页)，列为 日期/分类/金额/备注/吐槽，UTF-8 带 BOM，可直接用 Excel 打开。",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Export"
+                ],
+                "summary": "导出 CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "分类",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期 2006-01-02",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期 2006-01-02 (包含当天)",
+                        "name": "end_date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV 文件",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/export/journal": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "把筛选出的全部账单导出为 Beancount 或 hledger 日记账。分类映射为支出账户 (默认 Expenses:\u003c分类\u003e)，元数据中带 expense_id 和吐槽 (roast)。",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Export"
+                ],
+                "summary": "导出日记账",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "beancount (默认) / hledger",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "分类",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期 2006-01-02",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期 2006-01-02 (包含当天)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "支出账户前缀，默认 Expenses",
+                        "name": "expense_root",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "付款账户，默认 Assets:Cash",
+                        "name": "default_funding",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "货币，默认 CNY",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "日记账文本",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/export/xlsx": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "按与列表接口相同的筛选条件导出全部账单 (不分页)，列为 日期/分类/金额/备注/吐槽。",
+                "produces": [
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Export"
+                ],
+                "summary": "导出 Excel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "分类",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始日期 2006-01-02",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束日期 2006-01-02 (包含当天)",
+                        "name": "end_date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "XLSX 文件",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/jobs": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "提交后立即返回任务，不需要保持连接。之后用 /expenses/jobs/detail 轮询，或者填写 callback_url 等待回调。\n模型调用失败会按指数退避自动重试；服务重启后未完成的任务会继续处理。\n回调为 POST JSON，内容与任务详情相同；配置了签名密钥时带 X-FaceTax-Signature: sha256=\u003cHMAC-SHA256(body)\u003e。回调返回非 2xx 时会重试几次。\ncallback_url 必须解析到公网地址，本机、内网和链路本地地址会被拒绝 (400)。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "异步记账",
+                "parameters": [
+                    {
+                        "description": "记账内容",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.SubmitJobRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.AnalysisJob"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/jobs/detail": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "status 为 pending / running / succeeded / failed。succeeded 时 result 与同步记账接口的 data 相同；\nerror_code 为最近一次失败的原因码 (取值同 SSE 的 error 事件)，status 仍为 pending 时表示正在等待重试。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "异步记账任务详情",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.AnalysisJob"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/notifications": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "粘贴一条或多条银行短信、支付宝/微信支付通知，按模板提取金额、商户、尾号和时间后入账，AI 只负责分类和吐槽。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "银行短信/支付通知记账",
+                "parameters": [
+                    {
+                        "description": "通知原文",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.NotificationImportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.NotificationImportResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/reanalyze/apply": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "提交预览中确认 (可以修改过) 的结果，在一个事务里全部更新：任意一条不合法时整批都不保存。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "应用重新分析",
+                "parameters": [
+                    {
+                        "description": "确认的修改",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.ReanalyzeApplyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.ReanalyzeApplyResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/reanalyze/preview": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "把账单保存的原始描述重新交给模型分析 (比如换了模型或改了分类之后)，返回与当前数据的差异，不落库。\n只比较金额、分类和备注，日期不重新推断。没有原始描述的账单 (图片记账、账单导入) 带 error_code=no_description。最多 50 条。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "重新分析预览",
+                "parameters": [
+                    {
+                        "description": "账单 ID 或筛选条件",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.ReanalyzePreviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/controller.ReanalyzePreviewResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/receipt": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "上传购物小票或支付截图，由多模态模型识别出一笔或多笔消费。\n吐槽要先经过内容检查，识别和落库完成后才推送：每一笔先推送 split 事件 (data 为序号)，再推送一个 delta 事件 (该笔完整的工具参数 JSON，comment 为检查后的吐槽)，最后 done 推送已保存的账单数组。\n同一张图片的几笔消费在一个事务里保存，任何一笔识别失败时推送 error 事件，所有消费都不保存。",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "图片记账",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "小票图片 (jpeg/png/webp/gif，最大 8MB)",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "done 事件的数据",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ExpenseEntity"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/expenses/update": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "修改已存在的账单信息，仅限本人操作",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Expense"
+                ],
+                "summary": "更新账本条目",
+                "parameters": [
+                    {
+                        "description": "更新参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/batches": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "最近 50 个导入批次及其进度",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "导入批次列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/model.ImportBatch"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/batches/detail": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "查询单个导入批次的状态和进度 (processed/total)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "导入批次进度",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "批次 ID",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.ImportBatch"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/batches/resume": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "从断点继续一个失败的导入批次",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "继续导入",
+                "parameters": [
+                    {
+                        "description": "批次 ID",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.BatchIDRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.ImportBatch"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/batches/rollback": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除该批次导入的全部账单及其 AI 记忆",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "回滚导入批次",
+                "parameters": [
+                    {
+                        "description": "批次 ID",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.BatchIDRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.ImportBatch"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/bill": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "上传支付宝或微信支付导出的账单 CSV，按交易单号去重，规则 + AI 批量分类后入账。dry_run=true 时只返回预览不落库。",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "导入支付宝/微信账单",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "账单 CSV 文件",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "仅预览，不落库",
+                        "name": "dry_run",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.ImportReport"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/profiles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "列映射方案列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/model.ImportProfile"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "保存某家银行流水 CSV 的列映射，下次导入时通过 profile_id 复用",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "保存列映射方案",
+                "parameters": [
+                    {
+                        "description": "映射方案",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.SaveProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.ImportProfile"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/profiles/delete": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "删除列映射方案",
+                "parameters": [
+                    {
+                        "description": "映射方案 ID",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.ProfileIDRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/statement": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "上传 CSV/OFX/QIF 银行流水，创建一个异步导入批次。CSV 需要通过 mapping (JSON) 或 profile_id 指定列映射。按 日期+金额+描述 去重。",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "导入银行流水",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "流水文件 (.csv/.ofx/.qfx/.qif)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "已保存的列映射方案 ID",
+                        "name": "profile_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "列映射 JSON，优先级高于 profile_id",
+                        "name": "mapping",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.ImportBatch"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/profile": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "返回当前用户的资料。未设置时区时 timezone 为服务端默认时区。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "用户资料",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.User"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/profile/update": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "设置用户所在时区。记账时的 \"今天/昨天\"、模型提示词中的当前时间、列表筛选和导出的日期边界都按该时区计算。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "修改用户资料",
+                "parameters": [
+                    {
+                        "description": "用户资料",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/roast/personas": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "第一个是默认人设。当前选择的人设和力度见用户资料的 roast_persona / roast_intensity。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "吐槽人设列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/prompt.Persona"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/roast/update": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "之后记账时的 comment 按选择的人设和力度生成；力度为 0 时不再吐槽，comment 为空。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "修改吐槽人设和力度",
+                "parameters": [
+                    {
+                        "description": "人设和力度",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateRoastRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controller.AssignPromptRequest": {
+            "type": "object",
+            "required": [
+                "user_id"
+            ],
+            "properties": {
+                "user_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "为空时恢复为默认版本",
+                    "type": "string"
+                }
+            }
+        },
+        "controller.AuditListResponse": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AnalysisAudit"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.BatchIDRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.BulkCommitRequest": {
+            "type": "object",
+            "required": [
+                "entries"
+            ],
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.BulkEntry"
+                    }
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Asia/Shanghai"
+                }
+            }
+        },
+        "controller.BulkCommitResponse": {
+            "type": "object",
+            "properties": {
+                "expenses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ExpenseEntity"
+                    }
+                }
+            }
+        },
+        "controller.BulkPreviewRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "text": {
+                    "description": "每行一笔，也可以用分号分隔",
+                    "type": "string",
+                    "example": "3/2 超市 86"
+                },
+                "timezone": {
+                    "description": "可选，同 /expenses/analyze",
+                    "type": "string",
+                    "example": "Asia/Shanghai"
+                }
+            }
+        },
+        "controller.BulkPreviewResponse": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.BulkLine"
+                    }
+                }
+            }
+        },
+        "controller.DeleteRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.ExpenseAnalyzeRequest": {
+            "type": "object",
+            "required": [
+                "description"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "可选，本次记账使用的时区，优先于用户资料中的时区",
+                    "type": "string",
+                    "example": "Asia/Shanghai"
+                }
+            }
+        },
+        "controller.ExpenseAnalyzeResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.ExperimentIDRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.ListRequest": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "start_date": {
+                    "description": "格式 2023-01-01",
+                    "type": "string"
+                }
+            }
+        },
+        "controller.ListResponse": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ExpenseEntity"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.LoginRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.ModerationLogListResponse": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ModerationLog"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.NotificationImportRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.NotificationImportResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.NotificationResult"
+                    }
+                },
+                "success": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.ProfileIDRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.PromptDetailResponse": {
+            "type": "object",
+            "properties": {
+                "source": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.ReanalyzeApplyRequest": {
+            "type": "object",
+            "required": [
+                "changes"
+            ],
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.ReanalyzeChange"
+                    }
+                }
+            }
+        },
+        "controller.ReanalyzeApplyResponse": {
+            "type": "object",
+            "properties": {
+                "expenses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ExpenseEntity"
+                    }
+                }
+            }
+        },
+        "controller.ReanalyzePreviewRequest": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string",
+                    "example": "2024-03-31"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "start_date": {
+                    "type": "string",
+                    "example": "2024-03-01"
+                }
+            }
+        },
+        "controller.ReanalyzePreviewResponse": {
+            "type": "object",
+            "properties": {
+                "diffs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.ReanalyzeDiff"
+                    }
+                }
+            }
+        },
+        "controller.RegisterRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 6
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 2
+                }
+            }
+        },
+        "controller.RoastFeedbackRequest": {
+            "type": "object",
+            "required": [
+                "expense_id",
+                "feedback"
+            ],
+            "properties": {
+                "expense_id": {
+                    "type": "integer"
+                },
+                "feedback": {
+                    "description": "1 赞，-1 踩，0 撤销",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "controller.SaveProfileRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "mapping": {
+                    "$ref": "#/definitions/model.ColumnMapping"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                }
+            }
+        },
+        "controller.SubmitJobRequest": {
+            "type": "object",
+            "required": [
+                "description"
+            ],
+            "properties": {
+                "callback_url": {
+                    "description": "可选，任务结束后 POST 任务详情到这个地址",
+                    "type": "string",
+                    "example": "https://example.com/facetax/hook"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "可选，同 /expenses/analyze",
+                    "type": "string",
+                    "example": "Asia/Shanghai"
+                }
+            }
+        },
+        "controller.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "timezone": {
+                    "description": "IANA 时区名，传空串恢复默认时区",
+                    "type": "string",
+                    "example": "America/New_York"
+                }
+            }
+        },
+        "controller.UpdateRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.UpdateRoastRequest": {
+            "type": "object",
+            "required": [
+                "intensity"
+            ],
+            "properties": {
+                "intensity": {
+                    "description": "0 不吐槽，1 轻微调侃，2 辛辣幽默，3 火力全开",
+                    "type": "integer",
+                    "example": 2
+                },
+                "persona": {
+                    "description": "人设 ID，传空串恢复默认人设",
+                    "type": "string",
+                    "example": "mom"
+                }
+            }
+        },
+        "model.AnalysisAudit": {
+            "type": "object",
+            "properties": {
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "corrected_at": {
+                    "type": "string"
+                },
+                "corrected_fields": {
+                    "description": "用户事后修改了这笔账单的哪些字段 (amount / category / note)，为空表示没改过；直接改账单记在第一次记账的识别记录上，改重新分析的建议记在那次重新分析的记录上",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "description": "用户的原始描述，图片记账为空",
+                    "type": "string"
+                },
+                "expense_id": {
+                    "type": "integer"
+                },
+                "experiment": {
+                    "description": "调用时用户所在的实验和分组",
+                    "type": "string"
+                },
+                "history": {
+                    "description": "检索到并放进 Prompt 的历史消费",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "injection": {
+                    "description": "描述命中的提示词注入特征，为空表示没有",
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "description": "从调用模型到输出结束",
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "persona": {
+                    "description": "吐槽人设，不吐槽时为空",
+                    "type": "string"
+                },
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "prompt_version": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "raw_output": {
+                    "description": "模型输出的原始 JSON，解析失败的也保留",
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "variant": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.AnalysisJob": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "已经被领取的次数",
+                    "type": "integer"
+                },
+                "callback_attempts": {
+                    "type": "integer"
+                },
+                "callback_status": {
+                    "type": "string"
+                },
+                "callback_url": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "error_code": {
+                    "description": "最近一次失败的原因码和文案，取值同 SSE 的 error 事件",
+                    "type": "string"
+                },
+                "expense_id": {
+                    "description": "成功时的结果：与同步记账接口的 data 相同",
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "next_run_at": {
+                    "description": "排队中的任务最早什么时候可以被领取",
+                    "type": "string"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ColumnMapping": {
+            "type": "object",
+            "properties": {
+                "amount_column": {
+                    "description": "单列带符号金额",
+                    "type": "string"
+                },
+                "counterparty_column": {
+                    "description": "对方户名，可选",
+                    "type": "string"
+                },
+                "credit_column": {
+                    "description": "或者：收入列",
+                    "type": "string"
+                },
+                "date_column": {
+                    "type": "string"
+                },
+                "date_layout": {
+                    "description": "Go 时间格式，为空时自动尝试常见格式",
+                    "type": "string"
+                },
+                "debit_column": {
+                    "description": "或者：支出列",
+                    "type": "string"
+                },
+                "delimiter": {
+                    "description": "默认逗号",
+                    "type": "string"
+                },
+                "description_column": {
+                    "description": "摘要/用途",
+                    "type": "string"
+                },
+                "expense_negative": {
+                    "description": "单列金额时，支出是否为负数",
+                    "type": "boolean"
+                },
+                "has_header": {
+                    "description": "第一行 (SkipRows 之后) 是否为表头",
+                    "type": "boolean"
+                },
+                "skip_rows": {
+                    "description": "表头前需要跳过的说明行数",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ExpenseEntity": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "description": "用户的原始描述，重新分析时再交给模型；图片记账和账单导入为空",
+                    "type": "string"
+                },
+                "external_id": {
+                    "description": "平台交易单号，用于导入去重",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "import_batch_id": {
+                    "description": "所属导入批次，整批回滚时按它删除",
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "导入来源，手动记账为空",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "输入数据",
+                    "type": "string"
+                }
+            }
+        },
+        "model.Experiment": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "stopped_at": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ExperimentVariant"
+                    }
+                }
+            }
+        },
+        "model.ExperimentVariant": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "prompt_version": {
+                    "description": "Prompt 模板版本",
+                    "type": "string"
+                },
+                "provider": {
+                    "description": "llm.providers 里的后端名字",
+                    "type": "string"
+                },
+                "weight": {
+                    "description": "分流权重，不填按 1",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.FaceTaxAnalysis": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "comment": {
+                    "description": "Comment: 毒舌评价\n这是产品的核心灵魂，必须展示给用户",
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "note": {
+                    "description": "AI生成的精简备注",
+                    "type": "string"
+                }
+            }
+        },
+        "model.ImportBatch": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "format": {
+                    "description": "csv / ofx / qif",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "mapping": {
+                    "$ref": "#/definitions/model.ColumnMapping"
+                },
+                "processed": {
+                    "description": "断点：已处理的记录数",
+                    "type": "integer"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ImportProfile": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "mapping": {
+                    "$ref": "#/definitions/model.ColumnMapping"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ModerationLog": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "regenerate / replace",
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "classifier": {
+                    "type": "string"
+                },
+                "comment": {
+                    "description": "没通过的吐槽",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expense_id": {
+                    "description": "0 表示还没落库 (批量预览) 或没有落库",
+                    "type": "integer"
+                },
+                "final": {
+                    "description": "最终写进账单的吐槽",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "persona": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.User": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "roast_intensity": {
+                    "type": "integer"
+                },
+                "roast_persona": {
+                    "description": "吐槽人设 (为空表示默认人设) 和力度 0-3，0 表示不吐槽",
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "IANA 时区名，为空表示使用服务端默认时区",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "notify.Notification": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "支出金额 (元)",
+                    "type": "number"
+                },
+                "card_tail": {
+                    "description": "卡号尾号，可能为空",
+                    "type": "string"
+                },
+                "merchant": {
+                    "description": "商户/收款方，可能为空",
+                    "type": "string"
+                },
+                "raw": {
+                    "description": "原始文本",
+                    "type": "string"
+                },
+                "source": {
+                    "description": "命中的模板名，如 cmb、alipay",
+                    "type": "string"
+                },
+                "time": {
+                    "description": "交易时间，零值表示通知里没有",
+                    "type": "string"
+                }
+            }
+        },
+        "prompt.Persona": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "description": "给用户看的介绍",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.RoastStats": {
+            "type": "object",
+            "properties": {
+                "disliked": {
+                    "description": "被踩",
+                    "type": "integer"
+                },
+                "like_rate": {
+                    "description": "赞 / (赞 + 踩)，没有反馈时为 0",
+                    "type": "number"
+                },
+                "liked": {
+                    "description": "被赞",
+                    "type": "integer"
+                },
+                "persona": {
+                    "type": "string"
+                },
+                "prompt_version": {
+                    "type": "string"
+                },
+                "roasts": {
+                    "description": "吐槽条数",
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.VariantStats": {
+            "type": "object",
+            "properties": {
+                "avg_completion_tokens": {
+                    "type": "number"
+                },
+                "avg_latency_ms": {
+                    "type": "number"
                 },
-                "end_date": {
-                    "type": "string"
+                "avg_prompt_tokens": {
+                    "type": "number"
                 },
-                "page": {
+                "calls": {
+                    "description": "模型调用次数",
                     "type": "integer"
                 },
-                "page_size": {
+                "corrected": {
+                    "description": "其中被用户修改过的",
                     "type": "integer"
                 },
-                "start_date": {
-                    "description": "格式 2023-01-01",
+                "expenses": {
+                    "description": "其中落库成账单的",
+                    "type": "integer"
+                },
+                "roast_disliked": {
+                    "description": "其中吐槽被踩的",
+                    "type": "integer"
+                },
+                "roast_liked": {
+                    "description": "其中吐槽被赞的",
+                    "type": "integer"
+                },
+                "variant": {
                     "type": "string"
                 }
             }
         },
-        "controller.ListResponse": {
+        "response.Response": {
             "type": "object",
             "properties": {
-                "list": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.ExpenseEntity"
-                    }
-                },
-                "page": {
+                "code": {
+                    "description": "0 代表成功，非 0 代表错误码",
                     "type": "integer"
                 },
-                "total": {
+                "data": {
+                    "description": "数据载荷"
+                },
+                "msg": {
+                    "description": "提示信息",
+                    "type": "string"
+                }
+            }
+        },
+        "service.AnalyzeResult": {
+            "type": "object",
+            "properties": {
+                "analysis": {
+                    "description": "按保存后的账单给出的分析结果 (吐槽是内容检查后的)，只有 date 保留模型推断的日期",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.FaceTaxAnalysis"
+                        }
+                    ]
+                },
+                "expense": {
+                    "description": "保存后的账单",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ExpenseEntity"
+                        }
+                    ]
+                },
+                "provider": {
+                    "description": "实际服务的模型后端",
+                    "type": "string"
+                },
+                "resolved_date": {
+                    "description": "消费日期的判定过程",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.DateResolution"
+                        }
+                    ]
+                }
+            }
+        },
+        "service.BulkEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "audit_id": {
+                    "description": "预览结果里的 audit_id，用于把审计记录关联到账单",
                     "type": "integer"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "date": {
+                    "description": "YYYY-MM-DD",
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "text": {
+                    "description": "原文，写进向量记忆",
+                    "type": "string"
                 }
             }
         },
-        "controller.LoginRequest": {
+        "service.BulkLine": {
             "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
             "properties": {
-                "email": {
+                "amount": {
+                    "type": "number"
+                },
+                "audit_id": {
+                    "description": "这一行的识别审计记录，确认入账时原样带回",
+                    "type": "integer"
+                },
+                "category": {
                     "type": "string"
                 },
-                "password": {
+                "comment": {
+                    "type": "string"
+                },
+                "date": {
+                    "description": "YYYY-MM-DD，用户所在时区",
+                    "type": "string"
+                },
+                "date_source": {
+                    "description": "text / llm / default，同 DateResolution.Source",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "error_code": {
+                    "description": "这一行识别失败的原因码和文案，取值同 SSE 的 error 事件；有错误时其余字段没有意义",
+                    "type": "string"
+                },
+                "line": {
+                    "description": "在原文中的行号，从 1 开始",
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "text": {
+                    "description": "去掉列表标记后的原文",
                     "type": "string"
                 }
             }
         },
-        "controller.LoginResponse": {
+        "service.DateResolution": {
             "type": "object",
             "properties": {
-                "token": {
+                "date": {
+                    "description": "最终采用的日期 YYYY-MM-DD",
                     "type": "string"
                 },
-                "user_id": {
+                "expr": {
+                    "description": "描述里命中的日期表达，例如 \"上周五\"、\"除夕\"",
+                    "type": "string"
+                },
+                "llm_date": {
+                    "description": "模型给出的原始日期",
+                    "type": "string"
+                },
+                "source": {
+                    "description": "text / llm / default",
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "计算所用的时区",
                     "type": "string"
                 }
             }
         },
-        "controller.RegisterRequest": {
+        "service.ExperimentInput": {
             "type": "object",
             "required": [
-                "email",
-                "password",
-                "username"
+                "name",
+                "variants"
             ],
             "properties": {
-                "email": {
+                "description": {
                     "type": "string"
                 },
-                "password": {
-                    "type": "string",
-                    "minLength": 6
+                "name": {
+                    "type": "string"
                 },
-                "username": {
-                    "type": "string",
-                    "maxLength": 100,
-                    "minLength": 2
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ExperimentVariant"
+                    }
                 }
             }
         },
-        "controller.UpdateRequest": {
+        "service.ExperimentReport": {
+            "type": "object",
+            "properties": {
+                "experiment": {
+                    "$ref": "#/definitions/model.Experiment"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.VariantReport"
+                    }
+                }
+            }
+        },
+        "service.ImportItem": {
             "type": "object",
-            "required": [
-                "id"
-            ],
             "properties": {
                 "amount": {
                     "type": "number"
@@ -435,65 +3432,210 @@ const docTemplate = `{
                 "category": {
                     "type": "string"
                 },
-                "date": {
+                "category_source": {
+                    "description": "rule / llm / fallback",
                     "type": "string"
                 },
-                "id": {
+                "counterparty": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expense_id": {
                     "type": "integer"
                 },
-                "note": {
+                "reason": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "time": {
                     "type": "string"
                 }
             }
         },
-        "model.ExpenseEntity": {
+        "service.ImportReport": {
+            "type": "object",
+            "properties": {
+                "by_fallback": {
+                    "type": "integer"
+                },
+                "by_llm": {
+                    "type": "integer"
+                },
+                "by_rule": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.ImportItem"
+                    }
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "source": {
+                    "description": "alipay / wechat",
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.NotificationResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "expense": {
+                    "$ref": "#/definitions/model.ExpenseEntity"
+                },
+                "notification": {
+                    "$ref": "#/definitions/notify.Notification"
+                },
+                "raw": {
+                    "type": "string"
+                },
+                "warning": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.PromptVersions": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "type": "string"
+                },
+                "versions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "service.ReanalyzeChange": {
             "type": "object",
             "properties": {
                 "amount": {
                     "type": "number"
                 },
+                "audit_id": {
+                    "description": "预览结果里的 audit_id，用于把审计记录关联到账单",
+                    "type": "integer"
+                },
                 "category": {
                     "type": "string"
                 },
-                "comment": {
-                    "type": "string"
+                "expense_id": {
+                    "type": "integer"
                 },
-                "created_at": {
+                "note": {
                     "type": "string"
+                }
+            }
+        },
+        "service.ReanalyzeDiff": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "$ref": "#/definitions/service.ReanalyzeValues"
                 },
-                "id": {
+                "audit_id": {
+                    "description": "这次识别的审计记录，应用时原样带回",
                     "type": "integer"
                 },
-                "note": {
+                "before": {
+                    "$ref": "#/definitions/service.ReanalyzeValues"
+                },
+                "changed": {
+                    "description": "有变化的字段：amount / category / note",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
                     "type": "string"
                 },
-                "updated_at": {
+                "error": {
                     "type": "string"
                 },
-                "user_id": {
-                    "description": "输入数据",
+                "error_code": {
+                    "description": "识别失败的原因码和文案，取值同 SSE 的 error 事件，另有 no_description；有错误时 After 没有意义",
                     "type": "string"
+                },
+                "expense_id": {
+                    "type": "integer"
                 }
             }
         },
-        "response.Response": {
+        "service.ReanalyzeValues": {
             "type": "object",
             "properties": {
-                "code": {
-                    "description": "0 代表成功，非 0 代表错误码",
-                    "type": "integer"
+                "amount": {
+                    "type": "number"
                 },
-                "data": {
-                    "description": "数据载荷"
+                "category": {
+                    "type": "string"
                 },
-                "msg": {
-                    "description": "提示信息",
+                "note": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.StreamFailure": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
                     "type": "string"
                 }
             }
+        },
+        "service.VariantReport": {
+            "type": "object",
+            "properties": {
+                "correction_rate": {
+                    "description": "落库的账单中被用户修改过的比例",
+                    "type": "number"
+                },
+                "stats": {
+                    "$ref": "#/definitions/repository.VariantStats"
+                },
+                "variant": {
+                    "$ref": "#/definitions/model.ExperimentVariant"
+                }
+            }
         }
     },
     "securityDefinitions": {
+        "AdminToken": {
+            "description": "配置项 server.admin_token 的值",
+            "type": "apiKey",
+            "name": "X-Admin-Token",
+            "in": "header"
+        },
         "BearerAuth": {
             "description": "请在输入框中输入 \"Bearer \u003ctoken\u003e\" (注意 Bearer 和 token 之间有空格)",
             "type": "apiKey",
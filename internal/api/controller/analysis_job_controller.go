@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+// AnalysisJobController 异步记账任务
+type AnalysisJobController struct {
+	service *service.AnalysisJobService
+}
+
+// NewAnalysisJobController 构造函数
+func NewAnalysisJobController(s *service.AnalysisJobService) *AnalysisJobController {
+	return &AnalysisJobController{service: s}
+}
+
+// SubmitJobRequest 提交异步记账任务
+type SubmitJobRequest struct {
+	Description string `json:"description" binding:"required"`
+	TimeZone    string `json:"timezone" example:"Asia/Shanghai"`                        // 可选，同 /expenses/analyze
+	CallbackURL string `json:"callback_url" example:"https://example.com/facetax/hook"` // 可选，任务结束后 POST 任务详情到这个地址
+}
+
+// JobIDRequest 按 ID 查询任务
+type JobIDRequest struct {
+	ID uint `json:"id" form:"id" binding:"required"`
+}
+
+// Submit 提交异步记账任务
+// @Summary 异步记账
+// @Description 提交后立即返回任务，不需要保持连接。之后用 /expenses/jobs/detail 轮询，或者填写 callback_url 等待回调。
+// @Description 模型调用失败会按指数退避自动重试；服务重启后未完成的任务会继续处理。
+// @Description 回调为 POST JSON，内容与任务详情相同；配置了签名密钥时带 X-FaceTax-Signature: sha256=<HMAC-SHA256(body)>。回调返回非 2xx 时会重试几次。
+// @Description callback_url 必须解析到公网地址，本机、内网和链路本地地址会被拒绝 (400)。
+// @Tags Expense
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SubmitJobRequest true "记账内容"
+// @Success 200 {object} response.Response{data=model.AnalysisJob}
+// @Router /expenses/jobs [post]
+func (ctrl *AnalysisJobController) Submit(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req SubmitJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	job, err := ctrl.service.Submit(c.Request.Context(), userIDStr, req.Description, req.TimeZone, req.CallbackURL)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCallbackURL) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("提交异步记账任务失败", "uid", userIDStr, "error", err)
+		response.Error(c, http.StatusInternalServerError, "提交失败")
+		return
+	}
+	response.Success(c, job)
+}
+
+// Get 查询异步记账任务
+// @Summary 异步记账任务详情
+// @Description status 为 pending / running / succeeded / failed。succeeded 时 result 与同步记账接口的 data 相同；
+// @Description error_code 为最近一次失败的原因码 (取值同 SSE 的 error 事件)，status 仍为 pending 时表示正在等待重试。
+// @Tags Expense
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "任务 ID"
+// @Success 200 {object} response.Response{data=model.AnalysisJob}
+// @Router /expenses/jobs/detail [get]
+func (ctrl *AnalysisJobController) Get(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+	var req JobIDRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	job, err := ctrl.service.Get(c.Request.Context(), userIDStr, req.ID)
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			response.Error(c, http.StatusNotFound, err.Error())
+			return
+		}
+		slog.Error("查询异步记账任务失败", "uid", userIDStr, "job", req.ID, "error", err)
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, job)
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+// AuditController 管理员查看模型识别的审计记录
+type AuditController struct {
+	service *service.AuditService
+}
+
+// NewAuditController 构造函数
+func NewAuditController(s *service.AuditService) *AuditController {
+	return &AuditController{service: s}
+}
+
+// AuditListRequest 审计记录筛选条件，都可以不填
+type AuditListRequest struct {
+	UserID    string `form:"user_id"`
+	ExpenseID uint   `form:"expense_id"`
+	Source    string `form:"source"` // analyze / receipt / notification / bulk / reanalyze / moderation
+	Injected  bool   `form:"injected"`
+	Page      int    `form:"page,default=1"`
+	PageSize  int    `form:"page_size,default=20"`
+}
+
+// AuditListResponse 审计记录列表
+type AuditListResponse struct {
+	List  []model.AnalysisAudit `json:"list"`
+	Total int64                 `json:"total"`
+	Page  int                   `json:"page"`
+}
+
+// AuditIDRequest 按 ID 查询审计记录
+type AuditIDRequest struct {
+	ID uint `form:"id" binding:"required"`
+}
+
+// List 审计记录列表
+// @Summary 模型识别审计记录
+// @Description 每次模型识别都会留一条记录：原始描述、模型后端和模型名、Prompt 版本、放进 Prompt 的历史消费、模型原始输出、耗时和 token 用量。
+// @Description expense_id 为 0 的记录是没有落库的识别 (预览后没有确认，或输出解析失败、没有通过校验)。最新的在前。
+// @Description injection 为描述命中的提示词注入特征 (ignore_instructions / role_override / prompt_leak / fake_tag / field_override)，injected=true 只看这类记录。
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Param user_id query string false "用户 ID"
+// @Param expense_id query int false "账单 ID"
+// @Param source query string false "来源"
+// @Param injected query bool false "只看疑似提示词注入"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页条数"
+// @Success 200 {object} response.Response{data=controller.AuditListResponse}
+// @Router /admin/audits [get]
+func (ctrl *AuditController) List(c *gin.Context) {
+	var req AuditListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	audits, total, err := ctrl.service.List(c.Request.Context(), repository.AuditFilter{
+		UserID:    req.UserID,
+		ExpenseID: req.ExpenseID,
+		Source:    req.Source,
+		Injected:  req.Injected,
+		Page:      req.Page,
+		PageSize:  req.PageSize,
+	})
+	if err != nil {
+		slog.Error("查询审计记录失败", "error", err)
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, AuditListResponse{List: audits, Total: total, Page: req.Page})
+}
+
+// Get 审计记录详情
+// @Summary 模型识别审计记录详情
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Param id query int true "记录 ID"
+// @Success 200 {object} response.Response{data=model.AnalysisAudit}
+// @Router /admin/audits/detail [get]
+func (ctrl *AuditController) Get(c *gin.Context) {
+	var req AuditIDRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	audit, err := ctrl.service.Get(c.Request.Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, service.ErrAuditNotFound) {
+			response.Error(c, http.StatusNotFound, err.Error())
+			return
+		}
+		slog.Error("查询审计记录失败", "id", req.ID, "error", err)
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, audit)
+}
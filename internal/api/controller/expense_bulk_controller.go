@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+// BulkPreviewRequest 批量记账预览
+type BulkPreviewRequest struct {
+	Text     string `json:"text" binding:"required" example:"3/2 超市 86"` // 每行一笔，也可以用分号分隔
+	TimeZone string `json:"timezone" example:"Asia/Shanghai"`            // 可选，同 /expenses/analyze
+}
+
+// BulkPreviewResponse 批量记账预览结果
+type BulkPreviewResponse struct {
+	Lines []service.BulkLine `json:"lines"`
+}
+
+// BulkCommitRequest 确认批量记账
+type BulkCommitRequest struct {
+	Entries  []service.BulkEntry `json:"entries" binding:"required"`
+	TimeZone string              `json:"timezone" example:"Asia/Shanghai"`
+}
+
+// BulkCommitResponse 批量记账结果
+type BulkCommitResponse struct {
+	Expenses []*model.ExpenseEntity `json:"expenses"`
+}
+
+// BulkPreview 批量记账预览
+// @Summary 批量记账预览
+// @Description 月底补账用：粘贴多行文本，每行一笔，逐行识别金额、分类和日期，不落库。
+// @Description 单行识别失败时该行带 error_code / error，其他行不受影响。最多 50 行。
+// @Tags Expense
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkPreviewRequest true "多行文本"
+// @Success 200 {object} response.Response{data=controller.BulkPreviewResponse}
+// @Router /expenses/bulk/preview [post]
+func (ctrl *ExpenseController) BulkPreview(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req BulkPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	lines, err := ctrl.service.PreviewBulk(c.Request.Context(), userIDStr, req.Text, req.TimeZone)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, BulkPreviewResponse{Lines: lines})
+}
+
+// BulkCommit 确认批量记账
+// @Summary 确认批量记账
+// @Description 提交预览中确认 (可以修改过) 的行，在一个事务里全部入账：任意一行不合法时整批都不保存。
+// @Tags Expense
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkCommitRequest true "确认的行"
+// @Success 200 {object} response.Response{data=controller.BulkCommitResponse}
+// @Router /expenses/bulk/commit [post]
+func (ctrl *ExpenseController) BulkCommit(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req BulkCommitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	expenses, err := ctrl.service.CommitBulk(c.Request.Context(), userIDStr, req.Entries, req.TimeZone)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidBulkEntry) || errors.Is(err, service.ErrBulkEmpty) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("批量记账失败", "uid", userIDStr, "error", err)
+		response.Error(c, http.StatusInternalServerError, "保存失败")
+		return
+	}
+	response.Success(c, BulkCommitResponse{Expenses: expenses})
+}
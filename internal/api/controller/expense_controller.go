@@ -1,12 +1,17 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+	"github.com/leon37/FaceTaxLedger/internal/jsonstream"
 	"github.com/leon37/FaceTaxLedger/internal/model"
 	"github.com/leon37/FaceTaxLedger/internal/repository"
 	"github.com/leon37/FaceTaxLedger/internal/service"
+	"github.com/leon37/FaceTaxLedger/internal/sse"
+	"io"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -15,16 +20,25 @@ import (
 
 type ExpenseController struct {
 	service *service.ExpenseService // 依赖 Service
+	jobs    *sse.Hub                // 流式记账任务，支持断线重连
 }
 
 // NewExpenseController 构造函数
-func NewExpenseController(s *service.ExpenseService) *ExpenseController {
-	return &ExpenseController{service: s}
+func NewExpenseController(s *service.ExpenseService, jobs *sse.Hub) *ExpenseController {
+	return &ExpenseController{service: s, jobs: jobs}
 }
 
 // ExpenseAnalyzeRequest 定义前端传来的 JSON 参数结构
 type ExpenseAnalyzeRequest struct {
 	Description string `json:"description" binding:"required"`
+	TimeZone    string `json:"timezone" example:"Asia/Shanghai"` // 可选，本次记账使用的时区，优先于用户资料中的时区
+}
+
+// ExpenseDonePayload SSE done 事件的数据：保存后的账单 + 实际服务的模型后端 + 消费日期的判定过程
+type ExpenseDonePayload struct {
+	*model.ExpenseEntity
+	Provider     string                  `json:"provider"`
+	ResolvedDate *service.DateResolution `json:"resolved_date"`
 }
 
 type ExpenseAnalyzeResponse struct {
@@ -39,11 +53,19 @@ type ExpenseAnalyzeResponse struct {
 // Analyze 智能记账
 // @Summary 自然语言记账
 // @Description AI 自动提取金额、分类并生成吐槽。
+// @Description SSE 事件：field:amount、field:category、field:date、field:note 在对应字段的值确定后推送一次，data 为字段值。
+// @Description 吐槽落库前要经过内容检查，没通过时会被重新生成或替换，所以 field:comment 在检查之后、done 之前推送一次，data 为最终的吐槽；不吐槽时没有这个事件。
+// @Description delta 为完整的工具参数 JSON (兼容自己解析 JSON 的旧客户端)，同样在检查之后推送一次，其中的 comment 是检查后的吐槽。
+// @Description 失败时推送 error 事件，data 为 {"code": "...", "message": "..."}，code 取值：timeout / canceled / provider_unavailable / provider_error / invalid_output / save_failed。出现 error 事件时账单没有保存。
+// @Description done 事件中的 resolved_date 说明消费日期的来源：text 为描述里的日期表达 (如 "昨天"、"除夕")，llm 为模型推断，default 为当天。
+// @Description 分析在服务端作为任务运行，响应头 X-Job-ID 为任务 ID，每个事件带 id (任务ID:序号)，空闲时定期推送 ": heartbeat" 注释。
+// @Description 断线后带 Last-Event-ID 请求头重新请求本接口 (或 GET /expenses/analyze/resume) 即可补发错过的事件和最终结果，此时不需要请求体；还没收到任何事件时用 "任务ID:0"。
 // @Tags Expense
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param request body ExpenseAnalyzeRequest true "记账内容"
+// @Param Last-Event-ID header string false "断线重连时最后收到的事件 id"
 // @Success 200 {object} response.Response{data=controller.ExpenseAnalyzeResponse}
 // @Router /expenses/analyze [post]
 func (ctrl *ExpenseController) Analyze(c *gin.Context) {
@@ -53,6 +75,12 @@ func (ctrl *ExpenseController) Analyze(c *gin.Context) {
 		return
 	}
 
+	// 1. 断线重连：不重新分析，直接补发
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		ctrl.resume(c, userIDStr, lastEventID)
+		return
+	}
+
 	// 2. 解析 JSON 参数
 	var req ExpenseAnalyzeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -60,64 +88,344 @@ func (ctrl *ExpenseController) Analyze(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
 		return
 	}
-	// 2. 设置 SSE Header
+
+	slog.Info("收到 API 记账请求", "description", req.Description)
+
+	// 3. 分析在服务端任务里跑，客户端断开后任务继续，等待重连
+	job := ctrl.jobs.Start(c.Request.Context(), userIDStr, func(ctx context.Context, emit sse.Emit) {
+		ctrl.runAnalysis(ctx, userIDStr, req, emit)
+	})
+	ctrl.serveJob(c, job, 0)
+}
+
+// AnalyzeSync 同步记账
+// @Summary 自然语言记账 (非流式)
+// @Description 与 /expenses/analyze 相同的分析和落库流程，等模型输出完后一次性返回 JSON，适合快捷指令、脚本等不能处理 SSE 的调用方。
+// @Description 返回保存后的账单、模型的分析结果和消费日期的判定过程。失败时 data 为 {"code": "...", "message": "..."}，code 取值同 SSE 的 error 事件，账单没有保存。
+// @Tags Expense
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ExpenseAnalyzeRequest true "记账内容"
+// @Success 200 {object} response.Response{data=service.AnalyzeResult}
+// @Failure 422 {object} response.Response{data=service.StreamFailure} "模型输出无法解析"
+// @Failure 502 {object} response.Response{data=service.StreamFailure} "模型输出中断"
+// @Failure 503 {object} response.Response{data=service.StreamFailure} "模型后端都不可用"
+// @Failure 504 {object} response.Response{data=service.StreamFailure} "模型响应超时"
+// @Router /expenses/analyze/sync [post]
+func (ctrl *ExpenseController) AnalyzeSync(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+	if userIDStr == "" {
+		response.Error(c, http.StatusUnauthorized, "缺少 X-User-ID 请求头")
+		return
+	}
+
+	var req ExpenseAnalyzeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	slog.Info("收到同步记账请求", "description", req.Description)
+	result, err := ctrl.service.AnalyzeExpense(c.Request.Context(), service.ExpenseInput{
+		UserID:      userIDStr,
+		Description: req.Description,
+		TimeZone:    req.TimeZone,
+	})
+	if err != nil {
+		slog.Error("同步记账失败", "uid", userIDStr, "error", err)
+		failure := service.NewStreamFailure(err)
+		response.ErrorWithData(c, failureStatus[failure.Code], failure.Message, failure)
+		return
+	}
+	response.Success(c, result)
+}
+
+// failureStatus 同步接口中各原因码对应的 HTTP 状态码
+var failureStatus = map[string]int{
+	service.ReasonTimeout:             http.StatusGatewayTimeout,
+	service.ReasonCanceled:            http.StatusRequestTimeout,
+	service.ReasonProviderUnavailable: http.StatusServiceUnavailable,
+	service.ReasonProviderError:       http.StatusBadGateway,
+	service.ReasonInvalidOutput:       http.StatusUnprocessableEntity,
+	service.ReasonSaveFailed:          http.StatusInternalServerError,
+}
+
+// ResumeAnalyze 断线重连
+// @Summary 记账流断线重连
+// @Description 补发 Last-Event-ID 之后的事件；任务还在运行时继续推送直到结束。任务结束后事件保留几分钟，过期返回 404。
+// @Tags Expense
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param Last-Event-ID header string false "最后收到的事件 id (任务ID:序号)"
+// @Param last_event_id query string false "同 Last-Event-ID，不方便设置请求头时使用"
+// @Success 200 {string} string "与 /expenses/analyze 相同的事件流"
+// @Router /expenses/analyze/resume [get]
+func (ctrl *ExpenseController) ResumeAnalyze(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	ctrl.resume(c, userIDStr, lastEventID)
+}
+
+// resume 找回任务，从 lastEventID 之后开始推送
+func (ctrl *ExpenseController) resume(c *gin.Context, userID string, lastEventID string) {
+	jobID, seq, ok := sse.ParseEventID(lastEventID)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Last-Event-ID 格式错误")
+		return
+	}
+	job, err := ctrl.jobs.Attach(jobID, userID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	slog.Info("记账流断线重连", "uid", userID, "job", jobID, "after", seq)
+	ctrl.serveJob(c, job, seq)
+}
+
+// serveJob 推送任务中 after 之后的事件，直到任务结束或客户端断开
+func (ctrl *ExpenseController) serveJob(c *gin.Context, job *sse.Job, after int) {
+	defer ctrl.jobs.Detach(job)
+
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
 	c.Writer.Header().Set("Connection", "keep-alive")
 	c.Writer.Header().Set("Transfer-Encoding", "chunked")
+	c.Writer.Header().Set("X-Job-ID", job.ID)
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(ctrl.jobs.Heartbeat())
+	defer heartbeat.Stop()
+	// 客户端断开时请求 ctx 取消；任务本身不受影响
+	clientGone := c.Request.Context().Done()
+	for {
+		events, finished, changed := job.Since(after)
+		for _, ev := range events {
+			if err := sse.WriteEvent(c.Writer, job.EventID(ev.Seq), ev.Name, ev.Data); err != nil {
+				return
+			}
+			after = ev.Seq
+		}
+		c.Writer.Flush()
+		if finished {
+			return
+		}
 
-	slog.Info("收到 API 记账请求", "description", req.Description)
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+			if err := sse.WriteComment(c.Writer, "heartbeat"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-clientGone:
+			return
+		}
+	}
+}
 
-	// 3. 调用 Service 业务逻辑
-	// 注意：Context 应该传递下去，用于链路追踪或超时控制
+// runAnalysis 一次记账分析，事件写进任务缓存
+func (ctrl *ExpenseController) runAnalysis(ctx context.Context, userID string, req ExpenseAnalyzeRequest, emit sse.Emit) {
+	// 调用 Service 业务逻辑
 	ei := service.ExpenseInput{
-		UserID:      userIDStr,
+		UserID:      userID,
 		Description: req.Description,
+		TimeZone:    req.TimeZone,
 	}
-	streamCh, commitFunc, err := ctrl.service.StreamExpense(c.Request.Context(), ei)
+	// 挂上 CallInfo，结束时告诉前端这次是哪个模型后端服务的
+	ctx, callInfo := llm.WithCallInfo(ctx)
+	stream, commitFunc, err := ctrl.service.StreamExpense(ctx, ei)
 	if err != nil {
 		slog.Error("API 调用业务层失败", "error", err)
-		response.Error(c, http.StatusInternalServerError, "AI 大脑短路了，请稍后再试")
+		emit("error", failureData(err))
 		return
 	}
-	// 4. 循环读取流，推送到前端，同时在内存拼接
+
+	// 读取流，在内存拼接
 	var fullJSONBuilder strings.Builder
+	// 服务端增量解析，前端不用自己处理半截 JSON
+	fields := jsonstream.NewParser()
+	for fragment := range stream.C {
+		// 已经确定的字段立即推送；吐槽没经过内容检查，原始片段里也带着它，都等落库后再推送
+		for _, ev := range fields.Feed(fragment) {
+			if ev.Field != "comment" {
+				emit("field:"+ev.Field, ev.Value)
+			}
+		}
+		fullJSONBuilder.WriteString(fragment)
+	}
+
+	// 流中途出错时拿到的是截断的 JSON，不能落库
+	if err := stream.Err(); err != nil {
+		slog.Error("记账模型输出中断", "uid", userID, "provider", callInfo.Provider(), "error", err)
+		emit("error", failureData(err))
+		return
+	}
+	// 流传输完毕，执行落库逻辑
+	expense, resolution, err := commitFunc(fullJSONBuilder.String())
+	if err != nil {
+		slog.Error("记账落库失败", "uid", userID, "error", err)
+		emit("error", failureData(err))
+		return
+	}
+	// 检查后的吐槽，以及按落库结果重新拼出的工具参数
+	if expense.Comment != "" {
+		emit("field:comment", expense.Comment)
+	}
+	emit("delta", toolArguments(expense, resolution.LLMDate))
+
+	finalData, _ := json.Marshal(ExpenseDonePayload{ExpenseEntity: expense, Provider: callInfo.Provider(), ResolvedDate: resolution})
+	emit("done", string(finalData))
+}
 
-	// 监听客户端断开 (Gin 的特性)
-	clientGone := c.Writer.CloseNotify()
+// sendFailure 推送 error 事件，data 为带原因码的 JSON (service.StreamFailure)
+func sendFailure(c *gin.Context, err error) {
+	c.SSEvent("error", failureData(err))
+	c.Writer.Flush()
+}
+
+func failureData(err error) string {
+	data, _ := json.Marshal(service.NewStreamFailure(err))
+	return string(data)
+}
+
+// toolArguments 按保存后的账单拼出 book_expense 的参数 JSON，代替模型的原始片段推给旧客户端
+func toolArguments(expense *model.ExpenseEntity, date string) string {
+	data, _ := json.Marshal(model.FaceTaxAnalysis{
+		Amount:   expense.Amount,
+		Date:     date,
+		Note:     expense.Note,
+		Comment:  expense.Comment,
+		Category: expense.Category,
+	})
+	return string(data)
+}
+
+// 小票图片大小上限，base64 之后还会再膨胀三分之一
+const maxReceiptSize = 8 << 20
+
+var allowedReceiptTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+// AnalyzeReceipt 小票/截图记账
+// @Summary 图片记账
+// @Description 上传购物小票或支付截图，由多模态模型识别出一笔或多笔消费。
+// @Description 吐槽要先经过内容检查，识别和落库完成后才推送：每一笔先推送 split 事件 (data 为序号)，再推送一个 delta 事件 (该笔完整的工具参数 JSON，comment 为检查后的吐槽)，最后 done 推送已保存的账单数组。
+// @Description 同一张图片的几笔消费在一个事务里保存，任何一笔识别失败时推送 error 事件，所有消费都不保存。
+// @Tags Expense
+// @Accept multipart/form-data
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param image formData file true "小票图片 (jpeg/png/webp/gif，最大 8MB)"
+// @Success 200 {array} model.ExpenseEntity "done 事件的数据"
+// @Router /expenses/receipt [post]
+func (ctrl *ExpenseController) AnalyzeReceipt(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+	if userIDStr == "" {
+		response.Error(c, http.StatusUnauthorized, "缺少 X-User-ID 请求头")
+		return
+	}
+
+	// 1. 读取上传的图片
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: 缺少 image 文件")
+		return
+	}
+	if fileHeader.Size > maxReceiptSize {
+		response.Error(c, http.StatusBadRequest, "图片过大，请压缩到 8MB 以内")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "图片读取失败")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxReceiptSize))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "图片读取失败")
+		return
+	}
+	// 不信任客户端声明的 Content-Type，按文件头嗅探
+	mimeType := http.DetectContentType(data)
+	if !allowedReceiptTypes[mimeType] {
+		response.Error(c, http.StatusBadRequest, "不支持的图片格式: "+mimeType)
+		return
+	}
+
+	// 2. 调用 Service
+	image := llm.ReceiptImage{Data: data, MimeType: mimeType}
+	stream, commitFunc, err := ctrl.service.StreamReceipt(c.Request.Context(), userIDStr, image)
+	if err != nil {
+		slog.Error("API 调用图片识别失败", "error", err)
+		response.Error(c, http.StatusInternalServerError, "AI 大脑短路了，请稍后再试")
+		return
+	}
+
+	// 3. 设置 SSE Header
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("Transfer-Encoding", "chunked")
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.Flush()
+
+	// 4. 按 Index 分别拼接每一笔消费；片段里带着没经过内容检查的吐槽，不推给前端
+	var builders []*strings.Builder
 	for {
 		select {
-		case <-clientGone:
-			// 客户端断开了，停止处理
+		case <-c.Request.Context().Done():
 			return
-		case fragment, ok := <-streamCh:
+		case fragment, ok := <-stream.C:
 			if !ok {
-				// 通道关闭，说明流结束了 -> 进入结算阶段
 				goto Finalize
 			}
-
-			// A. 推送给前端 (Raw Fragment)
-			// 前端收到后拼接到 buffer 中尝试解析
-			c.SSEvent("delta", fragment)
-
-			// B. 后端累积
-			fullJSONBuilder.WriteString(fragment)
-
-			// 这里的 Flush 很重要，确保数据立刻发出去
-			c.Writer.Flush()
+			for len(builders) <= fragment.Index {
+				builders = append(builders, &strings.Builder{})
+			}
+			builders[fragment.Index].WriteString(fragment.Arguments)
 		}
 	}
 
 Finalize:
-	// 5. 流传输完毕，执行落库逻辑
-	fullJSON := fullJSONBuilder.String()
-	expense, err := commitFunc(fullJSON)
+	if err := stream.Err(); err != nil {
+		slog.Error("图片识别输出中断", "uid", userIDStr, "error", err)
+		sendFailure(c, err)
+		return
+	}
+	fullJSONs := make([]string, 0, len(builders))
+	for _, b := range builders {
+		if b.Len() > 0 {
+			fullJSONs = append(fullJSONs, b.String())
+		}
+	}
+	if len(fullJSONs) == 0 {
+		data, _ := json.Marshal(service.StreamFailure{Code: service.ReasonInvalidOutput, Message: "图片中没有识别到消费"})
+		c.SSEvent("error", string(data))
+		c.Writer.Flush()
+		return
+	}
+	expenses, err := commitFunc(fullJSONs)
 	if err != nil {
-		c.SSEvent("error", "saving failed: "+err.Error())
+		slog.Error("小票落库失败", "uid", userIDStr, "error", err)
+		sendFailure(c, err)
 		return
 	}
 
-	finalData, _ := json.Marshal(expense)
+	for i, expense := range expenses {
+		c.SSEvent("split", i)
+		c.SSEvent("delta", toolArguments(expense, expense.CreatedAt.Format(time.DateOnly)))
+	}
+	finalData, _ := json.Marshal(expenses)
 	c.SSEvent("done", string(finalData))
 }
 
@@ -130,6 +438,27 @@ type ListRequest struct {
 	EndDate   string `form:"end_date"`
 }
 
+// toFilter 转成仓储层的筛选条件，导出接口也复用这套参数
+// 日期按用户所在时区解析，"10 月 1 日" 是用户那边的零点到零点
+func (req ListRequest) toFilter(userID string, loc *time.Location) repository.ExpenseFilter {
+	filter := repository.ExpenseFilter{
+		UserID:   userID,
+		Category: req.Category,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}
+	// 解析时间字符串 (简单处理)
+	if req.StartDate != "" {
+		t, _ := time.ParseInLocation("2006-01-02", req.StartDate, loc)
+		filter.StartDate = t
+	}
+	if req.EndDate != "" {
+		t, _ := time.ParseInLocation("2006-01-02", req.EndDate, loc)
+		filter.EndDate = t.AddDate(0, 0, 1) // 包含当天，跨夏令时的那天不一定是 24 小时
+	}
+	return filter
+}
+
 type ListResponse struct {
 	List  []model.ExpenseEntity `json:"list"`
 	Total int64                 `json:"total"`
@@ -158,21 +487,7 @@ func (ctrl *ExpenseController) List(c *gin.Context) {
 	}
 
 	// 3. 构造 Filter
-	filter := repository.ExpenseFilter{
-		UserID:   userIDStr,
-		Category: req.Category,
-		Page:     req.Page,
-		PageSize: req.PageSize,
-	}
-	// 解析时间字符串 (简单处理)
-	if req.StartDate != "" {
-		t, _ := time.Parse("2006-01-02", req.StartDate)
-		filter.StartDate = t
-	}
-	if req.EndDate != "" {
-		t, _ := time.Parse("2006-01-02", req.EndDate)
-		filter.EndDate = t.Add(24 * time.Hour) // 包含当天
-	}
+	filter := req.toFilter(userIDStr, ctrl.service.Location(c.Request.Context(), userIDStr))
 
 	// 4. 调用 Service
 	list, total, err := ctrl.service.GetExpensesList(c.Request.Context(), filter)
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+// ReanalyzePreviewRequest 重新分析预览：填 ids 时按 ID，否则按筛选条件 (同 /expenses)
+type ReanalyzePreviewRequest struct {
+	IDs       []uint `json:"ids"`
+	Category  string `json:"category"`
+	StartDate string `json:"start_date" example:"2024-03-01"`
+	EndDate   string `json:"end_date" example:"2024-03-31"`
+}
+
+// ReanalyzePreviewResponse 重新分析预览结果
+type ReanalyzePreviewResponse struct {
+	Diffs []service.ReanalyzeDiff `json:"diffs"`
+}
+
+// ReanalyzeApplyRequest 确认应用重新分析的结果
+type ReanalyzeApplyRequest struct {
+	Changes []service.ReanalyzeChange `json:"changes" binding:"required"`
+}
+
+// ReanalyzeApplyResponse 应用后的账单
+type ReanalyzeApplyResponse struct {
+	Expenses []*model.ExpenseEntity `json:"expenses"`
+}
+
+// ReanalyzePreview 重新分析预览
+// @Summary 重新分析预览
+// @Description 把账单保存的原始描述重新交给模型分析 (比如换了模型或改了分类之后)，返回与当前数据的差异，不落库。
+// @Description 只比较金额、分类和备注，日期不重新推断。没有原始描述的账单 (图片记账、账单导入) 带 error_code=no_description。最多 50 条。
+// @Tags Expense
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ReanalyzePreviewRequest true "账单 ID 或筛选条件"
+// @Success 200 {object} response.Response{data=controller.ReanalyzePreviewResponse}
+// @Router /expenses/reanalyze/preview [post]
+func (ctrl *ExpenseController) ReanalyzePreview(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req ReanalyzePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	filter := ListRequest{Category: req.Category, StartDate: req.StartDate, EndDate: req.EndDate}.
+		toFilter(userIDStr, ctrl.service.Location(ctx, userIDStr))
+	diffs, err := ctrl.service.PreviewReanalyze(ctx, userIDStr, req.IDs, filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidReanalyze) || errors.Is(err, service.ErrReanalyzeEmpty) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("重新分析预览失败", "uid", userIDStr, "error", err)
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, ReanalyzePreviewResponse{Diffs: diffs})
+}
+
+// ReanalyzeApply 确认应用重新分析
+// @Summary 应用重新分析
+// @Description 提交预览中确认 (可以修改过) 的结果，在一个事务里全部更新：任意一条不合法时整批都不保存。
+// @Tags Expense
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ReanalyzeApplyRequest true "确认的修改"
+// @Success 200 {object} response.Response{data=controller.ReanalyzeApplyResponse}
+// @Router /expenses/reanalyze/apply [post]
+func (ctrl *ExpenseController) ReanalyzeApply(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req ReanalyzeApplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	expenses, err := ctrl.service.ApplyReanalyze(c.Request.Context(), userIDStr, req.Changes)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidReanalyze) || errors.Is(err, service.ErrReanalyzeEmpty) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("应用重新分析失败", "uid", userIDStr, "error", err)
+		response.Error(c, http.StatusInternalServerError, "保存失败")
+		return
+	}
+	response.Success(c, ReanalyzeApplyResponse{Expenses: expenses})
+}
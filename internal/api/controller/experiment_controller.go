@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+// ExperimentController 管理 Prompt / 模型的 A/B 实验
+type ExperimentController struct {
+	service *service.ExperimentService
+}
+
+// NewExperimentController 构造函数
+func NewExperimentController(s *service.ExperimentService) *ExperimentController {
+	return &ExperimentController{service: s}
+}
+
+// ExperimentIDRequest 按 ID 操作实验
+type ExperimentIDRequest struct {
+	ID uint `form:"id" json:"id" binding:"required"`
+}
+
+// List 实验列表
+// @Summary A/B 实验列表
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} response.Response{data=[]model.Experiment}
+// @Router /admin/experiments [get]
+func (ctrl *ExperimentController) List(c *gin.Context) {
+	exps, err := ctrl.service.List(c.Request.Context())
+	if err != nil {
+		slog.Error("查询实验失败", "error", err)
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, exps)
+}
+
+// Create 创建实验
+// @Summary 创建 A/B 实验
+// @Description 每个分组指定一个 Prompt 版本和/或 llm.providers 里的一个模型后端，留空的沿用平时的配置 (全部留空即对照组)。
+// @Description 用户按 ID 哈希稳定分组，weight 是分流权重。管理员单独指定了 Prompt 版本的用户和图片记账不参与实验。创建后需要调用 start 才开始分流。
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body service.ExperimentInput true "实验配置"
+// @Success 200 {object} response.Response{data=model.Experiment}
+// @Router /admin/experiments [post]
+func (ctrl *ExperimentController) Create(c *gin.Context) {
+	var req service.ExperimentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	exp, err := ctrl.service.Create(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidExperiment) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("创建实验失败", "name", req.Name, "error", err)
+		response.Error(c, http.StatusInternalServerError, "创建失败")
+		return
+	}
+	response.Success(c, exp)
+}
+
+// Start 开始实验
+// @Summary 开始 A/B 实验
+// @Description 同一时间只有一个实验在进行，其他进行中的实验会被停止。
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body ExperimentIDRequest true "实验 ID"
+// @Success 200 {object} response.Response
+// @Router /admin/experiments/start [post]
+func (ctrl *ExperimentController) Start(c *gin.Context) {
+	ctrl.changeStatus(c, ctrl.service.Start)
+}
+
+// Stop 停止实验
+// @Summary 停止 A/B 实验
+// @Description 停止后的记账回到平时的配置，已有的数据保留在报表里。
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body ExperimentIDRequest true "实验 ID"
+// @Success 200 {object} response.Response
+// @Router /admin/experiments/stop [post]
+func (ctrl *ExperimentController) Stop(c *gin.Context) {
+	ctrl.changeStatus(c, ctrl.service.Stop)
+}
+
+func (ctrl *ExperimentController) changeStatus(c *gin.Context, change func(ctx context.Context, id uint) error) {
+	var req ExperimentIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := change(c.Request.Context(), req.ID); err != nil {
+		if errors.Is(err, service.ErrExperimentNotFound) {
+			response.Error(c, http.StatusNotFound, err.Error())
+			return
+		}
+		slog.Error("修改实验状态失败", "id", req.ID, "error", err)
+		response.Error(c, http.StatusInternalServerError, "操作失败")
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Report 实验报表
+// @Summary A/B 实验报表
+// @Description 按分组对比：模型调用次数、落库的账单数、其中被用户修改过分类/金额/备注的比例 (correction_rate)、吐槽被赞/被踩的条数、平均耗时和 token 用量。
+// @Description 只统计第一次记账时的识别 (文字记账、批量记账、支付通知)，重新分析和吐槽重新生成的调用不计入。
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Param id query int true "实验 ID"
+// @Success 200 {object} response.Response{data=service.ExperimentReport}
+// @Router /admin/experiments/report [get]
+func (ctrl *ExperimentController) Report(c *gin.Context) {
+	var req ExperimentIDRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	report, err := ctrl.service.Report(c.Request.Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, service.ErrExperimentNotFound) {
+			response.Error(c, http.StatusNotFound, err.Error())
+			return
+		}
+		slog.Error("生成实验报表失败", "id", req.ID, "error", err)
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, report)
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/ledger"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+	"github.com/leon37/FaceTaxLedger/internal/sheet"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ExportController 处理账单导出
+type ExportController struct {
+	service *service.ExportService
+}
+
+// NewExportController 构造函数
+func NewExportController(s *service.ExportService) *ExportController {
+	return &ExportController{service: s}
+}
+
+// JournalExportRequest 日记账导出参数，筛选条件与 ListRequest 一致 (分页参数会被忽略)
+type JournalExportRequest struct {
+	ListRequest
+	Format         string `form:"format,default=beancount"`
+	ExpenseRoot    string `form:"expense_root"`    // 覆盖默认的支出账户前缀
+	DefaultFunding string `form:"default_funding"` // 覆盖默认的付款账户
+	Currency       string `form:"currency"`
+}
+
+// 日记账文件扩展名
+var journalExtensions = map[string]string{
+	ledger.FormatBeancount: "beancount",
+	ledger.FormatHledger:   "journal",
+}
+
+// ExportJournal 导出 Beancount / hledger 日记账
+// @Summary 导出日记账
+// @Description 把筛选出的全部账单导出为 Beancount 或 hledger 日记账。分类映射为支出账户 (默认 Expenses:<分类>)，元数据中带 expense_id 和吐槽 (roast)。
+// @Tags Export
+// @Produce plain
+// @Security BearerAuth
+// @Param format query string false "beancount (默认) / hledger"
+// @Param category query string false "分类"
+// @Param start_date query string false "开始日期 2006-01-02"
+// @Param end_date query string false "结束日期 2006-01-02 (包含当天)"
+// @Param expense_root query string false "支出账户前缀，默认 Expenses"
+// @Param default_funding query string false "付款账户，默认 Assets:Cash"
+// @Param currency query string false "货币，默认 CNY"
+// @Success 200 {string} string "日记账文本"
+// @Router /expenses/export/journal [get]
+func (ctrl *ExportController) ExportJournal(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req JournalExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	ext, ok := journalExtensions[req.Format]
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "不支持的导出格式: "+req.Format)
+		return
+	}
+
+	scheme := ctrl.service.Scheme()
+	if req.ExpenseRoot != "" {
+		scheme.ExpenseRoot = req.ExpenseRoot
+	}
+	if req.DefaultFunding != "" {
+		scheme.DefaultFunding = req.DefaultFunding
+	}
+	if req.Currency != "" {
+		scheme.Currency = req.Currency
+	}
+
+	loc := ctrl.service.Location(c.Request.Context(), userIDStr)
+	filter := req.toFilter(userIDStr, loc)
+	fileName := fmt.Sprintf("facetax-%s.%s", time.Now().In(loc).Format("20060102"), ext)
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Status(http.StatusOK)
+
+	// 已经开始往响应里写了，出错只能记日志，客户端会收到一个截断的文件
+	if err := ctrl.service.ExportJournal(c.Request.Context(), c.Writer, filter, req.Format, scheme); err != nil {
+		slog.Error("日记账导出失败", "uid", userIDStr, "error", err)
+	}
+}
+
+// ExportCSV 导出 CSV
+// @Summary 导出 CSV
+// @Description 按与列表接口相同的筛选条件导出全部账单 (不分页)，列为 日期/分类/金额/备注/吐槽，UTF-8 带 BOM，可直接用 Excel 打开。
+// @Tags Export
+// @Produce text/csv
+// @Security BearerAuth
+// @Param category query string false "分类"
+// @Param start_date query string false "开始日期 2006-01-02"
+// @Param end_date query string false "结束日期 2006-01-02 (包含当天)"
+// @Success 200 {file} file "CSV 文件"
+// @Router /expenses/export/csv [get]
+func (ctrl *ExportController) ExportCSV(c *gin.Context) {
+	ctrl.exportTable(c, sheet.FormatCSV)
+}
+
+// ExportXLSX 导出 Excel
+// @Summary 导出 Excel
+// @Description 按与列表接口相同的筛选条件导出全部账单 (不分页)，列为 日期/分类/金额/备注/吐槽。
+// @Tags Export
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BearerAuth
+// @Param category query string false "分类"
+// @Param start_date query string false "开始日期 2006-01-02"
+// @Param end_date query string false "结束日期 2006-01-02 (包含当天)"
+// @Success 200 {file} file "XLSX 文件"
+// @Router /expenses/export/xlsx [get]
+func (ctrl *ExportController) ExportXLSX(c *gin.Context) {
+	ctrl.exportTable(c, sheet.FormatXLSX)
+}
+
+func (ctrl *ExportController) exportTable(c *gin.Context, format string) {
+	userIDStr := c.GetString("userID")
+
+	var req ListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	loc := ctrl.service.Location(c.Request.Context(), userIDStr)
+	filter := req.toFilter(userIDStr, loc)
+	fileName := fmt.Sprintf("facetax-%s.%s", time.Now().In(loc).Format("20060102"), format)
+	c.Header("Content-Type", sheet.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Status(http.StatusOK)
+
+	if err := ctrl.service.ExportTable(c.Request.Context(), c.Writer, filter, format); err != nil {
+		slog.Error("表格导出失败", "uid", userIDStr, "format", format, "error", err)
+	}
+}
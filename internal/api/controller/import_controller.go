@@ -0,0 +1,325 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/importer"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// 账单文件大小上限，几年的支付宝账单一般也就几 MB
+const maxBillSize = 20 << 20
+
+// ImportController 处理第三方账单导入
+type ImportController struct {
+	service *service.ImportService
+}
+
+// NewImportController 构造函数
+func NewImportController(s *service.ImportService) *ImportController {
+	return &ImportController{service: s}
+}
+
+// ImportBill 导入支付宝/微信支付账单
+// @Summary 导入支付宝/微信账单
+// @Description 上传支付宝或微信支付导出的账单 CSV，按交易单号去重，规则 + AI 批量分类后入账。dry_run=true 时只返回预览不落库。
+// @Tags Import
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "账单 CSV 文件"
+// @Param dry_run formData bool false "仅预览，不落库"
+// @Success 200 {object} response.Response{data=service.ImportReport}
+// @Router /imports/bill [post]
+func (ctrl *ImportController) ImportBill(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: 缺少 file 文件")
+		return
+	}
+	if fileHeader.Size > maxBillSize {
+		response.Error(c, http.StatusBadRequest, "账单文件过大，请按年份拆分后导入")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "文件读取失败")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxBillSize))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "文件读取失败")
+		return
+	}
+	dryRun := c.PostForm("dry_run") == "true"
+
+	report, err := ctrl.service.ImportBill(c.Request.Context(), userIDStr, data, dryRun)
+	if err != nil {
+		if errors.Is(err, importer.ErrUnknownFormat) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("账单导入失败", "error", err)
+		response.Error(c, http.StatusInternalServerError, "导入失败: "+err.Error())
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// ImportStatement 导入银行流水 (异步)
+// @Summary 导入银行流水
+// @Description 上传 CSV/OFX/QIF 银行流水，创建一个异步导入批次。CSV 需要通过 mapping (JSON) 或 profile_id 指定列映射。按 日期+金额+描述 去重。
+// @Tags Import
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "流水文件 (.csv/.ofx/.qfx/.qif)"
+// @Param profile_id formData int false "已保存的列映射方案 ID"
+// @Param mapping formData string false "列映射 JSON，优先级高于 profile_id"
+// @Success 200 {object} response.Response{data=model.ImportBatch}
+// @Router /imports/statement [post]
+func (ctrl *ImportController) ImportStatement(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: 缺少 file 文件")
+		return
+	}
+	if fileHeader.Size > maxBillSize {
+		response.Error(c, http.StatusBadRequest, "流水文件过大，请按年份拆分后导入")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "文件读取失败")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxBillSize))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "文件读取失败")
+		return
+	}
+
+	var profileID uint64
+	if raw := c.PostForm("profile_id"); raw != "" {
+		if profileID, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			response.Error(c, http.StatusBadRequest, "参数错误: profile_id 无效")
+			return
+		}
+	}
+	var mapping *model.ColumnMapping
+	if raw := c.PostForm("mapping"); raw != "" {
+		mapping = &model.ColumnMapping{}
+		if err := json.Unmarshal([]byte(raw), mapping); err != nil {
+			response.Error(c, http.StatusBadRequest, "参数错误: mapping 不是合法的 JSON")
+			return
+		}
+	}
+
+	batch, err := ctrl.service.StartStatementImport(c.Request.Context(), userIDStr, fileHeader.Filename, data, uint(profileID), mapping)
+	if err != nil {
+		slog.Warn("创建流水导入失败", "error", err)
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, batch)
+}
+
+// BatchIDRequest 按批次 ID 操作
+type BatchIDRequest struct {
+	ID uint `json:"id" form:"id" binding:"required"`
+}
+
+// ListBatches 导入批次列表
+// @Summary 导入批次列表
+// @Description 最近 50 个导入批次及其进度
+// @Tags Import
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]model.ImportBatch}
+// @Router /imports/batches [get]
+func (ctrl *ImportController) ListBatches(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	batches, err := ctrl.service.ListBatches(c.Request.Context(), userIDStr)
+	if err != nil {
+		slog.Error("获取导入批次失败", "error", err)
+		response.Error(c, http.StatusInternalServerError, "获取导入批次失败")
+		return
+	}
+	response.Success(c, batches)
+}
+
+// GetBatch 导入批次进度
+// @Summary 导入批次进度
+// @Description 查询单个导入批次的状态和进度 (processed/total)
+// @Tags Import
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "批次 ID"
+// @Success 200 {object} response.Response{data=model.ImportBatch}
+// @Router /imports/batches/detail [get]
+func (ctrl *ImportController) GetBatch(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req BatchIDRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	batch, err := ctrl.service.GetBatch(c.Request.Context(), userIDStr, req.ID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	response.Success(c, batch)
+}
+
+// ResumeBatch 继续导入
+// @Summary 继续导入
+// @Description 从断点继续一个失败的导入批次
+// @Tags Import
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BatchIDRequest true "批次 ID"
+// @Success 200 {object} response.Response{data=model.ImportBatch}
+// @Router /imports/batches/resume [post]
+func (ctrl *ImportController) ResumeBatch(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req BatchIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	batch, err := ctrl.service.ResumeBatch(c.Request.Context(), userIDStr, req.ID)
+	if err != nil {
+		slog.Warn("继续导入失败", "id", req.ID, "error", err)
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, batch)
+}
+
+// RollbackBatch 回滚导入批次
+// @Summary 回滚导入批次
+// @Description 删除该批次导入的全部账单及其 AI 记忆
+// @Tags Import
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BatchIDRequest true "批次 ID"
+// @Success 200 {object} response.Response{data=model.ImportBatch}
+// @Router /imports/batches/rollback [post]
+func (ctrl *ImportController) RollbackBatch(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req BatchIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	batch, err := ctrl.service.RollbackBatch(c.Request.Context(), userIDStr, req.ID)
+	if err != nil {
+		slog.Warn("回滚导入失败", "id", req.ID, "error", err)
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, batch)
+}
+
+// SaveProfileRequest 新建 (ID 为空) 或更新列映射方案
+type SaveProfileRequest struct {
+	ID      uint                `json:"id"`
+	Name    string              `json:"name" binding:"required,max=100"`
+	Mapping model.ColumnMapping `json:"mapping"`
+}
+
+// ListProfiles 列映射方案列表
+// @Summary 列映射方案列表
+// @Tags Import
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]model.ImportProfile}
+// @Router /imports/profiles [get]
+func (ctrl *ImportController) ListProfiles(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	profiles, err := ctrl.service.ListProfiles(c.Request.Context(), userIDStr)
+	if err != nil {
+		slog.Error("获取映射方案失败", "error", err)
+		response.Error(c, http.StatusInternalServerError, "获取映射方案失败")
+		return
+	}
+	response.Success(c, profiles)
+}
+
+// SaveProfile 保存列映射方案
+// @Summary 保存列映射方案
+// @Description 保存某家银行流水 CSV 的列映射，下次导入时通过 profile_id 复用
+// @Tags Import
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SaveProfileRequest true "映射方案"
+// @Success 200 {object} response.Response{data=model.ImportProfile}
+// @Router /imports/profiles [post]
+func (ctrl *ImportController) SaveProfile(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req SaveProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	profile := &model.ImportProfile{ID: req.ID, Name: req.Name, Mapping: req.Mapping}
+	if err := ctrl.service.SaveProfile(c.Request.Context(), userIDStr, profile); err != nil {
+		slog.Warn("保存映射方案失败", "error", err)
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, profile)
+}
+
+// ProfileIDRequest 按映射方案 ID 操作
+type ProfileIDRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// DeleteProfile 删除列映射方案
+// @Summary 删除列映射方案
+// @Tags Import
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ProfileIDRequest true "映射方案 ID"
+// @Success 200 {object} response.Response "成功"
+// @Router /imports/profiles/delete [post]
+func (ctrl *ImportController) DeleteProfile(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req ProfileIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if err := ctrl.service.DeleteProfile(c.Request.Context(), userIDStr, req.ID); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+// ModerationController 管理员查看没通过内容检查的吐槽
+type ModerationController struct {
+	service *service.ModerationService
+}
+
+// NewModerationController 构造函数
+func NewModerationController(s *service.ModerationService) *ModerationController {
+	return &ModerationController{service: s}
+}
+
+// ModerationLogListRequest 检查日志筛选条件，都可以不填
+type ModerationLogListRequest struct {
+	UserID   string `form:"user_id"`
+	Category string `form:"category"` // protected_attribute / self_harm / harassment / other
+	Page     int    `form:"page,default=1"`
+	PageSize int    `form:"page_size,default=20"`
+}
+
+// ModerationLogListResponse 检查日志列表
+type ModerationLogListResponse struct {
+	List  []model.ModerationLog `json:"list"`
+	Total int64                 `json:"total"`
+	Page  int                   `json:"page"`
+}
+
+// ListLogs 内容检查日志
+// @Summary 吐槽内容检查日志
+// @Description 模型生成的吐槽落库前会经过屏蔽词和 (配置了的话) 外部分类器检查，没通过的先重新生成，仍没通过就换成兜底文案。
+// @Description 每条没通过的吐槽一条记录：命中的类别和原因、给出结论的检查器、处理方式 (regenerate / replace) 和最终写进账单的吐槽。
+// @Description 前端、账单和 done 事件拿到的都是检查后的吐槽，SSE 记账也不会提前推送原始吐槽。expense_id 为 0 的记录是批量预览或没有落库的识别。最新的在前。
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Param user_id query string false "用户 ID"
+// @Param category query string false "类别"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页条数"
+// @Success 200 {object} response.Response{data=controller.ModerationLogListResponse}
+// @Router /admin/moderation/logs [get]
+func (ctrl *ModerationController) ListLogs(c *gin.Context) {
+	var req ModerationLogListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	logs, total, err := ctrl.service.List(c.Request.Context(), repository.ModerationLogFilter{
+		UserID:   req.UserID,
+		Category: req.Category,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	})
+	if err != nil {
+		slog.Error("查询内容检查日志失败", "error", err)
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, ModerationLogListResponse{List: logs, Total: total, Page: req.Page})
+}
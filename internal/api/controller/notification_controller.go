@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+	"log/slog"
+	"net/http"
+)
+
+// NotificationController 处理银行短信/支付通知导入
+type NotificationController struct {
+	service *service.NotificationService
+}
+
+// NewNotificationController 构造函数
+func NewNotificationController(s *service.NotificationService) *NotificationController {
+	return &NotificationController{service: s}
+}
+
+// NotificationImportRequest 粘贴的通知原文，多条之间用换行或空行分隔
+type NotificationImportRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+type NotificationImportResponse struct {
+	Results []service.NotificationResult `json:"results"`
+	Success int                          `json:"success"`
+	Failed  int                          `json:"failed"`
+}
+
+// Import 通知批量记账
+// @Summary 银行短信/支付通知记账
+// @Description 粘贴一条或多条银行短信、支付宝/微信支付通知，按模板提取金额、商户、尾号和时间后入账，AI 只负责分类和吐槽。
+// @Tags Expense
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body NotificationImportRequest true "通知原文"
+// @Success 200 {object} response.Response{data=controller.NotificationImportResponse}
+// @Router /expenses/notifications [post]
+func (ctrl *NotificationController) Import(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req NotificationImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	results, err := ctrl.service.Import(c.Request.Context(), userIDStr, req.Text)
+	if err != nil {
+		slog.Warn("通知导入失败", "error", err)
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rsp := NotificationImportResponse{Results: results}
+	for _, r := range results {
+		if r.Expense != nil {
+			rsp.Success++
+		} else {
+			rsp.Failed++
+		}
+	}
+	response.Success(c, rsp)
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+// PromptController 管理 Prompt 模板版本
+type PromptController struct {
+	service *service.PromptService
+}
+
+// NewPromptController 构造函数
+func NewPromptController(s *service.PromptService) *PromptController {
+	return &PromptController{service: s}
+}
+
+// PromptVersionRequest 按版本号查询模板
+type PromptVersionRequest struct {
+	Version string `form:"version" binding:"required"`
+}
+
+// PromptDetailResponse 模板原文
+type PromptDetailResponse struct {
+	Version string `json:"version"`
+	Source  string `json:"source"`
+}
+
+// AssignPromptRequest 给用户指定模板版本
+type AssignPromptRequest struct {
+	UserID  string `json:"user_id" binding:"required"`
+	Version string `json:"version"` // 为空时恢复为默认版本
+}
+
+// List Prompt 模板版本
+// @Summary Prompt 模板版本列表
+// @Description 代码内置的版本 (builtin-N) 总是可用；prompt.dir 目录里的 .tmpl 文件各是一个版本，文件改动后几秒内自动生效，不需要重启。
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} response.Response{data=service.PromptVersions}
+// @Router /admin/prompts [get]
+func (ctrl *PromptController) List(c *gin.Context) {
+	response.Success(c, ctrl.service.Versions())
+}
+
+// Get Prompt 模板原文
+// @Summary Prompt 模板原文
+// @Description 新版本可以复制内置版本的原文改写。
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Param version query string true "版本号"
+// @Success 200 {object} response.Response{data=controller.PromptDetailResponse}
+// @Router /admin/prompts/detail [get]
+func (ctrl *PromptController) Get(c *gin.Context) {
+	var req PromptVersionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	t, err := ctrl.service.Get(req.Version)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	response.Success(c, PromptDetailResponse{Version: t.Version, Source: t.Source})
+}
+
+// Assign 给用户指定 Prompt 模板版本
+// @Summary 给用户指定 Prompt 版本
+// @Description 之后这个用户的记账都使用指定的版本；version 为空时恢复为默认版本。
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body AssignPromptRequest true "用户和版本"
+// @Success 200 {object} response.Response
+// @Router /admin/prompts/assign [post]
+func (ctrl *PromptController) Assign(c *gin.Context) {
+	var req AssignPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := ctrl.service.AssignUser(c.Request.Context(), req.UserID, req.Version); err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownPromptVersion):
+			response.Error(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrUserNotFound):
+			response.Error(c, http.StatusNotFound, err.Error())
+		default:
+			slog.Error("指定 Prompt 版本失败", "uid", req.UserID, "version", req.Version, "error", err)
+			response.Error(c, http.StatusInternalServerError, "保存失败")
+		}
+		return
+	}
+	response.Success(c, nil)
+}
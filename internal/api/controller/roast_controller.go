@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+// RoastController 吐槽的反馈和统计
+type RoastController struct {
+	service *service.RoastService
+}
+
+// NewRoastController 构造函数
+func NewRoastController(s *service.RoastService) *RoastController {
+	return &RoastController{service: s}
+}
+
+// RoastFeedbackRequest 给账单的吐槽点赞或点踩
+type RoastFeedbackRequest struct {
+	ExpenseID uint `json:"expense_id" binding:"required"`
+	Feedback  *int `json:"feedback" binding:"required" example:"1"` // 1 赞，-1 踩，0 撤销
+}
+
+// Feedback 给吐槽点赞或点踩
+// @Summary 吐槽反馈
+// @Description 对账单的 comment 点赞或点踩。之后生成吐槽时，最近说过的和被踩的不会再重复，被赞的作为风格参考。
+// @Tags Expense
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body RoastFeedbackRequest true "账单和反馈"
+// @Success 200 {object} response.Response
+// @Router /expenses/comment/feedback [post]
+func (ctrl *RoastController) Feedback(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req RoastFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := ctrl.service.Feedback(c.Request.Context(), userIDStr, req.ExpenseID, *req.Feedback); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidFeedback), errors.Is(err, service.ErrNoComment):
+			response.Error(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrExpenseNotFound):
+			response.Error(c, http.StatusNotFound, err.Error())
+		default:
+			slog.Error("保存吐槽反馈失败", "uid", userIDStr, "expense", req.ExpenseID, "error", err)
+			response.Error(c, http.StatusInternalServerError, "保存失败")
+		}
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Stats 吐槽反馈统计
+// @Summary 吐槽反馈统计
+// @Description 按人设和 Prompt 版本汇总吐槽条数、被赞和被踩的条数，like_rate = 赞 / (赞 + 踩)。persona 为空的是功能上线前的吐槽。
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} response.Response{data=[]repository.RoastStats}
+// @Router /admin/roasts/stats [get]
+func (ctrl *RoastController) Stats(c *gin.Context) {
+	stats, err := ctrl.service.Stats(c.Request.Context())
+	if err != nil {
+		slog.Error("查询吐槽统计失败", "error", err)
+		response.Error(c, http.StatusInternalServerError, "查询失败")
+		return
+	}
+	response.Success(c, stats)
+}
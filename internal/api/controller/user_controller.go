@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leon37/FaceTaxLedger/internal/api/response"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+	"github.com/leon37/FaceTaxLedger/internal/service"
+)
+
+// UserController 处理用户资料
+type UserController struct {
+	service *service.UserService
+}
+
+// NewUserController 构造函数
+func NewUserController(s *service.UserService) *UserController {
+	return &UserController{service: s}
+}
+
+// UpdateProfileRequest 修改用户资料
+type UpdateProfileRequest struct {
+	TimeZone string `json:"timezone" example:"America/New_York"` // IANA 时区名，传空串恢复默认时区
+}
+
+// UpdateRoastRequest 修改吐槽人设和力度
+type UpdateRoastRequest struct {
+	Persona   string `json:"persona" example:"mom"`                    // 人设 ID，传空串恢复默认人设
+	Intensity *int   `json:"intensity" binding:"required" example:"2"` // 0 不吐槽，1 轻微调侃，2 辛辣幽默，3 火力全开
+}
+
+// GetProfile 查询用户资料
+// @Summary 用户资料
+// @Description 返回当前用户的资料。未设置时区时 timezone 为服务端默认时区。
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=model.User}
+// @Router /users/profile [get]
+func (ctrl *UserController) GetProfile(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	user, err := ctrl.service.GetProfile(c.Request.Context(), userIDStr)
+	if err != nil {
+		slog.Error("查询用户资料失败", "uid", userIDStr, "error", err)
+		response.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+	response.Success(c, user)
+}
+
+// UpdateProfile 修改用户资料
+// @Summary 修改用户资料
+// @Description 设置用户所在时区。记账时的 "今天/昨天"、模型提示词中的当前时间、列表筛选和导出的日期边界都按该时区计算。
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateProfileRequest true "用户资料"
+// @Success 200 {object} response.Response
+// @Router /users/profile/update [post]
+func (ctrl *UserController) UpdateProfile(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := ctrl.service.UpdateTimeZone(c.Request.Context(), userIDStr, req.TimeZone); err != nil {
+		if errors.Is(err, service.ErrInvalidTimeZone) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("修改用户资料失败", "uid", userIDStr, "error", err)
+		response.Error(c, http.StatusInternalServerError, "修改失败")
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ListPersonas 可选的吐槽人设
+// @Summary 吐槽人设列表
+// @Description 第一个是默认人设。当前选择的人设和力度见用户资料的 roast_persona / roast_intensity。
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]prompt.Persona}
+// @Router /users/roast/personas [get]
+func (ctrl *UserController) ListPersonas(c *gin.Context) {
+	response.Success(c, prompt.Personas)
+}
+
+// UpdateRoast 修改吐槽人设和力度
+// @Summary 修改吐槽人设和力度
+// @Description 之后记账时的 comment 按选择的人设和力度生成；力度为 0 时不再吐槽，comment 为空。
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateRoastRequest true "人设和力度"
+// @Success 200 {object} response.Response
+// @Router /users/roast/update [post]
+func (ctrl *UserController) UpdateRoast(c *gin.Context) {
+	userIDStr := c.GetString("userID")
+
+	var req UpdateRoastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := ctrl.service.UpdateRoast(c.Request.Context(), userIDStr, req.Persona, *req.Intensity); err != nil {
+		if errors.Is(err, service.ErrUnknownPersona) || errors.Is(err, service.ErrInvalidIntensity) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		slog.Error("修改吐槽设置失败", "uid", userIDStr, "error", err)
+		response.Error(c, http.StatusInternalServerError, "修改失败")
+		return
+	}
+	response.Success(c, nil)
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// AdminAuth 管理接口鉴权：请求头 X-Admin-Token 与配置的 server.admin_token 一致才放行
+// 没有配置 admin_token 时管理接口全部关闭
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := viper.GetString("server.admin_token")
+		if expected == "" {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "msg": "Admin API disabled"})
+			c.Abort()
+			return
+		}
+
+		token := c.GetHeader("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "Invalid admin token"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -30,3 +30,12 @@ func Error(c *gin.Context, httpStatus int, msg string) {
 		Data: nil,
 	})
 }
+
+// ErrorWithData 错误响应，附带结构化的错误详情 (如原因码)
+func ErrorWithData(c *gin.Context, httpStatus int, msg string, data interface{}) {
+	c.JSON(httpStatus, Response{
+		Code: -1,
+		Msg:  msg,
+		Data: data,
+	})
+}
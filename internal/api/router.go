@@ -11,7 +11,7 @@ import (
 )
 
 // RegisterRoutes 注册所有路由
-func RegisterRoutes(r *gin.Engine, authCtrl *controller.AuthController, expenseCtrl *controller.ExpenseController) {
+func RegisterRoutes(r *gin.Engine, authCtrl *controller.AuthController, expenseCtrl *controller.ExpenseController, notificationCtrl *controller.NotificationController, importCtrl *controller.ImportController, exportCtrl *controller.ExportController, userCtrl *controller.UserController, jobCtrl *controller.AnalysisJobController, auditCtrl *controller.AuditController, promptCtrl *controller.PromptController, experimentCtrl *controller.ExperimentController, roastCtrl *controller.RoastController, moderationCtrl *controller.ModerationController) {
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
@@ -28,9 +28,54 @@ func RegisterRoutes(r *gin.Engine, authCtrl *controller.AuthController, expenseC
 	protected := r.Group("/api/v1")
 	protected.Use(middleware.JWTAuth())
 	{
+		protected.GET("/users/profile", userCtrl.GetProfile)
+		protected.POST("/users/profile/update", userCtrl.UpdateProfile)
+		protected.GET("/users/roast/personas", userCtrl.ListPersonas)
+		protected.POST("/users/roast/update", userCtrl.UpdateRoast)
 		protected.POST("/expenses/analyze", expenseCtrl.Analyze)
+		protected.GET("/expenses/analyze/resume", expenseCtrl.ResumeAnalyze)
+		protected.POST("/expenses/analyze/sync", expenseCtrl.AnalyzeSync)
+		protected.POST("/expenses/bulk/preview", expenseCtrl.BulkPreview)
+		protected.POST("/expenses/bulk/commit", expenseCtrl.BulkCommit)
+		protected.POST("/expenses/reanalyze/preview", expenseCtrl.ReanalyzePreview)
+		protected.POST("/expenses/reanalyze/apply", expenseCtrl.ReanalyzeApply)
+		protected.POST("/expenses/jobs", jobCtrl.Submit)
+		protected.GET("/expenses/jobs/detail", jobCtrl.Get)
+		protected.POST("/expenses/receipt", expenseCtrl.AnalyzeReceipt)
 		protected.GET("/expenses", expenseCtrl.List)
 		protected.POST("/expenses/delete", expenseCtrl.Delete)
 		protected.POST("/expenses/update", expenseCtrl.Update)
+		protected.POST("/expenses/comment/feedback", roastCtrl.Feedback)
+		protected.POST("/expenses/notifications", notificationCtrl.Import)
+		protected.GET("/expenses/export/journal", exportCtrl.ExportJournal)
+		protected.GET("/expenses/export/csv", exportCtrl.ExportCSV)
+		protected.GET("/expenses/export/xlsx", exportCtrl.ExportXLSX)
+		protected.POST("/imports/bill", importCtrl.ImportBill)
+		protected.POST("/imports/statement", importCtrl.ImportStatement)
+		protected.GET("/imports/batches", importCtrl.ListBatches)
+		protected.GET("/imports/batches/detail", importCtrl.GetBatch)
+		protected.POST("/imports/batches/resume", importCtrl.ResumeBatch)
+		protected.POST("/imports/batches/rollback", importCtrl.RollbackBatch)
+		protected.GET("/imports/profiles", importCtrl.ListProfiles)
+		protected.POST("/imports/profiles", importCtrl.SaveProfile)
+		protected.POST("/imports/profiles/delete", importCtrl.DeleteProfile)
+	}
+
+	// 管理接口，用 X-Admin-Token 鉴权
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.AdminAuth())
+	{
+		admin.GET("/audits", auditCtrl.List)
+		admin.GET("/audits/detail", auditCtrl.Get)
+		admin.GET("/prompts", promptCtrl.List)
+		admin.GET("/prompts/detail", promptCtrl.Get)
+		admin.POST("/prompts/assign", promptCtrl.Assign)
+		admin.GET("/experiments", experimentCtrl.List)
+		admin.POST("/experiments", experimentCtrl.Create)
+		admin.POST("/experiments/start", experimentCtrl.Start)
+		admin.POST("/experiments/stop", experimentCtrl.Stop)
+		admin.GET("/experiments/report", experimentCtrl.Report)
+		admin.GET("/roasts/stats", roastCtrl.Stats)
+		admin.GET("/moderation/logs", moderationCtrl.ListLogs)
 	}
 }
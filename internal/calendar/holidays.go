@@ -0,0 +1,110 @@
+package calendar
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// holidays.json 按公历年份列出农历节日和清明的日期，每年初补上新一年的即可
+// 除夕、元宵由春节推算，公历固定日期的节日不进表
+//
+//go:embed holidays.json
+var holidayData []byte
+
+// lunarTable 年份 -> 节日 -> 日期 (YYYY-MM-DD)
+var lunarTable = func() map[int]map[string]string {
+	var raw map[string]map[string]string
+	if err := json.Unmarshal(holidayData, &raw); err != nil {
+		panic("calendar: holidays.json 格式错误: " + err.Error())
+	}
+	table := make(map[int]map[string]string, len(raw))
+	for y, days := range raw {
+		year, err := strconv.Atoi(y)
+		if err != nil {
+			panic("calendar: holidays.json 年份格式错误: " + y)
+		}
+		table[year] = days
+	}
+	return table
+}()
+
+// holiday 一个节日的写法及其日期算法
+type holiday struct {
+	alias string
+	// 公历固定日期
+	month, day int
+	// 查表的农历节日，offset 是相对表中日期的天数
+	lunar  string
+	offset int
+}
+
+// holidays 长的写法在前，避免 "春节" 抢了 "大年初一" 之类的匹配
+// "五一"、"六一"、"十一" 单独出现时多半是金额或数量，只认带后缀的写法
+var holidays = []holiday{
+	{alias: "大年三十", lunar: "春节", offset: -1},
+	{alias: "大年初一", lunar: "春节"},
+	{alias: "正月十五", lunar: "春节", offset: 14},
+	{alias: "年三十", lunar: "春节", offset: -1},
+	{alias: "除夕", lunar: "春节", offset: -1},
+	{alias: "春节", lunar: "春节"},
+	{alias: "元宵", lunar: "春节", offset: 14},
+	{alias: "清明", lunar: "清明"},
+	{alias: "端午", lunar: "端午"},
+	{alias: "中秋", lunar: "中秋"},
+	{alias: "五一假期", month: 5, day: 1},
+	{alias: "五一节", month: 5, day: 1},
+	{alias: "劳动节", month: 5, day: 1},
+	{alias: "十一假期", month: 10, day: 1},
+	{alias: "国庆", month: 10, day: 1},
+	{alias: "元旦", month: 1, day: 1},
+	{alias: "情人节", month: 2, day: 14},
+	{alias: "妇女节", month: 3, day: 8},
+	{alias: "儿童节", month: 6, day: 1},
+	{alias: "双十一", month: 11, day: 11},
+	{alias: "双11", month: 11, day: 11},
+	{alias: "双十二", month: 12, day: 12},
+	{alias: "双12", month: 12, day: 12},
+	{alias: "平安夜", month: 12, day: 24},
+	{alias: "圣诞", month: 12, day: 25},
+}
+
+// on 节日在某一年的日期 (零点)，农历节日超出表的范围时返回 false
+func (h *holiday) on(year int, loc *time.Location) (time.Time, bool) {
+	if h.lunar == "" {
+		return time.Date(year, time.Month(h.month), h.day, 0, 0, 0, 0, loc), true
+	}
+	s, ok := lunarTable[year][h.lunar]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.AddDate(0, 0, h.offset), true
+}
+
+// findHoliday 文本中最先出现的节日
+func findHoliday(text string) (*holiday, int) {
+	var found *holiday
+	at := -1
+	for i := range holidays {
+		if j := strings.Index(text, holidays[i].alias); j >= 0 && (at < 0 || j < at) {
+			found, at = &holidays[i], j
+		}
+	}
+	return found, at
+}
+
+// Holiday 按名字查某一年的节日日期，name 可以是任意一种写法 ("除夕"、"大年三十")
+func Holiday(name string, year int, loc *time.Location) (time.Time, bool) {
+	for i := range holidays {
+		if holidays[i].alias == name {
+			return holidays[i].on(year, loc)
+		}
+	}
+	return time.Time{}, false
+}
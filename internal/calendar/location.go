@@ -0,0 +1,21 @@
+package calendar
+
+import (
+	"time"
+	_ "time/tzdata" // 精简镜像里可能没有 zoneinfo，内置一份
+)
+
+// DefaultTimeZone 没有配置时区时使用的时区
+const DefaultTimeZone = "Asia/Shanghai"
+
+// Location 按 IANA 名字 (如 "Asia/Shanghai") 加载时区，名字为空或无法识别时返回 fallback
+func Location(name string, fallback *time.Location) *time.Location {
+	if name == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
@@ -0,0 +1,208 @@
+// Package calendar 从记账描述里确定性地解析消费日期：相对日期、星期、节假日、具体日期
+package calendar
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/leon37/FaceTaxLedger/internal/cnnum"
+)
+
+// 相对日期关键词 -> 距今天数，长词在前，避免 "前天" 抢了 "大前天"
+var relativeDays = []struct {
+	word   string
+	offset int
+}{
+	{"大前天", -3}, {"前天", -2}, {"前日", -2},
+	{"昨天", -1}, {"昨晚", -1}, {"昨日", -1}, {"昨儿", -1}, {"昨早", -1}, {"昨夜", -1},
+	{"今天", 0}, {"今晚", 0}, {"今早", 0}, {"今日", 0}, {"今儿", 0}, {"刚才", 0}, {"刚刚", 0},
+}
+
+// 月、日允许写成中文 ("三月五号")
+const monthDayNumber = `(\d{1,2}|[一二三四五六七八九十]{1,3})`
+
+var (
+	daysAgoPattern = regexp.MustCompile(`(\d+|[` + cnnum.Chars + `]+)\s*天(?:前|以前|之前)`)
+	weekdayPattern = regexp.MustCompile(`(上上|上|这|本)?个?(?:周|星期|礼拜)([一二三四五六日天1-7])`)
+	fullDate       = regexp.MustCompile(`(\d{4})\s*[-/.年]\s*(\d{1,2})\s*[-/.月]\s*(\d{1,2})\s*[日号]?`)
+	monthDay       = regexp.MustCompile(monthDayNumber + `\s*月\s*` + monthDayNumber + `\s*[日号]?`)
+	// "3/2" 只认斜杠，"3.2" 更可能是金额；前后不能紧挨数字或斜杠，避免吃掉 "1/2/3" 这类内容
+	slashMonthDay    = regexp.MustCompile(`(?:^|[^\d/])(\d{1,2})/(\d{1,2})(?:[^\d/]|$)`)
+	lastMonthDay     = regexp.MustCompile(`上个?月\s*` + monthDayNumber + `\s*[日号]`)
+	dayOnly          = regexp.MustCompile(`(\d{1,2})\s*号`)
+	yearPrefixOffset = map[string]int{"前年": -2, "去年": -1, "今年": 0}
+)
+
+var weekdayNumbers = map[string]int{
+	"一": 1, "二": 2, "三": 3, "四": 4, "五": 5, "六": 6, "日": 7, "天": 7,
+	"1": 1, "2": 2, "3": 3, "4": 4, "5": 5, "6": 6, "7": 7,
+}
+
+// Match 从文本中识别出的日期
+type Match struct {
+	Date       time.Time // now 所在时区的零点
+	Start, End int       // 命中的字节区间，调用方可以据此把日期从文本里摘掉
+	Expr       string    // 命中的原文，例如 "上周五"、"去年国庆"
+}
+
+// Resolve 从文本中识别消费日期，日期按 now 的时区计算
+// 记账说的都是过去的事：没写年份的日期落在未来时，按上一个周期 (去年 / 上个月 / 上周) 处理
+// 没有日期表达时 ok 为 false，调用方按今天处理
+func Resolve(text string, now time.Time) (Match, bool) {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	match := func(t time.Time, start, end int) (Match, bool) {
+		return Match{Date: t, Start: start, End: end, Expr: text[start:end]}, true
+	}
+
+	if m := fullDate.FindStringSubmatchIndex(text); m != nil {
+		y, _ := strconv.Atoi(text[m[2]:m[3]])
+		mo, _ := strconv.Atoi(text[m[4]:m[5]])
+		d, _ := strconv.Atoi(text[m[6]:m[7]])
+		if t, ok := makeDate(y, mo, d, loc); ok {
+			return match(t, m[0], m[1])
+		}
+	}
+	if m := lastMonthDay.FindStringSubmatchIndex(text); m != nil {
+		if d, ok := number(text[m[2]:m[3]]); ok {
+			first := time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, loc)
+			if t, ok := makeDate(first.Year(), int(first.Month()), d, loc); ok {
+				return match(t, m[0], m[1])
+			}
+		}
+	}
+	if m := monthDay.FindStringSubmatchIndex(text); m != nil {
+		mo, ok1 := number(text[m[2]:m[3]])
+		d, ok2 := number(text[m[4]:m[5]])
+		if ok1 && ok2 {
+			start, offset, explicit := yearPrefix(text, m[0])
+			if t, ok := makeDate(today.Year()+offset, mo, d, loc); ok {
+				if !explicit && t.After(today) {
+					t = t.AddDate(-1, 0, 0)
+				}
+				return match(t, start, m[1])
+			}
+		}
+	}
+	if m := slashMonthDay.FindStringSubmatchIndex(text); m != nil {
+		mo, _ := strconv.Atoi(text[m[2]:m[3]])
+		d, _ := strconv.Atoi(text[m[4]:m[5]])
+		if t, ok := makeDate(today.Year(), mo, d, loc); ok {
+			if t.After(today) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return match(t, m[2], m[5])
+		}
+	}
+	if h, i := findHoliday(text); h != nil {
+		start, offset, explicit := yearPrefix(text, i)
+		year := today.Year() + offset
+		t, ok := h.on(year, loc)
+		if ok && !explicit && t.After(today) {
+			t, ok = h.on(year-1, loc)
+		}
+		if ok {
+			return match(t, start, i+len(h.alias))
+		}
+	}
+	if m := weekdayPattern.FindStringSubmatchIndex(text); m != nil {
+		prefix := ""
+		if m[2] >= 0 {
+			prefix = text[m[2]:m[3]]
+		}
+		target := weekdayNumbers[text[m[4]:m[5]]]
+		// 中国习惯周一是一周的第一天
+		current := int(today.Weekday())
+		if current == 0 {
+			current = 7
+		}
+		monday := today.AddDate(0, 0, 1-current)
+		var t time.Time
+		switch prefix {
+		case "上上":
+			t = monday.AddDate(0, 0, target-1-14)
+		case "上":
+			t = monday.AddDate(0, 0, target-1-7)
+		case "这", "本":
+			t = monday.AddDate(0, 0, target-1)
+		default:
+			// 只说 "周五"：最近的一个周五 (含今天)
+			t = monday.AddDate(0, 0, target-1)
+			if t.After(today) {
+				t = t.AddDate(0, 0, -7)
+			}
+		}
+		return match(t, m[0], m[1])
+	}
+	if m := daysAgoPattern.FindStringSubmatchIndex(text); m != nil {
+		if n, ok := number(text[m[2]:m[3]]); ok {
+			return match(today.AddDate(0, 0, -n), m[0], m[1])
+		}
+	}
+	for _, r := range relativeDays {
+		if i := strings.Index(text, r.word); i >= 0 {
+			return match(today.AddDate(0, 0, r.offset), i, i+len(r.word))
+		}
+	}
+	if m := findDayOnly(text); m != nil {
+		d, _ := strconv.Atoi(text[m[2]:m[3]])
+		if t, ok := makeDate(today.Year(), int(today.Month()), d, loc); ok {
+			if t.After(today) {
+				// 退到上个月时重新校验，上个月不一定有 31 号
+				first := time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, loc)
+				if t, ok = makeDate(first.Year(), int(first.Month()), d, loc); !ok {
+					return Match{Date: today}, false
+				}
+			}
+			return match(t, m[0], m[1])
+		}
+	}
+	return Match{Date: today}, false
+}
+
+// 紧跟在 "N号" 后面时说明是线路、楼栋、座位这类编号，不是日期
+const dayOnlyNotDate = "线楼栋店门口厅机位床柜房室馆车道桥站台厂院屋街路巷窗桌包座层仓"
+
+// findDayOnly 找出只写了 "N号" 的日期，跳过 "2号线"、"3号楼"、"第5号"、"几号" 和更长数字的尾巴
+func findDayOnly(text string) []int {
+	for _, m := range dayOnly.FindAllStringSubmatchIndex(text, -1) {
+		prev, _ := utf8.DecodeLastRuneInString(text[:m[2]])
+		next, _ := utf8.DecodeRuneInString(text[m[1]:])
+		if unicode.IsDigit(prev) || prev == '第' || prev == '几' || strings.ContainsRune(dayOnlyNotDate, next) {
+			continue
+		}
+		return m
+	}
+	return nil
+}
+
+// yearPrefix 日期前面紧跟的 "去年"、"前年"、"今年"，返回扩展后的起点和年份偏移
+func yearPrefix(text string, start int) (int, int, bool) {
+	for word, offset := range yearPrefixOffset {
+		if strings.HasSuffix(text[:start], word) {
+			return start - len(word), offset, true
+		}
+	}
+	return start, 0, false
+}
+
+// number 阿拉伯数字或中文数字
+func number(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	return cnnum.Parse(s)
+}
+
+// makeDate 校验年月日是否合法 (time.Date 会把 2 月 30 日悄悄进位成 3 月)
+func makeDate(y, m, d int, loc *time.Location) (time.Time, bool) {
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, loc)
+	if t.Year() != y || int(t.Month()) != m || t.Day() != d {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,73 @@
+// Package cnnum 解析口语化的中文数字 ("一百二"、"两千五")
+package cnnum
+
+import "strings"
+
+var digits = map[rune]int{
+	'零': 0, '〇': 0, '一': 1, '壹': 1, '二': 2, '贰': 2, '两': 2, '三': 3, '叁': 3, '四': 4, '肆': 4,
+	'五': 5, '伍': 5, '六': 6, '陆': 6, '七': 7, '柒': 7, '八': 8, '捌': 8, '九': 9, '玖': 9,
+}
+
+var units = map[rune]int{'十': 10, '拾': 10, '百': 100, '佰': 100, '千': 1000, '仟': 1000, '万': 10000}
+
+// Chars 正则字符类里用的中文数字字符集
+const Chars = "零〇一壹二贰两三叁四肆五伍六陆七柒八捌九玖十拾百佰千仟万"
+
+// Parse 解析中文整数，支持口语省略写法："一百二" = 120，"两千五" = 2500，"十五" = 15
+func Parse(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+
+	total, section, digit := 0, 0, -1
+	lastUnit := 0
+	for _, r := range s {
+		if d, ok := digits[r]; ok {
+			if d == 0 {
+				// "一百零五" 有显式的零，末尾数字就是个位
+				lastUnit = 1
+			}
+			digit = d
+			continue
+		}
+		unit, ok := units[r]
+		if !ok {
+			return 0, false
+		}
+		if unit == 10000 {
+			if digit >= 0 {
+				section += digit
+			}
+			total += section * unit
+			section, digit, lastUnit = 0, -1, unit
+			continue
+		}
+		if digit < 0 {
+			// "十五" 省略了前面的 "一"
+			digit = 1
+		}
+		section += digit * unit
+		digit, lastUnit = -1, unit
+	}
+	if digit >= 0 {
+		// "一百二" 末尾的数字省略了单位，按上一级单位的十分之一算
+		if lastUnit >= 100 {
+			section += digit * lastUnit / 10
+		} else {
+			section += digit
+		}
+	}
+	return total + section, true
+}
+
+// Digit 单个数字字符 ("5"、"五") 的值，不是数字时返回 -1
+func Digit(r rune) int {
+	if r >= '0' && r <= '9' {
+		return int(r - '0')
+	}
+	if d, ok := digits[r]; ok {
+		return d
+	}
+	return -1
+}
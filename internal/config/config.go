@@ -2,19 +2,38 @@ package config
 
 import (
 	"fmt"
+	"github.com/leon37/FaceTaxLedger/internal/ledger"
 	"github.com/spf13/viper"
+	"time"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Qdrant   QdrantConfig   `mapstructure:"qdrant"`
-	OpenAI   ModelConfig    `mapstructure:"openai"`
-	DeepSeek ModelConfig    `mapstructure:"deepseek"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Qdrant     QdrantConfig     `mapstructure:"qdrant"`
+	OpenAI     ModelConfig      `mapstructure:"openai"`
+	DeepSeek   ModelConfig      `mapstructure:"deepseek"`
+	Vision     ModelConfig      `mapstructure:"vision"`
+	LLM        LLMConfig        `mapstructure:"llm"`
+	Ledger     LedgerConfig     `mapstructure:"ledger"`
+	Jobs       JobsConfig       `mapstructure:"jobs"`
+	Prompt     PromptConfig     `mapstructure:"prompt"`
+	Moderation ModerationConfig `mapstructure:"moderation"`
 }
 
 type ServerConfig struct {
-	Port string `mapstructure:"port"`
+	Port     string    `mapstructure:"port"`
+	TimeZone string    `mapstructure:"timezone"` // 默认时区，用户没有指定时按它解析 "昨天" 这类日期，默认 Asia/Shanghai
+	SSE      SSEConfig `mapstructure:"sse"`
+	// 管理接口 (/api/v1/admin) 的令牌，请求头 X-Admin-Token 携带；为空时管理接口关闭
+	AdminToken string `mapstructure:"admin_token"`
+}
+
+// SSEConfig 流式记账的心跳和断线重连参数，留空使用默认值
+type SSEConfig struct {
+	Heartbeat   time.Duration `mapstructure:"heartbeat"`    // 心跳间隔，默认 15s
+	ReplayTTL   time.Duration `mapstructure:"replay_ttl"`   // 任务结束后保留事件多久供重连补发，默认 5m
+	ResumeGrace time.Duration `mapstructure:"resume_grace"` // 客户端全部断开后任务继续运行多久等待重连，默认 30s
 }
 
 type DatabaseConfig struct {
@@ -28,9 +47,78 @@ type QdrantConfig struct {
 }
 
 type ModelConfig struct {
-	APIKey  string `mapstructure:"api_key"`
-	BaseURL string `mapstructure:"base_url"`
-	Model   string `mapstructure:"model"`
+	Provider  string `mapstructure:"provider"` // 后端类型：llm.providers 下为 deepseek / openai / ollama，vision 为 openai / fake
+	APIKey    string `mapstructure:"api_key"`
+	BaseURL   string `mapstructure:"base_url"`
+	Model     string `mapstructure:"model"`
+	ToolCalls *bool  `mapstructure:"tool_calls"` // 可选，是否支持工具调用，不填按后端类型默认
+}
+
+// LLMConfig 记账模型配置
+// Providers 以名字为键配置多个后端，Provider 选用其中一个；Provider 为空时沿用顶层的 deepseek 配置
+// 配置了 Chain 时按顺序组成降级链 (忽略 Provider)，每个后端独立熔断
+type LLMConfig struct {
+	Provider  string                 `mapstructure:"provider"`
+	Providers map[string]ModelConfig `mapstructure:"providers"`
+	Chain     []string               `mapstructure:"chain"`
+	Breaker   BreakerConfig          `mapstructure:"breaker"`
+}
+
+// BreakerConfig 降级链的熔断参数，留空使用默认值
+type BreakerConfig struct {
+	FailureThreshold  int           `mapstructure:"failure_threshold"`   // 连续失败多少次熔断，默认 3
+	Cooldown          time.Duration `mapstructure:"cooldown"`            // 熔断多久后探测恢复，默认 30s
+	FirstTokenTimeout time.Duration `mapstructure:"first_token_timeout"` // 首个片段超时，默认 10s
+}
+
+// JobsConfig 异步记账任务，留空使用默认值
+type JobsConfig struct {
+	Workers        int           `mapstructure:"workers"`         // 同时处理的任务数，默认 4
+	MaxAttempts    int           `mapstructure:"max_attempts"`    // 每个任务最多调用几次模型，默认 5
+	Backoff        time.Duration `mapstructure:"backoff"`         // 第一次重试的等待时间，之后每次翻倍，默认 5s
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`     // 重试等待的上限，默认 5m
+	Timeout        time.Duration `mapstructure:"timeout"`         // 单次处理的超时，默认 2m
+	CallbackSecret string        `mapstructure:"callback_secret"` // 回调签名的密钥，为空时不签名
+	// 允许回调内网和本机地址，只用于本地联调；默认只投递到公网地址
+	AllowPrivateCallbacks bool `mapstructure:"allow_private_callbacks"`
+}
+
+// PromptConfig Prompt 模板，留空只使用代码内置的模板
+type PromptConfig struct {
+	Dir            string        `mapstructure:"dir"`             // 模板目录，每个 .tmpl 文件是一个版本，文件名 (不含扩展名) 即版本号
+	Default        string        `mapstructure:"default"`         // 默认版本，默认为代码内置的版本
+	ReloadInterval time.Duration `mapstructure:"reload_interval"` // 检查模板文件变化的间隔，默认 10s
+}
+
+// ModerationConfig 吐槽的内容检查，留空只使用内置屏蔽词
+type ModerationConfig struct {
+	Blocklist          map[string][]string `mapstructure:"blocklist"`           // 追加的屏蔽词，键为类别：protected_attribute / self_harm / harassment，其他键记为 other
+	Classifier         string              `mapstructure:"classifier"`          // 屏蔽词之后再用的分类器，目前支持 openai，为空不用
+	APIKey             string              `mapstructure:"api_key"`             // 分类器的接口配置
+	BaseURL            string              `mapstructure:"base_url"`            // 默认 OpenAI 官方地址
+	Model              string              `mapstructure:"model"`               // 默认 omni-moderation-latest
+	RegenerateAttempts int                 `mapstructure:"regenerate_attempts"` // 没通过时重新生成的次数，默认 1，-1 表示不重新生成直接替换
+	Replacement        string              `mapstructure:"replacement"`         // 重新生成也没通过时使用的兜底文案
+}
+
+// LedgerConfig Beancount / hledger 导出的账户命名规则，留空使用默认值
+type LedgerConfig struct {
+	ExpenseRoot    string            `mapstructure:"expense_root"`    // 默认 Expenses，生成 Expenses:餐饮美食
+	Categories     map[string]string `mapstructure:"categories"`      // 分类 -> 完整账户名
+	DefaultFunding string            `mapstructure:"default_funding"` // 默认 Assets:Cash
+	Sources        map[string]string `mapstructure:"sources"`         // 导入来源 -> 付款账户
+	Currency       string            `mapstructure:"currency"`        // 默认 CNY
+}
+
+// Scheme 转成导出用的账户命名规则
+func (c LedgerConfig) Scheme() ledger.AccountScheme {
+	return ledger.AccountScheme{
+		ExpenseRoot:    c.ExpenseRoot,
+		Categories:     c.Categories,
+		DefaultFunding: c.DefaultFunding,
+		Sources:        c.Sources,
+		Currency:       c.Currency,
+	}
 }
 
 // LoadConfig 读取配置文件
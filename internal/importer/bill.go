@@ -0,0 +1,212 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+const (
+	SourceAlipay = "alipay"
+	SourceWechat = "wechat"
+)
+
+// ErrUnknownFormat 既不是支付宝也不是微信支付的账单
+var ErrUnknownFormat = errors.New("无法识别的账单格式，仅支持支付宝/微信支付导出的 CSV")
+
+// Record 账单中的一行交易
+type Record struct {
+	Row              int       `json:"row"`               // 源文件行号 (从 1 开始)
+	Source           string    `json:"source"`            // alipay / wechat
+	TradeNo          string    `json:"trade_no"`          // 平台交易单号，用于去重
+	Time             time.Time `json:"time"`              // 交易时间
+	Amount           float64   `json:"amount"`            // 金额 (元)
+	Direction        string    `json:"direction"`         // 支出 / 收入 / 不计收支
+	Counterparty     string    `json:"counterparty"`      // 交易对方
+	Description      string    `json:"description"`       // 商品说明
+	PlatformCategory string    `json:"platform_category"` // 支付宝自带的交易分类，微信没有
+	Status           string    `json:"status"`            // 交易状态
+}
+
+// SkipReason 非支出或未成功的交易返回跳过原因，正常支出返回空串
+func (r Record) SkipReason() string {
+	if r.Direction != "支出" {
+		return "非支出交易: " + r.Direction
+	}
+	for _, s := range []string{"关闭", "失败", "已全额退款", "退款成功"} {
+		if strings.Contains(r.Status, s) {
+			return "交易未成功: " + r.Status
+		}
+	}
+	if r.Amount <= 0 {
+		return "金额无效"
+	}
+	return ""
+}
+
+// Bill 解析后的账单
+type Bill struct {
+	Source  string
+	Records []Record
+}
+
+// 不同版本的导出文件列名不一样，这里按别名匹配
+var columnAliases = map[string][]string{
+	"time":         {"交易时间", "交易创建时间", "付款时间"},
+	"trade_no":     {"交易订单号", "交易单号", "交易号"},
+	"amount":       {"金额", "金额(元)", "金额（元）"},
+	"direction":    {"收/支"},
+	"counterparty": {"交易对方"},
+	"description":  {"商品说明", "商品名称", "商品"},
+	"category":     {"交易分类"},
+	"status":       {"交易状态", "当前状态"},
+}
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+}
+
+// ParseBill 自动识别支付宝/微信支付账单并解析
+// 支付宝导出默认是 GBK 编码，微信是带 BOM 的 UTF-8，两种都兼容
+func ParseBill(data []byte, loc *time.Location) (*Bill, error) {
+	text, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	source := detectSource(text)
+	if source == "" {
+		return nil, ErrUnknownFormat
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1 // 表头前后的说明行列数不固定
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV 解析失败: %w", err)
+	}
+
+	headerRow, columns := findHeader(rows)
+	if headerRow < 0 {
+		return nil, ErrUnknownFormat
+	}
+
+	bill := &Bill{Source: source}
+	for i := headerRow + 1; i < len(rows); i++ {
+		rec, ok := parseRow(rows[i], columns, loc)
+		if !ok {
+			// 尾部的统计行、分隔线等直接忽略
+			continue
+		}
+		rec.Row = i + 1
+		rec.Source = source
+		bill.Records = append(bill.Records, rec)
+	}
+	return bill, nil
+}
+
+func decode(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	decoded, err := io.ReadAll(transform.NewReader(bytes.NewReader(data), simplifiedchinese.GB18030.NewDecoder()))
+	if err != nil {
+		return "", fmt.Errorf("账单编码转换失败: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func detectSource(text string) string {
+	head := text
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	switch {
+	case strings.Contains(head, "微信支付"):
+		return SourceWechat
+	case strings.Contains(head, "支付宝"):
+		return SourceAlipay
+	}
+	return ""
+}
+
+// findHeader 找到表头所在行，并返回字段名到列下标的映射
+func findHeader(rows [][]string) (int, map[string]int) {
+	for i, row := range rows {
+		columns := map[string]int{}
+		for j, cell := range row {
+			cell = strings.TrimSpace(cell)
+			for field, aliases := range columnAliases {
+				if _, ok := columns[field]; ok {
+					continue
+				}
+				for _, alias := range aliases {
+					if cell == alias {
+						columns[field] = j
+					}
+				}
+			}
+		}
+		_, hasTime := columns["time"]
+		_, hasAmount := columns["amount"]
+		if hasTime && hasAmount {
+			return i, columns
+		}
+	}
+	return -1, nil
+}
+
+func parseRow(row []string, columns map[string]int, loc *time.Location) (Record, bool) {
+	get := func(field string) string {
+		if j, ok := columns[field]; ok && j < len(row) {
+			return strings.TrimSpace(row[j])
+		}
+		return ""
+	}
+
+	t, ok := parseTime(get("time"), loc)
+	if !ok {
+		return Record{}, false
+	}
+	amount, err := parseAmount(get("amount"))
+	if err != nil {
+		return Record{}, false
+	}
+	return Record{
+		TradeNo:          get("trade_no"),
+		Time:             t,
+		Amount:           amount,
+		Direction:        get("direction"),
+		Counterparty:     get("counterparty"),
+		Description:      get("description"),
+		PlatformCategory: get("category"),
+		Status:           get("status"),
+	}, true
+}
+
+func parseTime(raw string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseAmount(raw string) (float64, error) {
+	raw = strings.NewReplacer("¥", "", "￥", "", ",", "", " ", "").Replace(raw)
+	return strconv.ParseFloat(raw, 64)
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"strings"
+)
+
+// CategoryRule 关键词命中交易对方或商品说明即归入对应分类
+type CategoryRule struct {
+	Category string
+	Keywords []string
+}
+
+// DefaultCategoryRules 常见商户的规则表，按顺序匹配
+// 分类名必须来自 model.PredefinedCategories
+var DefaultCategoryRules = []CategoryRule{
+	{"餐饮美食", []string{"美团外卖", "饿了么", "星巴克", "瑞幸", "麦当劳", "肯德基", "喜茶", "奈雪", "蜜雪冰城", "必胜客", "海底捞", "餐饮", "餐厅", "饭店", "咖啡", "奶茶", "烘焙", "食堂"}},
+	{"交通出行", []string{"滴滴", "高德打车", "曹操出行", "T3出行", "地铁", "公交", "12306", "铁路", "航空", "机票", "加油", "中国石化", "中国石油", "停车", "ETC", "哈啰", "青桔", "共享单车"}},
+	{"休闲娱乐", []string{"电影", "猫眼", "淘票票", "KTV", "网易云音乐", "QQ音乐", "腾讯视频", "爱奇艺", "优酷", "哔哩哔哩", "Steam", "游戏", "健身"}},
+	{"数码电器", []string{"Apple", "苹果", "小米", "华为", "京东电器", "数码"}},
+	{"医疗健康", []string{"医院", "药房", "药店", "大药房", "诊所", "体检"}},
+	{"学习教育", []string{"书店", "当当", "得到", "知乎", "网课", "培训", "学费"}},
+	{"居家生活", []string{"超市", "便利店", "全家", "罗森", "7-ELEVEN", "物业", "水费", "电费", "燃气", "宽带", "话费", "盒马", "叮咚买菜", "朴朴"}},
+	{"服饰美容", []string{"优衣库", "ZARA", "H&M", "理发", "美发", "美容", "屈臣氏", "丝芙兰"}},
+	{"人情往来", []string{"红包", "转账", "礼金"}},
+	{"金融保险", []string{"保险", "还款", "花呗", "借呗", "信用卡"}},
+}
+
+// 支付宝自带的交易分类到本系统分类的映射，"其他" 不参与映射
+var platformCategoryMap = map[string]string{
+	"餐饮美食": "餐饮美食",
+	"交通出行": "交通出行",
+	"爱车养车": "交通出行",
+	"酒店旅游": "休闲娱乐",
+	"文化休闲": "休闲娱乐",
+	"运动户外": "休闲娱乐",
+	"日用百货": "居家生活",
+	"住房物业": "居家生活",
+	"家居家装": "居家生活",
+	"充值缴费": "居家生活",
+	"服饰装扮": "服饰美容",
+	"美容美发": "服饰美容",
+	"数码电器": "数码电器",
+	"医疗健康": "医疗健康",
+	"教育培训": "学习教育",
+	"转账红包": "人情往来",
+	"亲友代付": "人情往来",
+	"投资理财": "金融保险",
+	"保险":   "金融保险",
+	"信用借还": "金融保险",
+}
+
+// CategoryMapper 基于规则的分类器，命中不了的交给 LLM
+type CategoryMapper struct {
+	rules []CategoryRule
+}
+
+// NewCategoryMapper 构造函数，不传规则时使用内置规则
+func NewCategoryMapper(rules ...CategoryRule) *CategoryMapper {
+	if len(rules) == 0 {
+		rules = DefaultCategoryRules
+	}
+	return &CategoryMapper{rules: rules}
+}
+
+// Map 先按商户关键词匹配，再参考平台自带分类
+func (m *CategoryMapper) Map(counterparty, description, platformCategory string) (string, bool) {
+	text := strings.ToLower(counterparty + " " + description)
+	for _, rule := range m.rules {
+		for _, k := range rule.Keywords {
+			if strings.Contains(text, strings.ToLower(k)) {
+				return rule.Category, true
+			}
+		}
+	}
+	if category, ok := platformCategoryMap[platformCategory]; ok {
+		return category, true
+	}
+	return "", false
+}
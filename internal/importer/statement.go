@@ -0,0 +1,356 @@
+package importer
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+)
+
+const (
+	FormatCSV = "csv"
+	FormatOFX = "ofx"
+	FormatQIF = "qif"
+
+	// SourceStatement 银行流水导入的 ExpenseEntity.Source
+	SourceStatement = "statement"
+)
+
+// 银行流水常见的日期格式，用户没有指定 DateLayout 时依次尝试
+var statementDateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006/01/02",
+	"2006/1/2",
+	"20060102",
+	"2006年01月02日",
+	"01/02/2006",
+	"1/2/2006",
+	"02.01.2006",
+}
+
+// DetectFormat 按文件扩展名判断格式
+func DetectFormat(fileName string) (string, error) {
+	name := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(name, ".csv"), strings.HasSuffix(name, ".txt"):
+		return FormatCSV, nil
+	case strings.HasSuffix(name, ".ofx"), strings.HasSuffix(name, ".qfx"):
+		return FormatOFX, nil
+	case strings.HasSuffix(name, ".qif"):
+		return FormatQIF, nil
+	}
+	return "", fmt.Errorf("不支持的文件类型: %s", fileName)
+}
+
+// ParseStatement 解析银行流水，CSV 需要列映射，OFX/QIF 格式固定
+// 返回的记录已经带上了去重指纹 (TradeNo)
+func ParseStatement(format string, data []byte, mapping model.ColumnMapping, loc *time.Location) ([]Record, error) {
+	text, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	switch format {
+	case FormatCSV:
+		records, err = parseMappedCSV(text, mapping, loc)
+	case FormatOFX:
+		records, err = parseOFX(text, loc)
+	case FormatQIF:
+		records, err = parseQIF(text, mapping.DateLayout, loc)
+	default:
+		err = fmt.Errorf("不支持的流水格式: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	assignFingerprints(records)
+	return records, nil
+}
+
+// assignFingerprints 用 日期 + 金额 + 描述 生成去重指纹
+// 同一天同金额同描述的多笔交易 (比如两杯一样的咖啡) 追加序号区分，重复导入同一文件时序号一致
+func assignFingerprints(records []Record) {
+	seen := map[string]int{}
+	for i := range records {
+		r := &records[i]
+		raw := fmt.Sprintf("%s|%.2f|%s|%s", r.Time.Format("2006-01-02"), r.Amount, r.Counterparty, r.Description)
+		sum := sha1.Sum([]byte(raw))
+		fp := hex.EncodeToString(sum[:])
+		seen[fp]++
+		r.TradeNo = fmt.Sprintf("%s#%d", fp, seen[fp])
+		r.Source = SourceStatement
+	}
+}
+
+func parseMappedCSV(text string, mapping model.ColumnMapping, loc *time.Location) ([]Record, error) {
+	if mapping.DateColumn == "" {
+		return nil, fmt.Errorf("列映射缺少日期列")
+	}
+	if mapping.AmountColumn == "" && mapping.DebitColumn == "" {
+		return nil, fmt.Errorf("列映射缺少金额列或支出列")
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if d := []rune(mapping.Delimiter); len(d) == 1 {
+		reader.Comma = d[0]
+	} else if mapping.Delimiter == `\t` {
+		reader.Comma = '\t'
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV 解析失败: %w", err)
+	}
+	if mapping.SkipRows >= len(rows) {
+		return nil, fmt.Errorf("跳过 %d 行后文件为空", mapping.SkipRows)
+	}
+	rows = rows[mapping.SkipRows:]
+	rowOffset := mapping.SkipRows
+
+	var header []string
+	if mapping.HasHeader {
+		header = rows[0]
+		rows = rows[1:]
+		rowOffset++
+	}
+	col := func(name string) (int, error) {
+		return resolveColumn(name, header)
+	}
+	dateIdx, err := col(mapping.DateColumn)
+	if err != nil {
+		return nil, err
+	}
+	amountIdx, debitIdx, creditIdx, descIdx, partyIdx := -1, -1, -1, -1, -1
+	for _, c := range []struct {
+		name string
+		dst  *int
+	}{
+		{mapping.AmountColumn, &amountIdx},
+		{mapping.DebitColumn, &debitIdx},
+		{mapping.CreditColumn, &creditIdx},
+		{mapping.DescriptionColumn, &descIdx},
+		{mapping.CounterpartyColumn, &partyIdx},
+	} {
+		if c.name == "" {
+			continue
+		}
+		if *c.dst, err = col(c.name); err != nil {
+			return nil, err
+		}
+	}
+
+	cell := func(row []string, idx int) string {
+		if idx < 0 || idx >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[idx])
+	}
+
+	var records []Record
+	for i, row := range rows {
+		t, ok := parseStatementDate(cell(row, dateIdx), mapping.DateLayout, loc)
+		if !ok {
+			// 汇总行、空行等
+			continue
+		}
+		rec := Record{
+			Row:          rowOffset + i + 1,
+			Time:         t,
+			Counterparty: cell(row, partyIdx),
+			Description:  cell(row, descIdx),
+			Status:       "成功",
+		}
+
+		if amountIdx >= 0 {
+			amount, err := parseAmount(cell(row, amountIdx))
+			if err != nil {
+				continue
+			}
+			isExpense := amount < 0
+			if !mapping.ExpenseNegative {
+				isExpense = amount > 0
+			}
+			rec.Amount = math.Abs(amount)
+			rec.Direction = directionOf(isExpense)
+		} else {
+			debit, _ := parseAmount(cell(row, debitIdx))
+			credit, _ := parseAmount(cell(row, creditIdx))
+			if debit != 0 {
+				rec.Amount, rec.Direction = math.Abs(debit), directionOf(true)
+			} else {
+				rec.Amount, rec.Direction = math.Abs(credit), directionOf(false)
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// resolveColumn 列名优先匹配表头，纯数字视为从 1 开始的列序号
+func resolveColumn(name string, header []string) (int, error) {
+	for i, h := range header {
+		if strings.TrimSpace(h) == name {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil && n >= 1 {
+		return n - 1, nil
+	}
+	return -1, fmt.Errorf("找不到列: %s", name)
+}
+
+func parseStatementDate(raw string, layout string, loc *time.Location) (time.Time, bool) {
+	if raw == "" {
+		return time.Time{}, false
+	}
+	if layout != "" {
+		t, err := time.ParseInLocation(layout, raw, loc)
+		return t, err == nil
+	}
+	for _, l := range statementDateLayouts {
+		if t, err := time.ParseInLocation(l, raw, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func directionOf(isExpense bool) string {
+	if isExpense {
+		return "支出"
+	}
+	return "收入"
+}
+
+var (
+	ofxTxnStart     = regexp.MustCompile(`(?i)<STMTTRN>`)
+	ofxTxnEnd       = regexp.MustCompile(`(?i)</STMTTRN>|</BANKTRANLIST>`)
+	ofxFieldPattern = regexp.MustCompile(`(?i)<([A-Z.]+)>([^<\r\n]*)`)
+)
+
+// parseOFX 同时兼容 SGML (OFX 1.x，标签不闭合) 和 XML (OFX 2.x)
+func parseOFX(text string, loc *time.Location) ([]Record, error) {
+	// 按 <STMTTRN> 切块，SGML 里可能没有闭合标签，遇到下一个 <STMTTRN> 也算结束
+	blocks := ofxTxnStart.Split(text, -1)
+	if len(blocks) <= 1 {
+		return nil, fmt.Errorf("OFX 文件中没有交易记录")
+	}
+
+	var records []Record
+	for i, block := range blocks[1:] {
+		if end := ofxTxnEnd.FindStringIndex(block); end != nil {
+			block = block[:end[0]]
+		}
+		fields := map[string]string{}
+		for _, f := range ofxFieldPattern.FindAllStringSubmatch(block, -1) {
+			fields[strings.ToUpper(f[1])] = strings.TrimSpace(f[2])
+		}
+		t, ok := parseOFXDate(fields["DTPOSTED"], loc)
+		if !ok {
+			continue
+		}
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(fields["TRNAMT"], ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		// OFX 规定支出为负数
+		records = append(records, Record{
+			Row:          i + 1,
+			Time:         t,
+			Amount:       math.Abs(amount),
+			Direction:    directionOf(amount < 0),
+			Counterparty: fields["NAME"],
+			Description:  fields["MEMO"],
+			Status:       "成功",
+		})
+	}
+	return records, nil
+}
+
+// parseOFXDate 格式为 YYYYMMDD[HHMMSS[.XXX]][[+-]TZ]，这里只取到秒
+func parseOFXDate(raw string, loc *time.Location) (time.Time, bool) {
+	if len(raw) >= 14 {
+		if t, err := time.ParseInLocation("20060102150405", raw[:14], loc); err == nil {
+			return t, true
+		}
+	}
+	if len(raw) >= 8 {
+		if t, err := time.ParseInLocation("20060102", raw[:8], loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// QIF 常见的日期格式，美式软件还会用 ' 表示两位年份
+var qifDateLayouts = []string{"01/02/2006", "1/2/2006", "01/02'06", "1/2'06", "2006-01-02", "02/01/2006"}
+
+// parseQIF 按行解析，D=日期 T/U=金额 P=收款方 M=备注，^ 表示一条记录结束
+func parseQIF(text string, layout string, loc *time.Location) ([]Record, error) {
+	layouts := qifDateLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+
+	var records []Record
+	var cur Record
+	var hasDate, hasAmount bool
+	var amount float64
+	entry := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "!") {
+			continue
+		}
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch code {
+		case 'D':
+			value = strings.ReplaceAll(value, " ", "")
+			for _, l := range layouts {
+				if t, err := time.ParseInLocation(l, value, loc); err == nil {
+					cur.Time, hasDate = t, true
+					break
+				}
+			}
+		case 'T', 'U':
+			if v, err := parseAmount(value); err == nil {
+				amount, hasAmount = v, true
+			}
+		case 'P':
+			cur.Counterparty = value
+		case 'M':
+			cur.Description = value
+		case '^':
+			entry++
+			if hasDate && hasAmount {
+				cur.Row = entry
+				cur.Amount = math.Abs(amount)
+				cur.Direction = directionOf(amount < 0)
+				cur.Status = "成功"
+				records = append(records, cur)
+			}
+			cur, hasDate, hasAmount, amount = Record{}, false, false, 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("QIF 读取失败: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("QIF 文件中没有交易记录")
+	}
+	return records, nil
+}
@@ -28,6 +28,30 @@ func NewMySQLConnection(dsn string) *gorm.DB {
 		log.Fatalf("Fatal: 数据库迁移失败: %v", err)
 	}
 
+	if err = db.AutoMigrate(&model.ImportProfile{}, &model.ImportBatch{}); err != nil {
+		log.Fatalf("Fatal: 数据库迁移失败: %v", err)
+	}
+
+	if err = db.AutoMigrate(&model.AnalysisJob{}); err != nil {
+		log.Fatalf("Fatal: 数据库迁移失败: %v", err)
+	}
+
+	if err = db.AutoMigrate(&model.AnalysisAudit{}); err != nil {
+		log.Fatalf("Fatal: 数据库迁移失败: %v", err)
+	}
+
+	if err = db.AutoMigrate(&model.Experiment{}); err != nil {
+		log.Fatalf("Fatal: 数据库迁移失败: %v", err)
+	}
+
+	if err = db.AutoMigrate(&model.Roast{}); err != nil {
+		log.Fatalf("Fatal: 数据库迁移失败: %v", err)
+	}
+
+	if err = db.AutoMigrate(&model.ModerationLog{}); err != nil {
+		log.Fatalf("Fatal: 数据库迁移失败: %v", err)
+	}
+
 	sqlDB, _ := db.DB()
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
@@ -63,3 +63,30 @@ func (c *OpenAIClient) GetVector(ctx context.Context, text string) ([]float32, e
 	// 返回第一个结果的向量
 	return resp.Data[0].Embedding, nil
 }
+
+// GetVectors 一次请求批量生成向量，用于账单导入等大批量场景
+func (c *OpenAIClient) GetVectors(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	resp, err := c.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: texts,
+		Model: openai.EmbeddingModel(c.model),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai embedding failed: %w", err)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding count mismatch: want %d, got %d", len(texts), len(resp.Data))
+	}
+
+	// 按 Index 回填，不依赖返回顺序
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index out of range: %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -6,4 +6,6 @@ import "context"
 type Provider interface {
 	// GetVector 输入文本，返回 float32 数组
 	GetVector(ctx context.Context, text string) ([]float32, error)
+	// GetVectors 批量版本，返回的向量与 texts 一一对应
+	GetVectors(ctx context.Context, texts []string) ([][]float32, error)
 }
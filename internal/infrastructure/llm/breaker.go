@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	breakerClosed   = "closed"    // 正常
+	breakerOpen     = "open"      // 熔断中，直接跳过
+	breakerHalfOpen = "half_open" // 冷却结束，放一个请求过去探测
+)
+
+// BreakerConfig 熔断参数
+type BreakerConfig struct {
+	FailureThreshold  int           // 连续失败多少次后熔断，默认 3
+	Cooldown          time.Duration // 熔断多久后放行探测请求，默认 30s
+	FirstTokenTimeout time.Duration // 等待首个片段的超时，超时按失败处理，默认 10s
+}
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 3
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = 30 * time.Second
+	}
+	if c.FirstTokenTimeout <= 0 {
+		c.FirstTokenTimeout = 10 * time.Second
+	}
+	return c
+}
+
+// breaker 单个后端的熔断器
+// 冷却结束后只放行一个探测请求：成功则恢复，失败则重新熔断
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	state     string
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, state: breakerClosed}
+}
+
+// Allow 是否可以把请求发给这个后端
+func (b *breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Now().Before(b.openUntil) {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		// 已经有探测请求在路上了
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// Success 请求成功，清零计数并关闭熔断
+func (b *breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure 请求失败，返回本次是否触发了熔断
+func (b *breaker) Failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		tripped := b.state != breakerOpen
+		b.state = breakerOpen
+		b.openUntil = time.Now().Add(b.cooldown)
+		return tripped
+	}
+	return false
+}
+
+// Release 请求被调用方取消，既不算成功也不算失败，只释放探测名额
+func (b *breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
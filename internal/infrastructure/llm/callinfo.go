@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+type callInfoKey struct{}
+
+// CallInfo 记录一次调用实际由哪个后端完成
+// 调用方通过 WithCallInfo 挂到 ctx 上，组合型 Provider (如 FallbackProvider) 在选定后端后写入
+type CallInfo struct {
+	mu         sync.Mutex
+	provider   string
+	completion Completion
+}
+
+// Completion 一次完整输出的明细，由具体后端在输出正常结束时写入
+// 降级链上失败的后端不会写入，所以这里总是实际服务的那个后端的数据
+type Completion struct {
+	Model            string
+	PromptVersion    string
+	PromptTokens     int // 后端没有返回用量时为 0
+	CompletionTokens int
+}
+
+// WithCallInfo 返回挂载了 CallInfo 的 ctx
+func WithCallInfo(ctx context.Context) (context.Context, *CallInfo) {
+	info := &CallInfo{}
+	return context.WithValue(ctx, callInfoKey{}, info), info
+}
+
+// EnsureCallInfo ctx 上已经挂了 CallInfo 时原样返回，否则挂一个新的
+func EnsureCallInfo(ctx context.Context) (context.Context, *CallInfo) {
+	if info, ok := ctx.Value(callInfoKey{}).(*CallInfo); ok {
+		return ctx, info
+	}
+	return WithCallInfo(ctx)
+}
+
+// RecordProvider 记录实际服务的后端，已经记录过的不覆盖 (组合 Provider 内层先写，外层兜底)
+func RecordProvider(ctx context.Context, name string) {
+	info, ok := ctx.Value(callInfoKey{}).(*CallInfo)
+	if !ok {
+		return
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	if info.provider == "" {
+		info.provider = name
+	}
+}
+
+// RecordCompletion 记录输出的明细，由具体后端在流正常结束时调用
+func RecordCompletion(ctx context.Context, c Completion) {
+	info, ok := ctx.Value(callInfoKey{}).(*CallInfo)
+	if !ok {
+		return
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	info.completion = c
+}
+
+// ServedBy 读取 ctx 上记录的后端名字，没有挂 CallInfo 或未记录时为空
+func ServedBy(ctx context.Context) string {
+	info, ok := ctx.Value(callInfoKey{}).(*CallInfo)
+	if !ok {
+		return ""
+	}
+	return info.Provider()
+}
+
+// Provider 实际服务的后端名字，未记录时为空
+func (c *CallInfo) Provider() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.provider
+}
+
+// Completion 输出的明细，流还没结束或后端没有记录时为零值
+func (c *CallInfo) Completion() Completion {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.completion
+}
@@ -6,6 +6,37 @@ import (
 
 // Provider 定义了 LLM 的通用行为
 type Provider interface {
-	// AnalyzeExpense 接收用户输入，返回结构化的分析结果
-	AnalyzeExpense(ctx context.Context, userContext string, categories []string, historyContext []string, enableRoast bool) (<-chan string, error)
+	// AnalyzeExpense 接收用户输入，流式返回 book_expense 工具参数的 JSON 片段
+	// 返回的 error 表示没能开始输出；开始之后的失败 (断流、超时、ctx 取消) 通过 Stream.Err 返回
+	AnalyzeExpense(ctx context.Context, userContext string, categories []string, historyContext []string, enableRoast bool) (*Stream[string], error)
+	// Name 配置中的后端名字，用于日志
+	Name() string
+	// SupportsToolCalls 是否支持工具调用；不支持的后端走 JSON 模式，输出的 JSON 结构相同
+	SupportsToolCalls() bool
+}
+
+// ReceiptImage 待识别的小票/支付截图
+type ReceiptImage struct {
+	Data     []byte
+	MimeType string // 例如 image/jpeg、image/png
+}
+
+// ToolCallFragment 是多次工具调用场景下的流式片段
+// 一张小票可能包含多笔消费，模型会发起多次 book_expense 调用，Index 用来区分属于哪一次
+type ToolCallFragment struct {
+	Index     int
+	Arguments string
+}
+
+// VisionProvider 定义了多模态模型识别图片的能力，与 Provider 是兄弟接口
+// 不支持视觉的模型（如 DeepSeek）无需实现它
+type VisionProvider interface {
+	// AnalyzeReceipt 识别小票图片，对其中每一笔消费发起一次 book_expense 调用
+	AnalyzeReceipt(ctx context.Context, image ReceiptImage, categories []string, enableRoast bool) (*Stream[ToolCallFragment], error)
+}
+
+// Classifier 定义了批量分类能力，用于账单导入这类只需要分类、不需要吐槽的场景
+type Classifier interface {
+	// ClassifyBatch 对 items 逐条分类，返回与 items 等长的分类列表，无法判断的位置为空串
+	ClassifyBatch(ctx context.Context, items []string, categories []string) ([]string, error)
 }
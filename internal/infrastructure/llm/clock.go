@@ -0,0 +1,28 @@
+package llm
+
+import (
+	"context"
+	"time"
+)
+
+type nowKey struct{}
+
+// WithNow 把用户所在时区的当前时间挂到 ctx 上
+// 提示词里的 "当前时间" 按它生成，模型推断 "昨天" 时用的是用户的日历而不是服务器的
+func WithNow(ctx context.Context, now time.Time) context.Context {
+	return context.WithValue(ctx, nowKey{}, now)
+}
+
+// Now ctx 上的用户当前时间，没有挂载时使用服务器时间
+func Now(ctx context.Context) time.Time {
+	if now, ok := ctx.Value(nowKey{}).(time.Time); ok {
+		return now
+	}
+	return time.Now()
+}
+
+// promptTime 提示词中的当前时间，带上时区名
+func promptTime(ctx context.Context) string {
+	now := Now(ctx)
+	return now.Format("2006-01-02 15:04:05") + " " + now.Location().String()
+}
@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+)
+
+// ErrFakeInterrupted FakeClient 模拟的中途断流
+var ErrFakeInterrupted = errors.New("fake: 模拟模型输出中断")
+
+// FakeClient 记账用的本地假实现，按真实模型的节奏输出固定的 book_expense 参数
+// 可以模拟中途断流，并统计仍在输出的 goroutine 数，用来验证客户端断开后没有泄漏
+type FakeClient struct {
+	name      string
+	chunkSize int
+	interval  time.Duration
+	failAfter atomic.Int64
+	active    atomic.Int64
+}
+
+func NewFakeClient(name string) *FakeClient {
+	if name == "" {
+		name = "fake"
+	}
+	return &FakeClient{
+		name:      name,
+		chunkSize: 8,
+		interval:  20 * time.Millisecond,
+	}
+}
+
+// SetInterval 调整片段间隔，测试断开场景时拉长输出时间
+func (f *FakeClient) SetInterval(interval time.Duration) {
+	f.interval = interval
+}
+
+// SetFailAfter 之后的调用发出 n 个片段后以 ErrFakeInterrupted 结束，0 表示正常输出完
+func (f *FakeClient) SetFailAfter(n int) {
+	f.failAfter.Store(int64(n))
+}
+
+// Active 仍在输出的 goroutine 数
+func (f *FakeClient) Active() int64 {
+	return f.active.Load()
+}
+
+func (f *FakeClient) Name() string {
+	return f.name
+}
+
+func (f *FakeClient) SupportsToolCalls() bool {
+	return true
+}
+
+func (f *FakeClient) AnalyzeExpense(ctx context.Context, userContext string, categories []string, historyContext []string, enableRoast bool) (*Stream[string], error) {
+	category := "其他消费"
+	if len(categories) > 0 {
+		category = categories[0]
+	}
+	comment := ""
+	if enableRoast {
+		comment = "钱包：我是做错了什么，要被你这样对待？"
+	}
+	raw, _ := json.Marshal(map[string]interface{}{
+		"amount":   25.0,
+		"category": category,
+		"date":     Now(ctx).Format("2006-01-02"),
+		"note":     userContext,
+		"comment":  comment,
+	})
+	runes := []rune(string(raw))
+
+	failAfter := int(f.failAfter.Load())
+	out, w := newStream[string](ctx)
+	f.active.Add(1)
+	go func() {
+		defer f.active.Add(-1)
+		sent := 0
+		for start := 0; start < len(runes); start += f.chunkSize {
+			if failAfter > 0 && sent >= failAfter {
+				w.Close(ErrFakeInterrupted)
+				return
+			}
+			select {
+			case <-ctx.Done():
+				w.Close(nil)
+				return
+			case <-time.After(f.interval):
+			}
+			end := min(start+f.chunkSize, len(runes))
+			if !w.Send(string(runes[start:end])) {
+				w.Close(nil)
+				return
+			}
+			sent++
+		}
+		// 没有真实的 token，按片段数记
+		RecordCompletion(ctx, Completion{Model: f.name, PromptVersion: prompt.FromContext(ctx).Version, PromptTokens: len([]rune(userContext)), CompletionTokens: sent})
+		w.Close(nil)
+	}()
+
+	return out, nil
+}
+
+// FakeVisionClient 本地假实现，不调用任何模型
+// 按真实模型的节奏把固定的识别结果切成小片段推出去，方便在没有 API Key 的环境下联调 SSE
+type FakeVisionClient struct {
+	chunkSize int
+	interval  time.Duration
+}
+
+func NewFakeVisionClient() *FakeVisionClient {
+	return &FakeVisionClient{
+		chunkSize: 8,
+		interval:  20 * time.Millisecond,
+	}
+}
+
+func (f *FakeVisionClient) AnalyzeReceipt(ctx context.Context, image ReceiptImage, categories []string, enableRoast bool) (*Stream[ToolCallFragment], error) {
+	category := "其他消费"
+	if len(categories) > 0 {
+		category = categories[0]
+	}
+	comment := ""
+	if enableRoast {
+		comment = "小票都拍得这么认真，花钱的时候怎么不这么认真？"
+	}
+
+	today := Now(ctx).Format("2006-01-02")
+	items := []map[string]interface{}{
+		{"amount": 28.0, "category": category, "date": today, "note": "拿铁咖啡", "comment": comment},
+		{"amount": 12.5, "category": category, "date": today, "note": "可颂面包", "comment": comment},
+	}
+
+	out, w := newStream[ToolCallFragment](ctx)
+	go func() {
+		for index, item := range items {
+			raw, _ := json.Marshal(item)
+			// 按 rune 切片，避免把中文切成半个字符
+			runes := []rune(string(raw))
+			for start := 0; start < len(runes); start += f.chunkSize {
+				end := min(start+f.chunkSize, len(runes))
+				select {
+				case <-ctx.Done():
+					w.Close(nil)
+					return
+				case <-time.After(f.interval):
+				}
+				if !w.Send(ToolCallFragment{Index: index, Arguments: string(runes[start:end])}) {
+					w.Close(nil)
+					return
+				}
+			}
+		}
+		w.Close(nil)
+	}()
+
+	return out, nil
+}
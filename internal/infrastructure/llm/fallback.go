@@ -0,0 +1,158 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ErrNoProviderAvailable 链上所有后端都失败或处于熔断中
+var ErrNoProviderAvailable = errors.New("没有可用的模型后端")
+
+// FallbackProvider 按配置顺序依次尝试多个后端，每个后端带一个熔断器
+// 首个片段到达才算成功；打开连接失败、流直接结束或首片段超时都算失败，换下一个
+type FallbackProvider struct {
+	providers []Provider
+	breakers  []*breaker
+	timeout   time.Duration
+}
+
+func NewFallbackProvider(cfg BreakerConfig, providers ...Provider) *FallbackProvider {
+	cfg = cfg.withDefaults()
+	breakers := make([]*breaker, len(providers))
+	for i := range providers {
+		breakers[i] = newBreaker(cfg.FailureThreshold, cfg.Cooldown)
+	}
+	return &FallbackProvider{providers: providers, breakers: breakers, timeout: cfg.FirstTokenTimeout}
+}
+
+func (f *FallbackProvider) Name() string {
+	names := make([]string, len(f.providers))
+	for i, p := range f.providers {
+		names[i] = p.Name()
+	}
+	return "fallback(" + strings.Join(names, ",") + ")"
+}
+
+// SupportsToolCalls 只有链上所有后端都支持时才为 true
+func (f *FallbackProvider) SupportsToolCalls() bool {
+	for _, p := range f.providers {
+		if !p.SupportsToolCalls() {
+			return false
+		}
+	}
+	return len(f.providers) > 0
+}
+
+func (f *FallbackProvider) AnalyzeExpense(ctx context.Context, userContext string, categories []string, historyContext []string, enableRoast bool) (*Stream[string], error) {
+	var errs []error
+	for i, p := range f.providers {
+		b := f.breakers[i]
+		if !b.Allow() {
+			slog.Debug("模型后端熔断中，跳过", "provider", p.Name())
+			continue
+		}
+
+		stream, err := f.attempt(ctx, p, userContext, categories, historyContext, enableRoast)
+		if err == nil {
+			b.Success()
+			RecordProvider(ctx, p.Name())
+			if i > 0 {
+				slog.Warn("已降级到备用模型后端", "provider", p.Name())
+			}
+			return stream, nil
+		}
+		if ctx.Err() != nil {
+			// 调用方自己放弃了，不怪后端
+			b.Release()
+			return nil, ctx.Err()
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		if b.Failure() {
+			slog.Error("模型后端熔断", "provider", p.Name(), "error", err)
+		} else {
+			slog.Warn("模型后端调用失败，尝试下一个", "provider", p.Name(), "error", err)
+		}
+	}
+	return nil, errors.Join(append([]error{ErrNoProviderAvailable}, errs...)...)
+}
+
+// attempt 调用单个后端并等待首个片段，成功后把剩余片段转发到新的流
+// 首片段之后再出错已经无法换后端 (前端收到了一半)，错误原样透传给调用方
+func (f *FallbackProvider) attempt(ctx context.Context, p Provider, userContext string, categories []string, historyContext []string, enableRoast bool) (*Stream[string], error) {
+	attemptCtx, cancel := context.WithCancel(ctx)
+	inner, err := p.AnalyzeExpense(attemptCtx, userContext, categories, historyContext, enableRoast)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	timer := time.NewTimer(f.timeout)
+	defer timer.Stop()
+
+	var first string
+	select {
+	case fragment, ok := <-inner.C:
+		if !ok {
+			cancel()
+			if err := inner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, ErrEmptyOutput
+		}
+		first = fragment
+	case <-timer.C:
+		cancel()
+		go inner.Drain()
+		return nil, fmt.Errorf("等待首个片段超时 (%s)", f.timeout)
+	case <-ctx.Done():
+		cancel()
+		go inner.Drain()
+		return nil, ctx.Err()
+	}
+
+	out, w := newStream[string](ctx)
+	go func() {
+		defer cancel()
+		fragment, ok := first, true
+		for ok {
+			if !w.Send(fragment) {
+				// 下游不再读了，取消上游并把剩下的读完，上游 goroutine 才能退出
+				cancel()
+				inner.Drain()
+				w.Close(nil)
+				return
+			}
+			fragment, ok = <-inner.C
+		}
+		w.Close(inner.Err())
+	}()
+	return out, nil
+}
+
+// ClassifyBatch 按顺序交给链上第一个可用且支持批量分类的后端
+func (f *FallbackProvider) ClassifyBatch(ctx context.Context, items []string, categories []string) ([]string, error) {
+	var errs []error
+	for i, p := range f.providers {
+		classifier, ok := p.(Classifier)
+		if !ok || !f.breakers[i].Allow() {
+			continue
+		}
+		result, err := classifier.ClassifyBatch(ctx, items, categories)
+		if err == nil {
+			f.breakers[i].Success()
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			f.breakers[i].Release()
+			return nil, ctx.Err()
+		}
+		f.breakers[i].Failure()
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+	}
+	return nil, errors.Join(append([]error{ErrNoProviderAvailable}, errs...)...)
+}
@@ -0,0 +1,57 @@
+package llm
+
+import "strings"
+
+// jsonObjectFilter 从 JSON 模式的流式输出里截出第一个完整的 JSON 对象
+// 本地小模型经常不听话，会在前后加 ```json 代码块或者解释文字，这里按括号深度只放行对象本身
+type jsonObjectFilter struct {
+	depth    int
+	started  bool
+	done     bool
+	inString bool
+	escaped  bool
+}
+
+// Feed 输入一段原始输出，返回其中属于 JSON 对象的部分
+func (f *jsonObjectFilter) Feed(chunk string) string {
+	if f.done {
+		return ""
+	}
+	var out strings.Builder
+	for _, r := range chunk {
+		if !f.started {
+			if r != '{' {
+				continue
+			}
+			f.started = true
+		}
+		out.WriteRune(r)
+
+		switch {
+		case f.escaped:
+			f.escaped = false
+		case f.inString:
+			if r == '\\' {
+				f.escaped = true
+			} else if r == '"' {
+				f.inString = false
+			}
+		case r == '"':
+			f.inString = true
+		case r == '{':
+			f.depth++
+		case r == '}':
+			f.depth--
+			if f.depth == 0 {
+				f.done = true
+				return out.String()
+			}
+		}
+	}
+	return out.String()
+}
+
+// Done 对象已经闭合，后面的输出可以丢弃
+func (f *jsonObjectFilter) Done() bool {
+	return f.done
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/leon37/FaceTaxLedger/internal/offline"
+)
+
+// OfflineProvider 规则解析的降级后端：不调用任何模型，AI 全部不可用时也能记账
+// 输出的 JSON 结构与 JSON 模式相同，comment 为空
+type OfflineProvider struct {
+	name   string
+	parser *offline.Parser
+}
+
+func NewOfflineProvider(name string) *OfflineProvider {
+	if name == "" {
+		name = "offline"
+	}
+	return &OfflineProvider{name: name, parser: offline.NewParser()}
+}
+
+func (o *OfflineProvider) Name() string {
+	return o.name
+}
+
+func (o *OfflineProvider) SupportsToolCalls() bool {
+	return false
+}
+
+func (o *OfflineProvider) AnalyzeExpense(ctx context.Context, userContext string, categories []string, historyContext []string, enableRoast bool) (*Stream[string], error) {
+	result, err := o.parser.Parse(userContext, Now(ctx), categories)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(result.Analysis())
+	if err != nil {
+		return nil, err
+	}
+
+	// 通道带缓冲，一个片段发完直接结束，不需要 goroutine
+	out, w := newStream[string](ctx)
+	w.Send(string(data))
+	w.Close(nil)
+	return out, nil
+}
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+)
+
+// OllamaClient 对接 Ollama 风格的本地模型服务 (POST /api/chat，按行返回 JSON)
+// 本地小模型的流式工具调用普遍不可靠，统一走 format=json 的 JSON 模式
+type OllamaClient struct {
+	name      string
+	baseURL   string
+	modelName string
+	http      *http.Client
+}
+
+// Ollama /api/chat 请求
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   string          `json:"format,omitempty"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Ollama 流式响应的单行
+type ollamaChatChunk struct {
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+	Error   string        `json:"error,omitempty"`
+	// 最后一行 (done=true) 才有的 token 用量
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
+}
+
+func NewOllamaClient(name, baseUrl, modelName string) *OllamaClient {
+	if baseUrl == "" {
+		baseUrl = "http://localhost:11434"
+	}
+	return &OllamaClient{
+		name:      name,
+		baseURL:   strings.TrimRight(baseUrl, "/"),
+		modelName: modelName,
+		// 不设整体超时：流式响应可能持续较久，由调用方的 ctx 控制
+		http: &http.Client{},
+	}
+}
+
+func (o *OllamaClient) Name() string {
+	return o.name
+}
+
+func (o *OllamaClient) SupportsToolCalls() bool {
+	return false
+}
+
+func (o *OllamaClient) AnalyzeExpense(ctx context.Context, userContext string, categories []string, historyContext []string, enableRoast bool) (*Stream[string], error) {
+	sysPrompt, err := jsonModePrompt(ctx, categories, historyContext, enableRoast)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(ollamaChatRequest{
+		Model: o.modelName,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: sysPrompt},
+			{Role: "user", Content: userMessage(userContext)},
+		},
+		Stream:  true,
+		Format:  "json",
+		Options: map[string]any{"temperature": 0.1},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("ollama 返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	out, w := newStream[string](ctx)
+	go func() {
+		defer resp.Body.Close()
+		var filter jsonObjectFilter
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var chunk ollamaChatChunk
+			if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+				slog.Error("Stream error", "provider", o.name, "err", err)
+				w.Close(err)
+				return
+			}
+			if chunk.Error != "" {
+				slog.Error("Stream error", "provider", o.name, "err", chunk.Error)
+				w.Close(fmt.Errorf("ollama: %s", chunk.Error))
+				return
+			}
+			if fragment := filter.Feed(chunk.Message.Content); fragment != "" && !w.Send(fragment) {
+				w.Close(nil)
+				return
+			}
+			if chunk.Done || filter.Done() {
+				// JSON 对象提前结束时还没收到 done 行，用量记录为 0
+				RecordCompletion(ctx, Completion{
+					Model:            o.modelName,
+					PromptVersion:    prompt.FromContext(ctx).Version,
+					PromptTokens:     chunk.PromptEvalCount,
+					CompletionTokens: chunk.EvalCount,
+				})
+				w.Close(nil)
+				return
+			}
+		}
+		err := scanner.Err()
+		if err != nil {
+			slog.Error("Stream error", "provider", o.name, "err", err)
+		} else {
+			// 连接在 done 之前就断了
+			err = io.ErrUnexpectedEOF
+		}
+		w.Close(err)
+	}()
+
+	return out, nil
+}
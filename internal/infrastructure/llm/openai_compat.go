@@ -0,0 +1,221 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+	"github.com/sashabaranov/go-openai"
+	"io"
+	"log/slog"
+)
+
+// OpenAICompatibleClient 对接任意 OpenAI 兼容的 Chat Completions 接口 (DeepSeek、通义、vLLM 等)
+// 支持工具调用的模型走 book_expense 工具；不支持的走 JSON 模式，由 Prompt 模板的 json_system 约束输出
+type OpenAICompatibleClient struct {
+	name      string
+	modelName string
+	toolCalls bool
+	client    *openai.Client
+}
+
+func NewOpenAICompatibleClient(name, apiKey, baseUrl, modelName string, toolCalls bool) *OpenAICompatibleClient {
+	config := openai.DefaultConfig(apiKey)
+	if baseUrl != "" {
+		config.BaseURL = baseUrl
+	}
+
+	return &OpenAICompatibleClient{
+		name:      name,
+		modelName: modelName,
+		toolCalls: toolCalls,
+		client:    openai.NewClientWithConfig(config),
+	}
+}
+
+func (o *OpenAICompatibleClient) Name() string {
+	return o.name
+}
+
+func (o *OpenAICompatibleClient) SupportsToolCalls() bool {
+	return o.toolCalls
+}
+
+func (o *OpenAICompatibleClient) AnalyzeExpense(ctx context.Context, userContext string, categories []string, historyContext []string, enableRoast bool) (*Stream[string], error) {
+	if !o.toolCalls {
+		return o.analyzeJSONMode(ctx, userContext, categories, historyContext, enableRoast)
+	}
+
+	// 1. 按这次调用的 Prompt 模板构建 System Prompt 和工具定义
+	tmpl := prompt.FromContext(ctx)
+	data := promptData(ctx, categories, historyContext, enableRoast)
+	sysPrompt, err := tmpl.Render(prompt.System, data)
+	if err != nil {
+		return nil, err
+	}
+	tool, err := GenerateBookExpenseTool(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model: o.modelName,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: sysPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: userMessage(userContext)},
+		},
+		// 注入动态工具
+		Tools: []openai.Tool{tool},
+		// 强制模型思考是否需要调用工具 (Auto 也是常用选项，Required 强制必须调)
+		ToolChoice: openai.ToolChoice{
+			Type: openai.ToolTypeFunction,
+			Function: openai.ToolFunction{
+				Name: "book_expense",
+			},
+		},
+		Temperature:   0.1, // 低温有助于 JSON 格式稳定
+		Stream:        true,
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true}, // 最后一个 chunk 带上 token 用量
+	}
+	stream, err := o.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out, w := newStream[string](ctx)
+	go func() {
+		defer stream.Close()
+		var usage openai.Usage
+		for {
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				o.recordCompletion(ctx, usage)
+				w.Close(nil)
+				return
+			}
+			if err != nil {
+				slog.Error("Stream error", "provider", o.name, "err", err)
+				w.Close(err)
+				return
+			}
+			if response.Usage != nil {
+				usage = *response.Usage
+			}
+			if len(response.Choices) > 0 && len(response.Choices[0].Delta.ToolCalls) > 0 {
+				fragment := response.Choices[0].Delta.ToolCalls[0].Function.Arguments
+				if fragment != "" && !w.Send(fragment) {
+					w.Close(nil)
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// analyzeJSONMode 不支持工具调用的模型：JSON 模式 + 流式 content，输出协议与工具参数完全一致
+func (o *OpenAICompatibleClient) analyzeJSONMode(ctx context.Context, userContext string, categories []string, historyContext []string, enableRoast bool) (*Stream[string], error) {
+	sysPrompt, err := jsonModePrompt(ctx, categories, historyContext, enableRoast)
+	if err != nil {
+		return nil, err
+	}
+	req := openai.ChatCompletionRequest{
+		Model: o.modelName,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: sysPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: userMessage(userContext)},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		Temperature:    0.1,
+		Stream:         true,
+		StreamOptions:  &openai.StreamOptions{IncludeUsage: true},
+	}
+	stream, err := o.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out, w := newStream[string](ctx)
+	go func() {
+		defer stream.Close()
+		var filter jsonObjectFilter
+		var usage openai.Usage
+		for {
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				o.recordCompletion(ctx, usage)
+				w.Close(nil)
+				return
+			}
+			if err != nil {
+				slog.Error("Stream error", "provider", o.name, "err", err)
+				w.Close(err)
+				return
+			}
+			if response.Usage != nil {
+				usage = *response.Usage
+			}
+			if len(response.Choices) == 0 {
+				continue
+			}
+			if fragment := filter.Feed(response.Choices[0].Delta.Content); fragment != "" && !w.Send(fragment) {
+				w.Close(nil)
+				return
+			}
+			if filter.Done() {
+				// 提前结束时用量的 chunk 还没到，记录为 0
+				o.recordCompletion(ctx, usage)
+				w.Close(nil)
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (o *OpenAICompatibleClient) recordCompletion(ctx context.Context, usage openai.Usage) {
+	RecordCompletion(ctx, Completion{
+		Model:            o.modelName,
+		PromptVersion:    prompt.FromContext(ctx).Version,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	})
+}
+
+// promptData 渲染 Prompt 模板的数据，吐槽人设和力度取自 ctx
+// 历史消费和之前的吐槽来自用户输入或模型输出，逐条清洗后再交给模板
+func promptData(ctx context.Context, categories []string, historyContext []string, enableRoast bool) prompt.Data {
+	style := prompt.StyleFromContext(ctx)
+	return prompt.Data{
+		Now:        promptTime(ctx),
+		Categories: categories,
+		History:    escapeAll(historyContext),
+		Roast:      enableRoast && style.Intensity > 0,
+		Persona:    style.Persona,
+		Intensity:  style.Intensity,
+		Avoid:      escapeAll(style.Avoid),
+		Examples:   escapeAll(style.Examples),
+	}
+}
+
+// userMessage 用户的记账描述作为 user 消息发送前包上 <user_input> 标签，模板的 data_rules 说明了标签的含义
+func userMessage(userContext string) string {
+	return prompt.Fence(prompt.TagUserInput, userContext)
+}
+
+func escapeAll(texts []string) []string {
+	if len(texts) == 0 {
+		return texts
+	}
+	escaped := make([]string, len(texts))
+	for i, t := range texts {
+		escaped[i] = prompt.Escape(t)
+	}
+	return escaped
+}
+
+// jsonModePrompt 没有工具定义约束字段时，由模板的 json_system 描述输出协议
+func jsonModePrompt(ctx context.Context, categories []string, historyContext []string, enableRoast bool) (string, error) {
+	return prompt.FromContext(ctx).Render(prompt.JSONSystem, promptData(ctx, categories, historyContext, enableRoast))
+}
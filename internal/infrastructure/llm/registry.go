@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ProviderSpec 单个模型后端的配置
+type ProviderSpec struct {
+	Type      string // 后端类型，对应 Register 时的名字：deepseek / openai / ollama / offline / fake
+	APIKey    string
+	BaseURL   string
+	Model     string
+	ToolCalls *bool // 是否支持工具调用，不填则使用该类型的默认值
+}
+
+// Factory 根据配置创建 Provider，name 是配置里给这个后端起的名字，用于日志
+type Factory func(name string, spec ProviderSpec) (Provider, error)
+
+// factories 只在 init / main 启动阶段注册，运行期只读，不加锁
+var factories = map[string]Factory{
+	"deepseek": func(name string, spec ProviderSpec) (Provider, error) {
+		client := NewDeepSeekClient(spec.APIKey, spec.BaseURL, spec.Model)
+		client.name = name
+		if spec.ToolCalls != nil {
+			client.toolCalls = *spec.ToolCalls
+		}
+		return client, nil
+	},
+	"openai": func(name string, spec ProviderSpec) (Provider, error) {
+		if spec.Model == "" {
+			return nil, fmt.Errorf("provider %s: 缺少 model", name)
+		}
+		// 大部分 OpenAI 兼容接口都支持工具调用，不支持的在配置里显式关掉
+		toolCalls := true
+		if spec.ToolCalls != nil {
+			toolCalls = *spec.ToolCalls
+		}
+		return NewOpenAICompatibleClient(name, spec.APIKey, spec.BaseURL, spec.Model, toolCalls), nil
+	},
+	"ollama": func(name string, spec ProviderSpec) (Provider, error) {
+		if spec.Model == "" {
+			return nil, fmt.Errorf("provider %s: 缺少 model", name)
+		}
+		if spec.ToolCalls != nil && *spec.ToolCalls {
+			return nil, fmt.Errorf("provider %s: ollama 后端只支持 JSON 模式", name)
+		}
+		return NewOllamaClient(name, spec.BaseURL, spec.Model), nil
+	},
+	"offline": func(name string, spec ProviderSpec) (Provider, error) {
+		return NewOfflineProvider(name), nil
+	},
+	"fake": func(name string, spec ProviderSpec) (Provider, error) {
+		return NewFakeClient(name), nil
+	},
+}
+
+// Register 注册新的后端类型，同名覆盖
+func Register(typ string, factory Factory) {
+	factories[typ] = factory
+}
+
+// Types 已注册的后端类型
+func Types() []string {
+	types := make([]string, 0, len(factories))
+	for t := range factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewProvider 按配置创建 Provider
+func NewProvider(name string, spec ProviderSpec) (Provider, error) {
+	factory, ok := factories[spec.Type]
+	if !ok {
+		return nil, fmt.Errorf("provider %s: 未知的后端类型 %q，可选: %v", name, spec.Type, Types())
+	}
+	return factory(name, spec)
+}
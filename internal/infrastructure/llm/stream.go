@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrEmptyOutput 模型正常结束但没有输出任何内容
+var ErrEmptyOutput = errors.New("模型没有返回任何内容")
+
+// Stream 模型的流式输出
+// 消费方读 C 直到关闭，然后调用 Err 查看结束原因：nil 表示模型完整输出，
+// 否则拿到的内容是截断的，不能拿去落库
+type Stream[T any] struct {
+	C   <-chan T
+	err error // 在 C 关闭前写入，关闭之后读取是安全的
+}
+
+// Err 流的结束原因，只有在 C 关闭之后调用才有意义
+func (s *Stream[T]) Err() error {
+	return s.err
+}
+
+// Drain 丢弃剩余的片段并返回结束原因
+// 放弃读取时调用，生产端的 ctx 必须已经取消，否则会一直等到模型输出完
+func (s *Stream[T]) Drain() error {
+	for range s.C {
+	}
+	return s.err
+}
+
+// streamWriter 流的生产端，所有发送都感知 ctx：消费方走掉并取消 ctx 后，生产端不会卡在发送上
+type streamWriter[T any] struct {
+	ctx    context.Context
+	ch     chan T
+	stream *Stream[T]
+}
+
+func newStream[T any](ctx context.Context) (*Stream[T], *streamWriter[T]) {
+	ch := make(chan T, 10)
+	s := &Stream[T]{C: ch}
+	return s, &streamWriter[T]{ctx: ctx, ch: ch, stream: s}
+}
+
+// Send 发送一个片段，ctx 已取消时返回 false，生产端应当立刻收尾退出
+func (w *streamWriter[T]) Send(v T) bool {
+	select {
+	case w.ch <- v:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
+
+// Close 结束输出，每个流只能调用一次
+// err 为空但 ctx 已经取消时，记为 ctx 的错误，消费方不会把被打断的输出当成完整的
+func (w *streamWriter[T]) Close(err error) {
+	if err == nil {
+		err = w.ctx.Err()
+	}
+	w.stream.err = err
+	close(w.ch)
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+	"github.com/sashabaranov/go-openai"
+	"io"
+	"log/slog"
+)
+
+// OpenAIVisionClient 对接 OpenAI 兼容的多模态模型 (如 gpt-4o、qwen-vl)
+type OpenAIVisionClient struct {
+	modelName string
+	client    *openai.Client
+}
+
+func NewOpenAIVisionClient(apiKey, baseUrl, modelName string) *OpenAIVisionClient {
+	config := openai.DefaultConfig(apiKey)
+	if baseUrl != "" {
+		config.BaseURL = baseUrl
+	}
+	if modelName == "" {
+		modelName = openai.GPT4o
+	}
+
+	return &OpenAIVisionClient{
+		modelName: modelName,
+		client:    openai.NewClientWithConfig(config),
+	}
+}
+
+func (v *OpenAIVisionClient) AnalyzeReceipt(ctx context.Context, image ReceiptImage, categories []string, enableRoast bool) (*Stream[ToolCallFragment], error) {
+	tmpl := prompt.FromContext(ctx)
+	data := promptData(ctx, categories, nil, enableRoast)
+	sysPrompt, err := tmpl.Render(prompt.Receipt, data)
+	if err != nil {
+		return nil, err
+	}
+	tool, err := GenerateBookExpenseTool(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 图片以 Data URI 的形式内联，避免额外的对象存储依赖
+	dataURI := fmt.Sprintf("data:%s;base64,%s", image.MimeType, base64.StdEncoding.EncodeToString(image.Data))
+
+	req := openai.ChatCompletionRequest{
+		Model: v.modelName,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: sysPrompt},
+			{
+				Role: openai.ChatMessageRoleUser,
+				MultiContent: []openai.ChatMessagePart{
+					{Type: openai.ChatMessagePartTypeText, Text: "请帮我把这张图里的消费记下来。"},
+					{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{
+						URL:    dataURI,
+						Detail: openai.ImageURLDetailAuto,
+					}},
+				},
+			},
+		},
+		Tools: []openai.Tool{tool},
+		// 多笔消费需要多次调用，因此不能像文本记账那样锁定单个函数
+		ToolChoice:        "required",
+		ParallelToolCalls: true,
+		Temperature:       0.1,
+		Stream:            true,
+		StreamOptions:     &openai.StreamOptions{IncludeUsage: true},
+	}
+	stream, err := v.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out, w := newStream[ToolCallFragment](ctx)
+	go func() {
+		defer stream.Close()
+		var usage openai.Usage
+		for {
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				RecordCompletion(ctx, Completion{
+					Model:            v.modelName,
+					PromptVersion:    tmpl.Version,
+					PromptTokens:     usage.PromptTokens,
+					CompletionTokens: usage.CompletionTokens,
+				})
+				w.Close(nil)
+				return
+			}
+			if err != nil {
+				slog.Error("Vision stream error", "err", err)
+				w.Close(err)
+				return
+			}
+			if response.Usage != nil {
+				usage = *response.Usage
+			}
+			if len(response.Choices) == 0 {
+				continue
+			}
+			for _, call := range response.Choices[0].Delta.ToolCalls {
+				if call.Function.Arguments == "" {
+					continue
+				}
+				index := 0
+				if call.Index != nil {
+					index = *call.Index
+				}
+				if !w.Send(ToolCallFragment{Index: index, Arguments: call.Function.Arguments}) {
+					w.Close(nil)
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
@@ -20,25 +20,61 @@ func (r *QdrantRepository) SaveMemory(ctx context.Context, uid string, expenseID
 	// 2. 构造 Qdrant Point
 
 	points := []*pb.PointStruct{
-		{
-			Id: &pb.PointId{
-				PointIdOptions: &pb.PointId_Num{Num: uint64(expenseID)},
-			},
-			Vectors: &pb.Vectors{
-				VectorsOptions: &pb.Vectors_Vector{
-					Vector: &pb.Vector{Data: vector},
-				},
-			},
-			Payload: map[string]*pb.Value{
-				"user_id":     {Kind: &pb.Value_StringValue{StringValue: uid}},
-				"expense_id":  {Kind: &pb.Value_IntegerValue{IntegerValue: int64(expenseID)}},
-				"description": {Kind: &pb.Value_StringValue{StringValue: description}},
-				"timestamp":   {Kind: &pb.Value_IntegerValue{IntegerValue: time.Now().Unix()}},
-				"category":    {Kind: &pb.Value_StringValue{StringValue: category}},
+		buildPoint(uid, repository.MemoryItem{
+			ExpenseID:   expenseID,
+			Description: description,
+			Category:    category,
+			Vector:      vector,
+			Timestamp:   time.Now().Unix(),
+		}),
+	}
+
+	if err := r.upsert(ctx, points); err != nil {
+		return err
+	}
+
+	log.Printf("Saved memory to Qdrant. ID: %d, Desc: %s", expenseID, description)
+	return nil
+}
+
+// SaveMemories 批量写入记忆，一次 Upsert 多个 Point
+func (r *QdrantRepository) SaveMemories(ctx context.Context, uid string, items []repository.MemoryItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	points := make([]*pb.PointStruct, 0, len(items))
+	for _, item := range items {
+		points = append(points, buildPoint(uid, item))
+	}
+	if err := r.upsert(ctx, points); err != nil {
+		return err
+	}
+
+	log.Printf("Saved %d memories to Qdrant.", len(items))
+	return nil
+}
+
+func buildPoint(uid string, item repository.MemoryItem) *pb.PointStruct {
+	return &pb.PointStruct{
+		Id: &pb.PointId{
+			PointIdOptions: &pb.PointId_Num{Num: uint64(item.ExpenseID)},
+		},
+		Vectors: &pb.Vectors{
+			VectorsOptions: &pb.Vectors_Vector{
+				Vector: &pb.Vector{Data: item.Vector},
 			},
 		},
+		Payload: map[string]*pb.Value{
+			"user_id":     {Kind: &pb.Value_StringValue{StringValue: uid}},
+			"expense_id":  {Kind: &pb.Value_IntegerValue{IntegerValue: int64(item.ExpenseID)}},
+			"description": {Kind: &pb.Value_StringValue{StringValue: item.Description}},
+			"timestamp":   {Kind: &pb.Value_IntegerValue{IntegerValue: item.Timestamp}},
+			"category":    {Kind: &pb.Value_StringValue{StringValue: item.Category}},
+		},
 	}
+}
 
+func (r *QdrantRepository) upsert(ctx context.Context, points []*pb.PointStruct) error {
 	wait := true
 	// 3. Upsert 入库
 	_, err := r.client.points.Upsert(ctx, &pb.UpsertPoints{
@@ -48,11 +84,9 @@ func (r *QdrantRepository) SaveMemory(ctx context.Context, uid string, expenseID
 	})
 
 	if err != nil {
-		slog.Error("qdrant upsert failed: %v", err)
+		slog.Error("qdrant upsert failed", "error", err)
 		return fmt.Errorf("qdrant upsert failed: %v", err)
 	}
-
-	log.Printf("Saved memory to Qdrant. ID: %d, Desc: %s", expenseID, description)
 	return nil
 }
 
@@ -86,7 +120,7 @@ func (r *QdrantRepository) SearchSimilar(ctx context.Context, uid string, limit
 		},
 	})
 	if err != nil {
-		slog.Error("qdrant search failed: %v", err)
+		slog.Error("qdrant search failed", "error", err)
 		return nil, fmt.Errorf("qdrant search failed: %v", err)
 	}
 
@@ -114,6 +148,7 @@ func (r *QdrantRepository) SearchSimilar(ctx context.Context, uid string, limit
 			category = cat.GetStringValue()
 		}
 		histories = append(histories, repository.MemoryResult{
+			ExpenseID: uint(point.GetId().GetNum()), // Point ID 就是账单 ID
 			Content:   content,
 			Timestamp: ts,
 			Category:  category,
@@ -140,6 +175,26 @@ func (r *QdrantRepository) Delete(ctx context.Context, id int64) error {
 	return err
 }
 
+// DeleteMany 批量删除记忆，用于导入批次回滚
+func (r *QdrantRepository) DeleteMany(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	pointIDs := make([]*pb.PointId, 0, len(ids))
+	for _, id := range ids {
+		pointIDs = append(pointIDs, &pb.PointId{PointIdOptions: &pb.PointId_Num{Num: uint64(id)}})
+	}
+	_, err := r.client.points.Delete(ctx, &pb.DeletePoints{
+		CollectionName: CollectionName,
+		Points: &pb.PointsSelector{
+			PointsSelectorOneOf: &pb.PointsSelector_Points{
+				Points: &pb.PointsIdsList{Ids: pointIDs},
+			},
+		},
+	})
+	return err
+}
+
 // NewQdrantRepository 构造函数
 func NewQdrantRepository(client *QdrantClient) repository.MemoryRepo {
 	return &QdrantRepository{
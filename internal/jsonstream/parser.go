@@ -0,0 +1,283 @@
+// Package jsonstream 增量解析模型流式输出的工具参数 JSON
+// 片段可能在任意位置被切开 (包括转义序列和多字节字符的中间)，解析器逐字节推进，值一确定就产出事件
+package jsonstream
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Event 某个字段的值已经确定，或流式字段有了新的文本
+type Event struct {
+	Field string
+	// 普通字段为完整的值：字符串是反转义后的文本，数字、布尔、null 和嵌套结构是 JSON 原文
+	// 流式字段为本次新增的文本，调用方按顺序拼接
+	Value string
+	// Partial 流式字段的中间片段，字符串结束时的最后一个事件为 false
+	Partial bool
+}
+
+// 解析状态
+const (
+	stBeforeObject = iota // 等待最外层的 {
+	stBeforeKey           // 等待字段名 (或 })
+	stKey                 // 字段名字符串内部
+	stColon               // 等待 :
+	stBeforeValue         // 等待值的第一个字符
+	stString              // 字符串值内部
+	stLiteral             // 数字 / true / false / null
+	stNested              // 对象或数组，整体作为原文
+	stAfterValue          // 等待 , 或 }
+	stDone                // 最外层对象已结束，后续内容忽略
+)
+
+// Parser 只处理一层扁平对象 (book_expense 的参数就是这样)，嵌套的值不展开
+type Parser struct {
+	streaming map[string]bool
+
+	state int
+	key   strings.Builder
+	value strings.Builder
+	sent  int // 流式字段已经发出的字节数
+
+	// 字符串转义状态
+	escaping  bool
+	hex       []byte // \u 后面收集到的十六进制数字
+	surrogate rune   // 等待低位代理的高位代理
+
+	// 嵌套值的括号深度和字符串状态
+	depth          int
+	nestedInString bool
+	nestedEscaping bool
+
+	events []Event
+}
+
+// NewParser streaming 为需要逐字推送的字段名 (如 "comment")，其他字段在值完整后推送一次
+func NewParser(streaming ...string) *Parser {
+	p := &Parser{streaming: make(map[string]bool, len(streaming))}
+	for _, f := range streaming {
+		p.streaming[f] = true
+	}
+	return p
+}
+
+// Feed 喂入一个片段，返回因此确定的事件
+func (p *Parser) Feed(fragment string) []Event {
+	p.events = nil
+	for i := 0; i < len(fragment); i++ {
+		p.step(fragment[i])
+	}
+	// 流式字段在每个片段结束时推送一次新增的文本，不逐字节推送
+	if p.state == stString && p.streaming[p.key.String()] {
+		p.flushPartial(false)
+	}
+	return p.events
+}
+
+// Done 最外层对象是否已经闭合
+func (p *Parser) Done() bool {
+	return p.state == stDone
+}
+
+func (p *Parser) step(b byte) {
+	switch p.state {
+	case stBeforeObject:
+		if b == '{' {
+			p.state = stBeforeKey
+		}
+	case stBeforeKey:
+		switch b {
+		case '"':
+			p.key.Reset()
+			p.state = stKey
+		case '}':
+			p.state = stDone
+		}
+	case stKey:
+		if p.decodeString(b, &p.key) {
+			p.state = stColon
+		}
+	case stColon:
+		if b == ':' {
+			p.state = stBeforeValue
+		}
+	case stBeforeValue:
+		if isSpace(b) {
+			return
+		}
+		p.value.Reset()
+		p.sent = 0
+		switch b {
+		case '"':
+			p.state = stString
+		case '{', '[':
+			p.value.WriteByte(b)
+			p.depth, p.nestedInString, p.nestedEscaping = 1, false, false
+			p.state = stNested
+		default:
+			p.value.WriteByte(b)
+			p.state = stLiteral
+		}
+	case stString:
+		if p.decodeString(b, &p.value) {
+			if p.streaming[p.key.String()] {
+				p.flushPartial(true)
+			} else {
+				p.emit(p.value.String(), false)
+			}
+			p.state = stAfterValue
+		}
+	case stLiteral:
+		if b == ',' || b == '}' || isSpace(b) {
+			p.emit(p.value.String(), false)
+			p.state = stAfterValue
+			p.step(b)
+			return
+		}
+		p.value.WriteByte(b)
+	case stNested:
+		p.value.WriteByte(b)
+		p.stepNested(b)
+		if p.depth == 0 {
+			p.emit(p.value.String(), false)
+			p.state = stAfterValue
+		}
+	case stAfterValue:
+		switch b {
+		case ',':
+			p.state = stBeforeKey
+		case '}':
+			p.state = stDone
+		}
+	}
+}
+
+// stepNested 跟踪嵌套值的括号深度，字符串里的括号不算
+func (p *Parser) stepNested(b byte) {
+	switch {
+	case p.nestedEscaping:
+		p.nestedEscaping = false
+	case p.nestedInString:
+		switch b {
+		case '\\':
+			p.nestedEscaping = true
+		case '"':
+			p.nestedInString = false
+		}
+	case b == '"':
+		p.nestedInString = true
+	case b == '{' || b == '[':
+		p.depth++
+	case b == '}' || b == ']':
+		p.depth--
+	}
+}
+
+// decodeString 处理字符串内部的一个字节，遇到结束引号时返回 true
+func (p *Parser) decodeString(b byte, out *strings.Builder) bool {
+	if p.hex != nil {
+		p.hex = append(p.hex, b)
+		if len(p.hex) == 4 {
+			p.writeUnicode(out)
+		}
+		return false
+	}
+	if p.escaping {
+		p.escaping = false
+		if b == 'u' {
+			p.hex = make([]byte, 0, 4)
+			return false
+		}
+		p.flushSurrogate(out)
+		switch b {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		default: // \" \\ \/
+			out.WriteByte(b)
+		}
+		return false
+	}
+	switch b {
+	case '\\':
+		p.escaping = true
+		return false
+	case '"':
+		p.flushSurrogate(out)
+		return true
+	}
+	p.flushSurrogate(out)
+	out.WriteByte(b)
+	return false
+}
+
+// writeUnicode 解码 \uXXXX，emoji 这类字符会拆成一对代理项，要等到低位代理才能写出
+func (p *Parser) writeUnicode(out *strings.Builder) {
+	n, err := strconv.ParseUint(string(p.hex), 16, 16)
+	p.hex = nil
+	if err != nil {
+		p.flushSurrogate(out)
+		out.WriteRune(utf8.RuneError)
+		return
+	}
+	r := rune(n)
+	switch {
+	case utf16.IsSurrogate(r) && r < 0xDC00:
+		p.flushSurrogate(out)
+		p.surrogate = r
+	case utf16.IsSurrogate(r) && p.surrogate != 0:
+		out.WriteRune(utf16.DecodeRune(p.surrogate, r))
+		p.surrogate = 0
+	default:
+		p.flushSurrogate(out)
+		out.WriteRune(r)
+	}
+}
+
+// flushSurrogate 落单的高位代理写成替换字符
+func (p *Parser) flushSurrogate(out *strings.Builder) {
+	if p.surrogate != 0 {
+		out.WriteRune(utf8.RuneError)
+		p.surrogate = 0
+	}
+}
+
+// flushPartial 推送流式字段新增的文本；未结束时不切开多字节字符
+func (p *Parser) flushPartial(final bool) {
+	s := p.value.String()
+	end := len(s)
+	if !final {
+		// 往回找最后一个字符的起始字节，字符不完整就留到下次
+		for i := end - 1; i >= p.sent && i >= end-utf8.UTFMax; i-- {
+			if utf8.RuneStart(s[i]) {
+				if !utf8.FullRuneInString(s[i:end]) {
+					end = i
+				}
+				break
+			}
+		}
+	}
+	if end == p.sent && !final {
+		return
+	}
+	p.emit(s[p.sent:end], !final)
+	p.sent = end
+}
+
+func (p *Parser) emit(value string, partial bool) {
+	p.events = append(p.events, Event{Field: p.key.String(), Value: value, Partial: partial})
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
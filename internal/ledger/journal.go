@@ -0,0 +1,226 @@
+package ledger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+)
+
+const (
+	FormatBeancount = "beancount"
+	FormatHledger   = "hledger"
+)
+
+// AccountScheme 账户命名规则
+// 默认分类 "餐饮美食" 对应 Expenses:餐饮美食，付款账户按导入来源区分，手动记账走 DefaultFunding
+type AccountScheme struct {
+	ExpenseRoot    string            // 支出账户前缀，默认 Expenses
+	Categories     map[string]string // 分类 -> 完整账户名，覆盖 ExpenseRoot 规则
+	DefaultFunding string            // 默认付款账户，默认 Assets:Cash
+	Sources        map[string]string // 导入来源 -> 付款账户，如 alipay -> Assets:Alipay
+	Currency       string            // 默认 CNY
+}
+
+// DefaultScheme 默认命名规则
+func DefaultScheme() AccountScheme {
+	return AccountScheme{
+		ExpenseRoot:    "Expenses",
+		DefaultFunding: "Assets:Cash",
+		Sources: map[string]string{
+			"alipay": "Assets:Alipay",
+			"wechat": "Assets:WeChat",
+		},
+		Currency: "CNY",
+	}
+}
+
+// withDefaults 配置文件里没写的字段用默认值补齐
+func (s AccountScheme) withDefaults() AccountScheme {
+	def := DefaultScheme()
+	if s.ExpenseRoot == "" {
+		s.ExpenseRoot = def.ExpenseRoot
+	}
+	if s.DefaultFunding == "" {
+		s.DefaultFunding = def.DefaultFunding
+	}
+	if s.Sources == nil {
+		s.Sources = def.Sources
+	}
+	if s.Currency == "" {
+		s.Currency = def.Currency
+	}
+	return s
+}
+
+// ExpenseAccount 分类对应的支出账户
+func (s AccountScheme) ExpenseAccount(category string) string {
+	if account, ok := s.Categories[category]; ok {
+		return sanitizeAccount(account)
+	}
+	if category == "" {
+		category = "未分类"
+	}
+	return sanitizeAccount(s.ExpenseRoot + ":" + category)
+}
+
+// FundingAccount 账单的付款账户
+func (s AccountScheme) FundingAccount(source string) string {
+	if account, ok := s.Sources[source]; ok {
+		return sanitizeAccount(account)
+	}
+	return sanitizeAccount(s.DefaultFunding)
+}
+
+// sanitizeAccount 两种格式都要求账户名不含空白，Beancount 还要求每一段以大写字母、数字或非 ASCII 字符开头
+func sanitizeAccount(account string) string {
+	parts := strings.Split(account, ":")
+	out := parts[:0]
+	for _, part := range parts {
+		part = strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) || (r <= unicode.MaxASCII && !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-') {
+				return '-'
+			}
+			return r
+		}, strings.TrimSpace(part))
+		part = strings.Trim(part, "-")
+		if part == "" {
+			continue
+		}
+		if r := []rune(part); r[0] <= unicode.MaxASCII && unicode.IsLower(r[0]) {
+			r[0] = unicode.ToUpper(r[0])
+			part = string(r)
+		}
+		out = append(out, part)
+	}
+	return strings.Join(out, ":")
+}
+
+// AccountUsage 某个分类 + 来源组合第一次出现的时间，用于生成开户指令
+type AccountUsage struct {
+	Category string
+	Source   string
+	FirstAt  time.Time
+}
+
+// Writer 流式输出日记账：先调用 WriteHeader 写账户声明，再逐条 WriteExpense
+type Writer struct {
+	w      *bufio.Writer
+	format string
+	scheme AccountScheme
+	loc    *time.Location
+}
+
+// NewWriter 构造函数，loc 决定账单日期按哪个时区落到哪一天
+func NewWriter(w io.Writer, format string, scheme AccountScheme, loc *time.Location) (*Writer, error) {
+	if format != FormatBeancount && format != FormatHledger {
+		return nil, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	return &Writer{w: bufio.NewWriter(w), format: format, scheme: scheme.withDefaults(), loc: loc}, nil
+}
+
+// WriteHeader 写文件头和账户声明，每个账户的开户日期取第一次使用的日期
+func (jw *Writer) WriteHeader(usages []AccountUsage) error {
+	opened := map[string]time.Time{}
+	var order []string
+	open := func(account string, at time.Time) {
+		first, ok := opened[account]
+		if !ok {
+			order = append(order, account)
+		}
+		if !ok || at.Before(first) {
+			opened[account] = at
+		}
+	}
+	for _, u := range usages {
+		open(jw.scheme.FundingAccount(u.Source), u.FirstAt)
+		open(jw.scheme.ExpenseAccount(u.Category), u.FirstAt)
+	}
+
+	switch jw.format {
+	case FormatBeancount:
+		fmt.Fprintf(jw.w, "; FaceTax 导出 %s\n", time.Now().In(jw.loc).Format("2006-01-02 15:04"))
+		fmt.Fprintf(jw.w, "option \"operating_currency\" %s\n\n", strconv.Quote(jw.scheme.Currency))
+		for _, account := range order {
+			fmt.Fprintf(jw.w, "%s open %s %s\n", opened[account].In(jw.loc).Format("2006-01-02"), account, jw.scheme.Currency)
+		}
+	case FormatHledger:
+		fmt.Fprintf(jw.w, "; FaceTax 导出 %s\n", time.Now().In(jw.loc).Format("2006-01-02 15:04"))
+		fmt.Fprintf(jw.w, "commodity 1,000.00 %s\n\n", jw.scheme.Currency)
+		for _, account := range order {
+			fmt.Fprintf(jw.w, "account %s\n", account)
+		}
+	}
+	_, err := jw.w.WriteString("\n")
+	return err
+}
+
+// WriteExpense 写一笔交易，元数据里带上账单 ID 和吐槽，方便对账后再导回来
+func (jw *Writer) WriteExpense(e *model.ExpenseEntity) error {
+	date := e.CreatedAt.In(jw.loc)
+	amount := strconv.FormatFloat(e.Amount, 'f', 2, 64)
+	expenseAccount := jw.scheme.ExpenseAccount(e.Category)
+	fundingAccount := jw.scheme.FundingAccount(e.Source)
+
+	switch jw.format {
+	case FormatBeancount:
+		fmt.Fprintf(jw.w, "%s * %s\n", date.Format("2006-01-02"), beancountString(e.Note))
+		fmt.Fprintf(jw.w, "  expense_id: %d\n", e.ID)
+		fmt.Fprintf(jw.w, "  time: %s\n", beancountString(date.Format("15:04:05")))
+		if e.Comment != "" {
+			fmt.Fprintf(jw.w, "  roast: %s\n", beancountString(e.Comment))
+		}
+		if e.Source != "" {
+			fmt.Fprintf(jw.w, "  source: %s\n", beancountString(e.Source))
+		}
+		if e.ExternalID != "" {
+			fmt.Fprintf(jw.w, "  external_id: %s\n", beancountString(e.ExternalID))
+		}
+		fmt.Fprintf(jw.w, "  %s  %s %s\n", expenseAccount, amount, jw.scheme.Currency)
+		fmt.Fprintf(jw.w, "  %s\n\n", fundingAccount)
+	case FormatHledger:
+		// hledger 的标签值遇到逗号就结束，每个标签单独一行
+		fmt.Fprintf(jw.w, "%s * %s\n", date.Format("2006-01-02"), hledgerText(e.Note))
+		fmt.Fprintf(jw.w, "    ; expense_id: %d\n", e.ID)
+		fmt.Fprintf(jw.w, "    ; time: %s\n", date.Format("15:04:05"))
+		if e.Comment != "" {
+			fmt.Fprintf(jw.w, "    ; roast: %s\n", hledgerText(e.Comment))
+		}
+		if e.Source != "" {
+			fmt.Fprintf(jw.w, "    ; source: %s\n", hledgerText(e.Source))
+		}
+		if e.ExternalID != "" {
+			fmt.Fprintf(jw.w, "    ; external_id: %s\n", hledgerText(e.ExternalID))
+		}
+		fmt.Fprintf(jw.w, "    %s    %s %s\n", expenseAccount, amount, jw.scheme.Currency)
+		fmt.Fprintf(jw.w, "    %s\n\n", fundingAccount)
+	}
+	return nil
+}
+
+// Flush 把缓冲区写出去，导出结束时必须调用
+func (jw *Writer) Flush() error {
+	return jw.w.Flush()
+}
+
+// beancountString Beancount 字符串支持 \" 和 \\ 转义，换行压成空格
+func beancountString(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// hledgerText hledger 没有转义：换行压成空格，半角逗号/分号换成全角，避免截断标签值或被当成注释
+func hledgerText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.NewReplacer(",", "，", ";", "；").Replace(s)
+}
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// 审计记录的来源
+const (
+	AuditSourceAnalyze      = "analyze"      // 文字记账 (SSE、同步接口、异步任务)
+	AuditSourceReceipt      = "receipt"      // 图片记账
+	AuditSourceNotification = "notification" // 支付通知导入
+	AuditSourceBulk         = "bulk"         // 批量记账预览
+	AuditSourceReanalyze    = "reanalyze"    // 重新分析预览
+	AuditSourceModeration   = "moderation"   // 吐槽没通过内容检查后重新生成
+)
+
+// AnalysisAudit 一次模型识别的审计记录，用来排查分类错误
+// 预览类的识别 (批量记账、重新分析) 先写入，用户确认后再关联到账单；ExpenseID 为 0 表示结果没有被采用
+type AnalysisAudit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	UserID    string `gorm:"type:varchar(64);index" json:"user_id"`
+	ExpenseID uint   `gorm:"index" json:"expense_id"`
+	Source    string `gorm:"type:varchar(32)" json:"source"`
+
+	Description   string   `gorm:"type:text" json:"description"` // 用户的原始描述，图片记账为空
+	Provider      string   `gorm:"type:varchar(64)" json:"provider"`
+	Model         string   `gorm:"type:varchar(128)" json:"model"`
+	PromptVersion string   `gorm:"type:varchar(64)" json:"prompt_version"`
+	Persona       string   `gorm:"type:varchar(32)" json:"persona,omitempty"`         // 吐槽人设，不吐槽时为空
+	Injection     string   `gorm:"type:varchar(32);index" json:"injection,omitempty"` // 描述命中的提示词注入特征，为空表示没有
+	History       []string `gorm:"serializer:json;type:text" json:"history"`          // 检索到并放进 Prompt 的历史消费
+	RawOutput     string   `gorm:"type:text" json:"raw_output"`                       // 模型输出的原始 JSON，解析失败的也保留
+
+	LatencyMS        int64 `json:"latency_ms"` // 从调用模型到输出结束
+	PromptTokens     int   `json:"prompt_tokens"`
+	CompletionTokens int   `json:"completion_tokens"`
+
+	Experiment string `gorm:"type:varchar(64);index" json:"experiment,omitempty"` // 调用时用户所在的实验和分组
+	Variant    string `gorm:"type:varchar(64)" json:"variant,omitempty"`
+
+	// 用户事后修改了这笔账单的哪些字段 (amount / category / note)，为空表示没改过；直接改账单记在第一次记账的识别记录上，改重新分析的建议记在那次重新分析的记录上
+	CorrectedFields []string   `gorm:"serializer:json;type:text" json:"corrected_fields,omitempty"`
+	CorrectedAt     *time.Time `json:"corrected_at,omitempty"`
+}
+
+// TableName 强制指定表名
+func (AnalysisAudit) TableName() string {
+	return "analysis_audits"
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 异步记账任务状态
+const (
+	AnalysisJobPending   = "pending"   // 排队中 (包括等待重试)
+	AnalysisJobRunning   = "running"   // 有 worker 正在处理
+	AnalysisJobSucceeded = "succeeded" // 已记账
+	AnalysisJobFailed    = "failed"    // 重试次数用完仍然失败
+)
+
+// 回调投递状态
+const (
+	CallbackNone      = ""          // 没有配置回调地址
+	CallbackPending   = "pending"   // 等待投递 (包括等待重试)
+	CallbackDelivered = "delivered" // 对方已返回 2xx
+	CallbackFailed    = "failed"    // 重试次数用完仍然失败
+)
+
+// AnalysisJob 一次异步记账任务
+// 任务先落库再由 worker 领取，服务重启后没跑完的任务会重新排队
+type AnalysisJob struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID      string `gorm:"type:varchar(64);index" json:"user_id"`
+	Description string `gorm:"type:text" json:"description"`
+	TimeZone    string `gorm:"type:varchar(64)" json:"timezone,omitempty"`
+	CallbackURL string `gorm:"type:varchar(512)" json:"callback_url,omitempty"`
+
+	Status    string    `gorm:"type:varchar(16);index:idx_analysis_jobs_due,priority:1" json:"status"`
+	NextRunAt time.Time `gorm:"index:idx_analysis_jobs_due,priority:2" json:"next_run_at"` // 排队中的任务最早什么时候可以被领取
+	Attempts  int       `json:"attempts"`                                                  // 已经被领取的次数
+
+	// 成功时的结果：与同步记账接口的 data 相同
+	ExpenseID *uint           `json:"expense_id,omitempty"`
+	Result    json.RawMessage `gorm:"type:text" json:"result,omitempty" swaggertype:"object"`
+	// 最近一次失败的原因码和文案，取值同 SSE 的 error 事件
+	ErrorCode string `gorm:"type:varchar(32)" json:"error_code,omitempty"`
+	Error     string `gorm:"type:varchar(255)" json:"error,omitempty"`
+
+	CallbackStatus   string     `gorm:"type:varchar(16);index" json:"callback_status,omitempty"`
+	CallbackAttempts int        `json:"callback_attempts,omitempty"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+}
+
+func (AnalysisJob) TableName() string {
+	return "analysis_jobs"
+}
+
+// Finished 任务是否已经有最终结果
+func (j *AnalysisJob) Finished() bool {
+	return j.Status == AnalysisJobSucceeded || j.Status == AnalysisJobFailed
+}
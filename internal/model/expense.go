@@ -20,6 +20,16 @@ type ExpenseEntity struct {
 	Comment  string `gorm:"type:text" json:"comment"`
 	Category string `gorm:"type:varchar(64)" json:"category"`
 	Note     string `gorm:"type:text" json:"note"`
+	// 用户的原始描述，重新分析时再交给模型；图片记账和账单导入为空
+	Description string `gorm:"type:text" json:"description,omitempty"`
+
+	// 导入来源，手动记账为空
+	Source     string `gorm:"type:varchar(32)" json:"source,omitempty"`                                  // alipay / wechat
+	ExternalID string `gorm:"type:varchar(128);index:idx_expense_external" json:"external_id,omitempty"` // 平台交易单号，用于导入去重
+	// 所属导入批次，整批回滚时按它删除
+	ImportBatchID uint `gorm:"index" json:"import_batch_id,omitempty"`
+	// 生成这条账单的异步记账任务，任务重跑时据此判断是否已经落过库
+	AnalysisJobID *uint `gorm:"uniqueIndex" json:"-"`
 }
 
 // TableName 强制指定表名
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// 实验状态
+const (
+	ExperimentDraft   = "draft"   // 已创建，还没开始分流
+	ExperimentRunning = "running" // 正在分流，同一时间最多一个
+	ExperimentStopped = "stopped" // 已停止，数据保留用于对比
+)
+
+// ExperimentVariant 实验里的一组：使用哪个 Prompt 版本和哪个模型后端
+// 留空的一项沿用平时的配置，全部留空就是对照组
+type ExperimentVariant struct {
+	Name          string `json:"name"`
+	PromptVersion string `json:"prompt_version,omitempty"` // Prompt 模板版本
+	Provider      string `json:"provider,omitempty"`       // llm.providers 里的后端名字
+	Weight        int    `json:"weight"`                   // 分流权重，不填按 1
+}
+
+// Experiment Prompt / 模型的 A/B 实验
+// 用户按 ID 哈希稳定地分到某一组，审计记录上会记下实验和分组，报表据此对比各组的修改率
+type Experiment struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name        string              `gorm:"type:varchar(64);uniqueIndex" json:"name"`
+	Description string              `gorm:"type:text" json:"description,omitempty"`
+	Status      string              `gorm:"type:varchar(16);index" json:"status"`
+	Variants    []ExperimentVariant `gorm:"serializer:json;type:text" json:"variants"`
+
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+}
+
+// TableName 强制指定表名
+func (Experiment) TableName() string {
+	return "experiments"
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"time"
+)
+
+// 导入批次状态
+const (
+	ImportBatchPending    = "pending"
+	ImportBatchRunning    = "running"
+	ImportBatchCompleted  = "completed"
+	ImportBatchFailed     = "failed"
+	ImportBatchRolledBack = "rolled_back"
+)
+
+// ColumnMapping 描述一份银行流水 CSV 的列结构
+// 列可以写表头名 (如 "交易日期")，也可以写从 1 开始的列序号 (如 "3")
+type ColumnMapping struct {
+	DateColumn         string `gorm:"type:varchar(64)" json:"date_column"`
+	DateLayout         string `gorm:"type:varchar(64)" json:"date_layout"`         // Go 时间格式，为空时自动尝试常见格式
+	AmountColumn       string `gorm:"type:varchar(64)" json:"amount_column"`       // 单列带符号金额
+	DebitColumn        string `gorm:"type:varchar(64)" json:"debit_column"`        // 或者：支出列
+	CreditColumn       string `gorm:"type:varchar(64)" json:"credit_column"`       // 或者：收入列
+	DescriptionColumn  string `gorm:"type:varchar(64)" json:"description_column"`  // 摘要/用途
+	CounterpartyColumn string `gorm:"type:varchar(64)" json:"counterparty_column"` // 对方户名，可选
+	ExpenseNegative    bool   `json:"expense_negative"`                            // 单列金额时，支出是否为负数
+	HasHeader          bool   `json:"has_header"`                                  // 第一行 (SkipRows 之后) 是否为表头
+	SkipRows           int    `json:"skip_rows"`                                   // 表头前需要跳过的说明行数
+	Delimiter          string `gorm:"type:varchar(4)" json:"delimiter"`            // 默认逗号
+}
+
+// ImportProfile 用户保存的列映射方案，每家银行存一份，下次导入直接选
+type ImportProfile struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID  string        `gorm:"type:varchar(64);index" json:"user_id"`
+	Name    string        `gorm:"type:varchar(100)" json:"name"`
+	Mapping ColumnMapping `gorm:"embedded;embeddedPrefix:map_" json:"mapping"`
+}
+
+func (ImportProfile) TableName() string {
+	return "import_profiles"
+}
+
+// ImportBatch 一次异步导入任务
+// 原始文件和列映射快照都存在批次里，服务重启后可以从 Processed 断点继续
+type ImportBatch struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID   string        `gorm:"type:varchar(64);index" json:"user_id"`
+	Format   string        `gorm:"type:varchar(16)" json:"format"` // csv / ofx / qif
+	FileName string        `gorm:"type:varchar(255)" json:"file_name"`
+	Mapping  ColumnMapping `gorm:"embedded;embeddedPrefix:map_" json:"mapping"`
+	Payload  []byte        `gorm:"type:longblob" json:"-"`
+
+	Status     string     `gorm:"type:varchar(16);index" json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"` // 断点：已处理的记录数
+	Imported   int        `json:"imported"`
+	Duplicates int        `json:"duplicates"`
+	Skipped    int        `json:"skipped"`
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (ImportBatch) TableName() string {
+	return "import_batches"
+}
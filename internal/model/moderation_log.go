@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// 吐槽没通过内容检查后的处理方式
+const (
+	ModerationRegenerate = "regenerate" // 让模型重新生成
+	ModerationReplace    = "replace"    // 换成兜底文案
+)
+
+// ModerationLog 一条没通过内容检查的吐槽
+// 重新生成的吐槽也没通过时会再记一条，同一次记账的几条 Final 相同
+type ModerationLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	UserID    string `gorm:"type:varchar(64);index" json:"user_id"`
+	ExpenseID uint   `gorm:"index" json:"expense_id"` // 0 表示还没落库 (批量预览) 或没有落库
+	Source    string `gorm:"type:varchar(32)" json:"source"`
+	Persona   string `gorm:"type:varchar(32)" json:"persona"`
+
+	Comment    string `gorm:"type:text" json:"comment"` // 没通过的吐槽
+	Category   string `gorm:"type:varchar(32);index" json:"category"`
+	Reason     string `gorm:"type:varchar(255)" json:"reason"`
+	Classifier string `gorm:"type:varchar(32)" json:"classifier"`
+
+	Action string `gorm:"type:varchar(16)" json:"action"` // regenerate / replace
+	Final  string `gorm:"type:text" json:"final"`         // 最终写进账单的吐槽
+}
+
+// TableName 强制指定表名
+func (ModerationLog) TableName() string {
+	return "moderation_logs"
+}
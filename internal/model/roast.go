@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// 用户对吐槽的反馈
+const (
+	RoastDisliked int8 = -1
+	RoastNoVote   int8 = 0
+	RoastLiked    int8 = 1
+)
+
+// Roast 一条写进账单的吐槽 (ExpenseEntity.Comment)，连同生成它的人设、Prompt 版本和用户的反馈
+// 最近的和被踩的吐槽会放进 Prompt 避免重复，被赞的作为风格示例
+type Roast struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	UserID    string `gorm:"type:varchar(64);index" json:"user_id"`
+	ExpenseID uint   `gorm:"uniqueIndex" json:"expense_id"`
+	Comment   string `gorm:"type:text" json:"comment"`
+
+	Persona       string `gorm:"type:varchar(32)" json:"persona"` // 为空表示不知道 (功能上线前的账单)
+	PromptVersion string `gorm:"type:varchar(64)" json:"prompt_version"`
+
+	Feedback   int8       `json:"feedback"` // 1 赞，-1 踩，0 没有反馈
+	FeedbackAt *time.Time `json:"feedback_at,omitempty"`
+}
+
+// TableName 强制指定表名
+func (Roast) TableName() string {
+	return "roasts"
+}
@@ -3,12 +3,18 @@ package model
 import "time"
 
 type User struct {
-	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	Username  string    `gorm:"type:varchar(100);not null;unique" json:"username"`
-	Email     string    `gorm:"type:varchar(255);not null;unique;index" json:"email"`
-	Password  string    `gorm:"type:varchar(255);not null" json:"-"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       string `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Username string `gorm:"type:varchar(100);not null;unique" json:"username"`
+	Email    string `gorm:"type:varchar(255);not null;unique;index" json:"email"`
+	Password string `gorm:"type:varchar(255);not null" json:"-"`
+	TimeZone string `gorm:"type:varchar(64)" json:"timezone"` // IANA 时区名，为空表示使用服务端默认时区
+	// 吐槽人设 (为空表示默认人设) 和力度 0-3，0 表示不吐槽
+	RoastPersona   string `gorm:"type:varchar(32)" json:"roast_persona"`
+	RoastIntensity int    `gorm:"default:2" json:"roast_intensity"`
+	// 管理员指定的 Prompt 模板版本，为空表示使用默认版本
+	PromptVersion string    `gorm:"type:varchar(64)" json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type AuthClaims struct {
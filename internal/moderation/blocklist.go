@@ -0,0 +1,87 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// defaultTerms 内置屏蔽词，按类别；只收明确越界的说法，正常的毒舌不在其列
+// 容易误伤常用词的不收 (如 "废物利用"、"哑巴亏"、"跳楼价"、老妈人设的 "你妈妈")
+var defaultTerms = map[string][]string{
+	CategoryProtected: {
+		"支那", "黑鬼", "印度阿三", "高丽棒子", "小日本", "蛮夷",
+		"娘炮", "死娘们", "基佬", "死gay", "同性恋都",
+		"残废", "瘸子", "瞎子", "聋子", "智障", "弱智", "脑残",
+		"死胖子", "肥猪", "丑八怪",
+		"乡巴佬", "农民工就是", "外地佬", "偷井盖",
+		"穆斯林都", "基督徒都", "信佛的都",
+	},
+	CategorySelfHarm: {
+		"去死", "死了算了", "不如死了", "一死了之", "一了百了", "以死谢罪",
+		"自杀", "轻生", "去跳楼", "跳楼算了", "跳河", "上吊", "割腕", "烧炭", "吃安眠药",
+		"卖肾", "卖血", "活不下去", "没必要活",
+	},
+	CategoryHarassment: {
+		"垃圾人", "人渣", "贱人", "贱货", "滚蛋", "傻逼", "沙比", "狗东西", "你妈的",
+	},
+}
+
+// Blocklist 屏蔽词检查：忽略大小写、空白和标点，避免 "去 死"、"去、死" 这类写法绕过
+type Blocklist struct {
+	terms map[string][]string // 类别 → 归一化后的屏蔽词
+}
+
+// NewBlocklist 内置屏蔽词加上配置里追加的，extra 的键是类别，不认识的类别记为 other
+func NewBlocklist(extra map[string][]string) *Blocklist {
+	b := &Blocklist{terms: make(map[string][]string)}
+	for category, terms := range defaultTerms {
+		b.add(category, terms)
+	}
+	for category, terms := range extra {
+		switch category {
+		case CategoryProtected, CategorySelfHarm, CategoryHarassment:
+		default:
+			category = CategoryOther
+		}
+		b.add(category, terms)
+	}
+	return b
+}
+
+func (b *Blocklist) add(category string, terms []string) {
+	for _, t := range terms {
+		if t = normalize(t); t != "" {
+			b.terms[category] = append(b.terms[category], t)
+		}
+	}
+}
+
+func (b *Blocklist) Name() string {
+	return "blocklist"
+}
+
+func (b *Blocklist) Classify(ctx context.Context, text string) (Verdict, error) {
+	normalized := normalize(text)
+	// 按固定顺序检查，同时命中多个类别时结论稳定
+	for _, category := range []string{CategorySelfHarm, CategoryProtected, CategoryHarassment, CategoryOther} {
+		for _, term := range b.terms[category] {
+			if strings.Contains(normalized, term) {
+				return Verdict{Flagged: true, Category: category, Reason: term}, nil
+			}
+		}
+	}
+	return Verdict{}, nil
+}
+
+// normalize 转小写并去掉空白、标点和符号
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
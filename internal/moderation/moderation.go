@@ -0,0 +1,104 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// 不通过的类别
+const (
+	CategoryProtected  = "protected_attribute" // 针对地域、民族、宗教、性别、性取向、残障、外貌等的侮辱
+	CategorySelfHarm   = "self_harm"           // 自残、轻生，尤其是和欠债、还不起钱放在一起
+	CategoryHarassment = "harassment"          // 辱骂、人身攻击
+	CategoryOther      = "other"               // 自定义屏蔽词或外部分类器的其他类别
+)
+
+// Verdict 一次检查的结果
+type Verdict struct {
+	Flagged    bool
+	Category   string
+	Reason     string // 命中的屏蔽词或分类器给出的说明
+	Classifier string // 给出结论的检查器名字
+}
+
+// Classifier 检查一段生成的文本能不能展示给用户
+// 返回 error 表示没能完成检查 (如外部接口超时)，由 Checker 决定怎么处理
+type Classifier interface {
+	Name() string
+	Classify(ctx context.Context, text string) (Verdict, error)
+}
+
+// Checker 依次运行多个检查器，第一个不通过的结论即为最终结论
+// 检查器出错时跳过它 (屏蔽词总是在链上)，不能因为外部接口不可用就让记账失败
+type Checker struct {
+	classifiers []Classifier
+}
+
+// NewChecker 构造函数，按顺序检查
+func NewChecker(classifiers ...Classifier) *Checker {
+	return &Checker{classifiers: classifiers}
+}
+
+// Check 检查文本，空文本总是通过
+func (c *Checker) Check(ctx context.Context, text string) Verdict {
+	if text == "" {
+		return Verdict{}
+	}
+	for _, cl := range c.classifiers {
+		v, err := cl.Classify(ctx, text)
+		if err != nil {
+			slog.Warn("内容检查失败，跳过该检查器", "classifier", cl.Name(), "error", err)
+			continue
+		}
+		if v.Flagged {
+			if v.Classifier == "" {
+				v.Classifier = cl.Name()
+			}
+			return v
+		}
+	}
+	return Verdict{}
+}
+
+// Spec 外部分类器的配置
+type Spec struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+}
+
+// Factory 根据配置创建分类器
+type Factory func(spec Spec) (Classifier, error)
+
+// factories 只在 init / main 启动阶段注册，运行期只读，不加锁
+var factories = map[string]Factory{
+	"openai": func(spec Spec) (Classifier, error) {
+		return NewOpenAIClassifier(spec.APIKey, spec.BaseURL, spec.Model), nil
+	},
+}
+
+// Register 注册新的分类器类型，同名覆盖
+func Register(typ string, factory Factory) {
+	factories[typ] = factory
+}
+
+// Types 已注册的分类器类型
+func Types() []string {
+	types := make([]string, 0, len(factories))
+	for t := range factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New 按类型创建分类器
+func New(typ string, spec Spec) (Classifier, error) {
+	factory, ok := factories[typ]
+	if !ok {
+		return nil, fmt.Errorf("未知的内容分类器类型 %q，可选: %v", typ, Types())
+	}
+	return factory(spec)
+}
@@ -0,0 +1,56 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAIClassifier 调用 OpenAI 兼容的 /moderations 接口
+type OpenAIClassifier struct {
+	model  string
+	client *openai.Client
+}
+
+// NewOpenAIClassifier baseURL 为空时使用 OpenAI 官方地址，model 为空时使用 omni-moderation-latest
+func NewOpenAIClassifier(apiKey, baseURL, model string) *OpenAIClassifier {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = openai.ModerationOmniLatest
+	}
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseURL
+	return &OpenAIClassifier{model: model, client: openai.NewClientWithConfig(config)}
+}
+
+func (o *OpenAIClassifier) Name() string {
+	return "openai"
+}
+
+func (o *OpenAIClassifier) Classify(ctx context.Context, text string) (Verdict, error) {
+	resp, err := o.client.Moderations(ctx, openai.ModerationRequest{Input: text, Model: o.model})
+	if err != nil {
+		return Verdict{}, err
+	}
+	if len(resp.Results) == 0 {
+		return Verdict{}, fmt.Errorf("moderation 接口没有返回结果")
+	}
+	r := resp.Results[0]
+	if !r.Flagged {
+		return Verdict{}, nil
+	}
+	c := r.Categories
+	switch {
+	case c.SelfHarm || c.SelfHarmIntent || c.SelfHarmInstructions:
+		return Verdict{Flagged: true, Category: CategorySelfHarm, Reason: "self-harm"}, nil
+	case c.Hate || c.HateThreatening:
+		return Verdict{Flagged: true, Category: CategoryProtected, Reason: "hate"}, nil
+	case c.Harassment || c.HarassmentThreatening:
+		return Verdict{Flagged: true, Category: CategoryHarassment, Reason: "harassment"}, nil
+	default:
+		return Verdict{Flagged: true, Category: CategoryOther, Reason: "flagged"}, nil
+	}
+}
@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoTemplate 没有任何模板能识别这条通知
+var ErrNoTemplate = errors.New("无法识别的通知格式")
+
+// Notification 是从银行短信/支付通知中提取出的结构化数据
+type Notification struct {
+	Source   string    `json:"source"`    // 命中的模板名，如 cmb、alipay
+	Amount   float64   `json:"amount"`    // 支出金额 (元)
+	Merchant string    `json:"merchant"`  // 商户/收款方，可能为空
+	CardTail string    `json:"card_tail"` // 卡号尾号，可能为空
+	Time     time.Time `json:"time"`      // 交易时间，零值表示通知里没有
+	Raw      string    `json:"raw"`       // 原始文本
+}
+
+// Template 定义了一种通知格式的解析规则
+// 每家银行/App 一个实现，新增格式时只需 Register 一个新模板
+type Template interface {
+	// Name 模板名，会写入 Notification.Source
+	Name() string
+	// Parse 尝试解析文本，不匹配时返回 false
+	// now 用于补全通知中缺失的年份
+	Parse(text string, now time.Time) (*Notification, bool)
+}
+
+// Parser 按注册顺序依次尝试模板，第一个命中的生效
+type Parser struct {
+	mu        sync.RWMutex
+	templates []Template
+}
+
+// NewParser 构造函数，不传模板时使用内置模板
+func NewParser(templates ...Template) *Parser {
+	if len(templates) == 0 {
+		templates = DefaultTemplates()
+	}
+	return &Parser{templates: templates}
+}
+
+// Register 追加一个模板 (优先级低于已有模板)
+func (p *Parser) Register(t Template) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.templates = append(p.templates, t)
+}
+
+// Parse 解析单条通知
+func (p *Parser) Parse(text string, now time.Time) (*Notification, error) {
+	text = normalize(text)
+	if text == "" {
+		return nil, ErrNoTemplate
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, t := range p.templates {
+		if n, ok := t.Parse(text, now); ok {
+			n.Source = t.Name()
+			n.Raw = text
+			return n, nil
+		}
+	}
+	return nil, ErrNoTemplate
+}
+
+// Split 把用户一次性粘贴的多条通知拆开
+// 规则：先按空行分块；块内能单独识别的行各自是一条，连续几行都不能单独识别时合起来试一次 (如微信支付凭证)，
+// 合起来能识别就算一条多行通知，否则逐行返回，由调用方对每一行报错，不会吞掉同一块里能识别的行
+func (p *Parser) Split(text string, now time.Time) []string {
+	var messages []string
+	for _, block := range splitBlocks(text) {
+		var pending []string // 连续的、不能单独识别的行
+		for _, line := range nonEmptyLines(block) {
+			if !p.parsable(line, now) {
+				pending = append(pending, line)
+				continue
+			}
+			messages = append(messages, p.joinPending(pending, now)...)
+			messages = append(messages, line)
+			pending = nil
+		}
+		messages = append(messages, p.joinPending(pending, now)...)
+	}
+	return messages
+}
+
+// joinPending 几行合起来能识别时作为一条多行通知，否则原样逐行返回
+func (p *Parser) joinPending(lines []string, now time.Time) []string {
+	if len(lines) <= 1 {
+		return lines
+	}
+	if joined := strings.Join(lines, " "); p.parsable(joined, now) {
+		return []string{joined}
+	}
+	return lines
+}
+
+func (p *Parser) parsable(text string, now time.Time) bool {
+	_, err := p.Parse(text, now)
+	return err == nil
+}
+
+func splitBlocks(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var blocks []string
+	var current []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				blocks = append(blocks, strings.Join(current, "\n"))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, strings.Join(current, "\n"))
+	}
+	return blocks
+}
+
+func nonEmptyLines(block string) []string {
+	var lines []string
+	for _, line := range strings.Split(block, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// normalize 统一全角符号，减少模板里需要兼容的写法
+var normalizer = strings.NewReplacer(
+	"，", ",", "：", ":", "（", "(", "）", ")", "￥", "¥",
+	"　", " ", " ", " ",
+)
+
+func normalize(text string) string {
+	return strings.TrimSpace(normalizer.Replace(text))
+}
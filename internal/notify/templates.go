@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 金额片段，兼容千分位
+const amountExpr = `(?P<amount>\d[\d,]*(?:\.\d+)?)`
+
+// RegexTemplate 基于正则命名分组的通用模板
+// 支持的分组：amount (必需)、merchant、tail、time
+type RegexTemplate struct {
+	name     string
+	keywords []string // 快速过滤：文本必须包含其中任意一个，为空表示不过滤
+	pattern  *regexp.Regexp
+}
+
+// NewRegexTemplate 构造函数，pattern 中必须包含 amount 分组
+func NewRegexTemplate(name string, pattern string, keywords ...string) (*RegexTemplate, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("模板 %s 正则编译失败: %w", name, err)
+	}
+	if re.SubexpIndex("amount") < 0 {
+		return nil, fmt.Errorf("模板 %s 缺少 amount 分组", name)
+	}
+	return &RegexTemplate{name: name, keywords: keywords, pattern: re}, nil
+}
+
+// MustRegexTemplate 同 NewRegexTemplate，出错直接 panic，用于内置模板
+func MustRegexTemplate(name string, pattern string, keywords ...string) *RegexTemplate {
+	t, err := NewRegexTemplate(name, pattern, keywords...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t *RegexTemplate) Name() string {
+	return t.name
+}
+
+func (t *RegexTemplate) Parse(text string, now time.Time) (*Notification, bool) {
+	if !t.hasKeyword(text) {
+		return nil, false
+	}
+	match := t.pattern.FindStringSubmatch(text)
+	if match == nil {
+		return nil, false
+	}
+	group := func(name string) string {
+		if i := t.pattern.SubexpIndex(name); i >= 0 {
+			return strings.TrimSpace(match[i])
+		}
+		return ""
+	}
+
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(group("amount"), ",", ""), 64)
+	if err != nil || amount <= 0 {
+		return nil, false
+	}
+	n := &Notification{
+		Amount:   amount,
+		Merchant: strings.Trim(group("merchant"), "【】[]() "),
+		CardTail: group("tail"),
+	}
+	if raw := group("time"); raw != "" {
+		n.Time, _ = parseTime(raw, now)
+	}
+	return n, true
+}
+
+func (t *RegexTemplate) hasKeyword(text string) bool {
+	if len(t.keywords) == 0 {
+		return true
+	}
+	for _, k := range t.keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTemplates 内置模板，越具体的越靠前，通用兜底放最后
+func DefaultTemplates() []Template {
+	return []Template{
+		// 【招商银行】您账户1234于11月05日12:30在【美团】快捷支付35.50元,余额1000.00
+		MustRegexTemplate("cmb",
+			`(?:账户|尾号)(?P<tail>\d{4})\S*?于(?P<time>\d{1,2}月\d{1,2}日\s*\d{1,2}:\d{2})(?:在(?P<merchant>.+?))?(?:快捷支付|消费|支出|扣款)(?:人民币)?`+amountExpr+`元`,
+			"招商银行", "招行"),
+		// 您尾号1234卡11月5日12:30快捷支付(美团)支出35.50元,余额1000.00元。【工商银行】
+		MustRegexTemplate("icbc",
+			`尾号(?P<tail>\d{4})卡(?P<time>\d{1,2}月\d{1,2}日\s*\d{1,2}:\d{2})(?:[^(]*\((?P<merchant>[^)]+)\))?\D*?支出(?:人民币)?`+amountExpr+`元`,
+			"工商银行"),
+		// 您尾号1234的储蓄卡11月5日12时30分消费支出人民币35.50元,活期余额1000.00元。[建设银行]
+		MustRegexTemplate("ccb",
+			`尾号(?P<tail>\d{4})的\S*?卡(?P<time>\d{1,2}月\d{1,2}日\d{1,2}时\d{1,2}分)(?:在(?P<merchant>[^,]+?))?消费支出人民币`+amountExpr+`元`,
+			"建设银行"),
+		// 【支付宝】你在星巴克消费35.50元 / 您在星巴克成功付款35.50元
+		MustRegexTemplate("alipay",
+			`[你您]在(?P<merchant>.+?)(?:成功)?(?:消费|付款|支付)了?`+amountExpr+`元`,
+			"支付宝"),
+		// 微信支付凭证: 付款金额¥35.50 收款方 星巴克 支付时间 2024-11-05 12:30:21
+		MustRegexTemplate("wechat",
+			`(?:付款金额|支付金额|已支付)\s*:?\s*¥?\s*`+amountExpr+`(?:.*?(?:收款方|商户全称|收款商户)\s*:?\s*(?P<merchant>\S+))?(?:.*?(?:支付时间|交易时间)\s*:?\s*(?P<time>\d{4}-\d{1,2}-\d{1,2}\s+\d{1,2}:\d{2}(?::\d{2})?))?`,
+			"微信"),
+		// 支付宝账单详情截图转文字，格式与微信支付凭证类似
+		MustRegexTemplate("alipay_bill",
+			`(?:付款金额|支付金额)\s*:?\s*¥?\s*`+amountExpr+`(?:.*?(?:收款方|商家|对方)\s*:?\s*(?P<merchant>\S+))?(?:.*?(?:创建时间|支付时间)\s*:?\s*(?P<time>\d{4}-\d{1,2}-\d{1,2}\s+\d{1,2}:\d{2}(?::\d{2})?))?`,
+			"支付宝", "账单详情"),
+		// 兜底：其他银行的"尾号XXXX……消费/支出……元"
+		MustRegexTemplate("bank",
+			`尾号(?P<tail>\d{4}).*?(?:消费|支出|支付|扣款)(?:人民币|RMB)?\s*`+amountExpr+`元`,
+			"尾号"),
+	}
+}
+
+var timePattern = regexp.MustCompile(`(?:(\d{4})[年\-/])?(\d{1,2})[月\-/](\d{1,2})日?\s*(\d{1,2})[:时](\d{1,2})分?(?::(\d{1,2}))?`)
+
+// parseTime 解析通知里的时间，没有年份时取 now 所在年份；若推算结果在未来则视为去年 (跨年场景)
+func parseTime(raw string, now time.Time) (time.Time, error) {
+	m := timePattern.FindStringSubmatch(raw)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("无法解析时间: %s", raw)
+	}
+	num := func(s string) int {
+		v, _ := strconv.Atoi(s)
+		return v
+	}
+	year := now.Year()
+	if m[1] != "" {
+		year = num(m[1])
+	}
+	t := time.Date(year, time.Month(num(m[2])), num(m[3]), num(m[4]), num(m[5]), num(m[6]), 0, now.Location())
+	if m[1] == "" && t.After(now.Add(24*time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, nil
+}
@@ -0,0 +1,197 @@
+package offline
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/leon37/FaceTaxLedger/internal/cnnum"
+)
+
+// 数字 token：可选的货币符号 + 阿拉伯数字 (可以带 万/千/百，"1万"、"1.5千") 或中文数字 + 可选的金额单位 + 可选的角 ("35块5"、"三十五块五")
+var numberToken = regexp.MustCompile(`([¥￥]\s*)?(\d+(?:\.\d+)?[万千百]?|[` + cnnum.Chars + `]+)\s*(块钱|块|元|圆|毛钱|毛|角)?([0-9一二两三四五六七八九](?:毛|角)?)?`)
+
+// 阿拉伯数字后面的位数
+var arabicUnits = map[string]float64{"万": 10000, "千": 1000, "百": 100}
+
+// 千克、百米这类计量单位里的 千/百 不是位数
+const metricUnits = "克米瓦卡帕"
+
+// 量词：数字后面跟这些字是数量不是金额
+const measureWords = "个杯份张瓶件碗盒包次位人斤袋本支双条只顿罐听串根箱台部间套把朵颗粒片"
+
+// 数字后面跟这些字是日期、时间或折扣，跳过
+const skipSuffixes = "月日号点时分秒年周天岁折%:：/-.号楼层"
+
+// amountToken 从文本里识别出的一个数字
+type amountToken struct {
+	start, end int
+	value      float64
+	quantity   bool // 数量 (2杯)
+	unitPrice  bool // 单价 (各20 / 每杯20)
+}
+
+// extractAmount 识别文本中的消费金额，多笔金额求和，"2杯各20" 这类单价按数量相乘
+// 返回金额和所有被识别为金额/数量的区间 (用于生成备注)
+func extractAmount(text string) (float64, [][2]int, bool) {
+	hasEach := strings.ContainsAny(text, "各每")
+	var tokens []amountToken
+	for pos := 0; pos < len(text); {
+		m := numberToken.FindStringSubmatchIndex(text[pos:])
+		if m == nil {
+			break
+		}
+		for k := range m {
+			if m[k] >= 0 {
+				m[k] += pos
+			}
+		}
+		if !tailIsDecimal(text, m) {
+			// 尾巴上的数字留给下一轮匹配 ("19.9元3件" 的 3件)
+			m[1], m[8], m[9] = m[8], -1, -1
+		}
+		if tok, ok := classifyToken(text, m, hasEach); ok {
+			tokens = append(tokens, tok)
+		}
+		pos = m[1]
+	}
+
+	total, found := 0.0, false
+	var spans [][2]int
+	for i, tok := range tokens {
+		spans = append(spans, [2]int{tok.start, tok.end})
+		if tok.quantity {
+			continue
+		}
+		value := tok.value
+		if tok.unitPrice {
+			value *= nearestQuantity(tokens, i)
+		}
+		total += value
+		found = true
+	}
+	return math.Round(total*100) / 100, spans, found
+}
+
+// tailIsDecimal 单位后面的数字是不是角 ("35块5")：只有整数金额才有这种说法，
+// 而且后面不能再跟数字或量词 ("19.9元3件"、"20元30"、"10块2个")
+func tailIsDecimal(text string, m []int) bool {
+	if m[8] < 0 {
+		return true
+	}
+	if strings.Contains(text[m[4]:m[5]], ".") {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(strings.TrimLeft(text[m[9]:], " "))
+	return !unicode.IsDigit(next) && !strings.ContainsRune(measureWords, next)
+}
+
+// inName 数字前后紧挨着 "-数字" 的是名字的一部分 ("7-11"、"3-2号柜")，不是金额也不是分隔符
+func inName(text string, m []int) bool {
+	before := text[:m[0]]
+	if rest, ok := strings.CutSuffix(before, "-"); ok {
+		if prev, _ := utf8.DecodeLastRuneInString(rest); unicode.IsDigit(prev) {
+			return true
+		}
+	}
+	if rest, ok := strings.CutPrefix(text[m[1]:], "-"); ok {
+		if next, _ := utf8.DecodeRuneInString(rest); unicode.IsDigit(next) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyToken 判断一个数字 token 是金额、数量还是应当忽略的日期/时间
+func classifyToken(text string, m []int, hasEach bool) (amountToken, bool) {
+	tok := amountToken{start: m[0], end: m[1]}
+	currency := m[2] >= 0
+	raw := text[m[4]:m[5]]
+	unit := ""
+	if m[6] >= 0 {
+		unit = text[m[6]:m[7]]
+	}
+	tail := ""
+	if m[8] >= 0 {
+		tail = text[m[8]:m[9]]
+	}
+	isArabic := raw[0] >= '0' && raw[0] <= '9'
+	if isArabic && inName(text, m) {
+		return tok, false
+	}
+
+	if isArabic {
+		digits, multiplier := raw, 1.0
+		if last, size := utf8.DecodeLastRuneInString(raw); arabicUnits[string(last)] > 0 {
+			if next, _ := utf8.DecodeRuneInString(text[m[5]:]); strings.ContainsRune(metricUnits, next) {
+				return tok, false
+			}
+			digits, multiplier = raw[:len(raw)-size], arabicUnits[string(last)]
+		}
+		v, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			return tok, false
+		}
+		tok.value = v * multiplier
+	} else {
+		n, ok := cnnum.Parse(raw)
+		if !ok {
+			return tok, false
+		}
+		tok.value = float64(n)
+	}
+
+	next, _ := utf8.DecodeRuneInString(strings.TrimLeft(text[m[1]:], " "))
+	switch {
+	case unit == "毛" || unit == "毛钱" || unit == "角":
+		tok.value /= 10
+	case unit != "":
+		if tail != "" {
+			if d := cnnum.Digit([]rune(tail)[0]); d > 0 {
+				tok.value += float64(d) / 10
+			}
+		} else if unit == "块" && hasEach && unicode.Is(unicode.Han, next) && !strings.ContainsRune("钱的买吃给了", next) {
+			// "两块蛋糕各15"：这里的 块 是量词
+			tok.quantity = true
+		}
+	case currency:
+		// ¥35.5
+	case strings.ContainsRune(measureWords, next):
+		tok.quantity = true
+		// 量词一并算进区间，备注里不留 "杯"
+		tok.end = m[1] + strings.Index(text[m[1]:], string(next)) + utf8.RuneLen(next)
+	case strings.ContainsRune(skipSuffixes, next):
+		return tok, false
+	case !isArabic && (unicode.Is(unicode.Han, next) || !strings.ContainsAny(raw, "十拾百佰千仟万")):
+		// 句中的单独中文数字 ("一起"、"一下") 太容易误判；只认句末带位数的 ("火锅一百二")
+		return tok, false
+	}
+
+	// 前面三个字以内出现 各/每 的是单价
+	if !tok.quantity {
+		prefix := []rune(text[:m[0]])
+		if len(prefix) > 3 {
+			prefix = prefix[len(prefix)-3:]
+		}
+		tok.unitPrice = strings.ContainsAny(string(prefix), "各每")
+	}
+	return tok, true
+}
+
+// nearestQuantity 单价对应的数量：优先取它前面最近的数量，其次取后面第一个
+func nearestQuantity(tokens []amountToken, i int) float64 {
+	for j := i - 1; j >= 0; j-- {
+		if tokens[j].quantity {
+			return tokens[j].value
+		}
+	}
+	for j := i + 1; j < len(tokens); j++ {
+		if tokens[j].quantity {
+			return tokens[j].value
+		}
+	}
+	return 1
+}
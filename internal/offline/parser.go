@@ -0,0 +1,116 @@
+package offline
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/calendar"
+	"github.com/leon37/FaceTaxLedger/internal/importer"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+)
+
+// ErrNoAmount 文本里找不到金额，规则解析无能为力
+var ErrNoAmount = errors.New("没有识别到金额")
+
+// fallbackCategory 规则都没命中时的分类
+const fallbackCategory = "其他消费"
+
+// ColloquialRules 口语化的记账描述 ("午饭"、"打车")，排在商户规则前面
+var ColloquialRules = []importer.CategoryRule{
+	{Category: "餐饮美食", Keywords: []string{"早饭", "早餐", "午饭", "午餐", "中饭", "晚饭", "晚餐", "夜宵", "宵夜", "吃饭", "请客", "聚餐", "外卖", "火锅", "烧烤", "小龙虾", "饮料", "水果", "零食", "面包", "蛋糕", "拿铁", "喝"}},
+	{Category: "交通出行", Keywords: []string{"打车", "出租", "网约车", "高铁", "动车", "火车", "飞机", "油费", "过路费", "单车", "车费"}},
+	{Category: "居家生活", Keywords: []string{"房租", "水电", "日用", "纸巾", "洗衣", "买菜", "家具"}},
+	{Category: "服饰美容", Keywords: []string{"衣服", "裤子", "裙子", "鞋", "化妆", "口红", "护肤"}},
+	{Category: "休闲娱乐", Keywords: []string{"电影", "唱歌", "旅游", "门票", "演唱会", "剧本杀", "桌游", "按摩"}},
+	{Category: "数码电器", Keywords: []string{"手机", "电脑", "耳机", "键盘", "鼠标", "充电器", "显示器"}},
+	{Category: "医疗健康", Keywords: []string{"看病", "挂号", "买药", "药", "牙"}},
+	{Category: "人情往来", Keywords: []string{"份子", "随礼", "礼物"}},
+	{Category: "学习教育", Keywords: []string{"买书", "课程", "考试", "报名费"}},
+}
+
+// 从备注里去掉的口头语
+var fillerWords = []string{"一共", "总共", "总计", "合计", "大概", "差不多", "花了", "用了", "付了", "花", "共", "块钱", "块", "元", "各", "每"}
+
+// Result 规则解析结果，字段与 book_expense 工具参数一一对应
+type Result struct {
+	Amount   float64
+	Category string
+	Date     time.Time
+	Note     string
+}
+
+// Parser 不依赖任何模型的确定性解析器，AI 不可用时兜底
+type Parser struct {
+	mapper *importer.CategoryMapper
+}
+
+// NewParser 构造函数，不传规则时使用 口语规则 + 导入用的商户规则
+func NewParser(rules ...importer.CategoryRule) *Parser {
+	if len(rules) == 0 {
+		rules = append(slices.Clone(ColloquialRules), importer.DefaultCategoryRules...)
+	}
+	return &Parser{mapper: importer.NewCategoryMapper(rules...)}
+}
+
+// Parse 解析一句记账描述，categories 为可选分类，命中的分类不在其中时退回兜底分类
+func (p *Parser) Parse(text string, now time.Time, categories []string) (*Result, error) {
+	text = strings.TrimSpace(text)
+
+	// 先摘掉日期，否则 "3月5日" 里的数字会被当成金额
+	date, hasDate := calendar.Resolve(text, now)
+	rest := text
+	if hasDate {
+		rest = text[:date.Start] + " " + text[date.End:]
+	}
+
+	amount, spans, ok := extractAmount(rest)
+	if !ok || amount <= 0 {
+		return nil, ErrNoAmount
+	}
+
+	category, ok := p.mapper.Map("", rest, "")
+	if !ok || (len(categories) > 0 && !slices.Contains(categories, category)) {
+		category = fallbackCategory
+		if len(categories) > 0 && !slices.Contains(categories, category) {
+			category = categories[len(categories)-1]
+		}
+	}
+
+	return &Result{
+		Amount:   amount,
+		Category: category,
+		Date:     date.Date,
+		Note:     buildNote(rest, spans, text),
+	}, nil
+}
+
+// buildNote 去掉金额、数量和口头语后剩下的就是消费内容
+func buildNote(text string, spans [][2]int, original string) string {
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] > spans[j][0] })
+	for _, s := range spans {
+		text = text[:s[0]] + " " + text[s[1]:]
+	}
+	for _, w := range fillerWords {
+		text = strings.ReplaceAll(text, w, " ")
+	}
+	text = strings.Join(strings.Fields(text), "")
+	text = strings.Trim(text, "，。,.!！?？;；:：、~ ¥￥了的")
+	if text == "" {
+		return original
+	}
+	return text
+}
+
+// Analysis 转成与模型输出一致的结构，离线模式没有吐槽
+func (r *Result) Analysis() model.FaceTaxAnalysis {
+	return model.FaceTaxAnalysis{
+		Amount:   r.Amount,
+		Date:     r.Date.Format("2006-01-02"),
+		Note:     r.Note,
+		Comment:  "",
+		Category: r.Category,
+	}
+}
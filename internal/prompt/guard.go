@@ -0,0 +1,82 @@
+package prompt
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 包住不可信文本的标签，模板里说明标签里的内容只是数据
+const (
+	TagUserInput = "user_input" // 用户的记账描述，作为 user 消息发送
+	TagHistory   = "history"    // 检索到的历史消费，在模板里包住 .History
+)
+
+// Escape 清洗一段要放进 Prompt 的不可信文本 (用户描述、历史记忆、之前的吐槽)
+// 去掉控制字符和零宽字符，换行折成空格，尖括号换成全角，文本里伪造不出 </user_input> 这类标签
+func Escape(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			r = '＜'
+		case r == '>':
+			r = '＞'
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			if !space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		case unicode.Is(unicode.Cf, r): // 零宽字符、方向控制符
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Fence 清洗后用标签包住一段不可信文本
+func Fence(tag string, s string) string {
+	return "<" + tag + ">\n" + Escape(s) + "\n</" + tag + ">"
+}
+
+// injectionPatterns 提示词注入的常见说法，按名字报告命中了哪一种
+// 只做启发式的标记：命中后不写入向量记忆、不放进后续的 Prompt，本次识别照常进行，输出另有校验兜底
+var injectionPatterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"ignore_instructions", regexp.MustCompile(`(忽略|无视|忘记|忘掉|不要理会|不用管|跳过)掉?(以上|上面|上述|之前|前面|此前|先前|所有|全部|你的|系统)的?(所有|全部|一切)?的?(指令|指示|规则|提示|设定|要求|约束|说明)`)},
+	{"ignore_instructions", regexp.MustCompile(`(ignore|disregard|forget|override)(all|any)?(the|your|of)*(previous|prior|above|earlier|preceding|system)?(instructions?|rules|prompts?|directions)`)},
+	{"role_override", regexp.MustCompile(`(你现在是|你现在扮演|从现在(开始|起)你|假装你是|你的新身份|进入(开发者|管理员|调试|无限制|越狱)模式|解除(所有|全部|你的)?限制)`)},
+	{"role_override", regexp.MustCompile(`(youarenow|pretendtobe|developermode|jailbreak|doanythingnow)`)},
+	{"prompt_leak", regexp.MustCompile(`(输出|打印|告诉我|重复|显示|泄露)(一下)?你?的?(系统)?(提示词|prompt|指令|设定)`)},
+	{"prompt_leak", regexp.MustCompile(`(systemprompt|revealyour(instructions|prompt))`)},
+	{"fake_tag", regexp.MustCompile(`(</?(system|assistant|user_input|history|instructions?)>|<\|im_(start|end)\|>|\[/?inst\]|(role|角色)[:：](system|assistant|系统))`)},
+	// 改字段要有命令的口气："把金额一律改成"、"分类统一记为"、"忽略…金额改成"；"午饭30 分类记为餐饮" 是正常的记账描述
+	{"field_override", regexp.MustCompile(`(把|将)(所有|全部)?的?(金额|分类|类别|amount|category)(一律|统一|都|全部)(改成|改为|设为|设置为|写成|填成|记为)`)},
+	{"field_override", regexp.MustCompile(`(金额|分类|类别|amount|category)(一律|统一)(改成|改为|设为|设置为|写成|填成|记为)`)},
+	{"field_override", regexp.MustCompile(`(忽略|无视|不要管|不用管)[^。；;]{0,20}(金额|分类|类别|amount|category)(一律|统一|都|全部)?(改成|改为|设为|设置为|写成|填成|记为)`)},
+}
+
+// DetectInjection 检查一段不可信文本是否像提示词注入，返回命中的特征名，没有命中返回空
+// 匹配前转小写并去掉空白和零宽字符，避免 "忽 略 以上" 这类写法绕过
+func DetectInjection(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsSpace(r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	normalized := b.String()
+	for _, p := range injectionPatterns {
+		if p.re.MatchString(normalized) {
+			return p.name
+		}
+	}
+	return ""
+}
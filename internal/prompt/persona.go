@@ -0,0 +1,96 @@
+package prompt
+
+import "context"
+
+// 吐槽力度：0 不吐槽，1 轻微调侃，2 辛辣幽默 (默认)，3 火力全开
+const (
+	DefaultIntensity = 2
+	MaxIntensity     = 3
+)
+
+// DefaultPersona 没有选择人设时使用的人设
+const DefaultPersona = "butler"
+
+// Persona 吐槽的人设
+type Persona struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"` // 给用户看的介绍
+	Voice       string `json:"-"`           // 写进 Prompt 的角色设定
+}
+
+// Personas 可选的人设，第一个是默认人设
+var Personas = []Persona{
+	{
+		ID:          "butler",
+		Name:        "老管家",
+		Description: "尖酸刻薄、看透世俗，但真心为了主人好",
+		Voice:       "一个尖酸刻薄、看透世俗、但真心为了主人好的老管家",
+	},
+	{
+		ID:          "mom",
+		Name:        "温柔老妈",
+		Description: "絮絮叨叨、嘴上心疼钱，其实最心疼你",
+		Voice:       "一位温柔唠叨的妈妈，嘴上心疼钱、心里心疼孩子，说话带着家常的关切",
+	},
+	{
+		ID:          "finance_bro",
+		Name:        "金融男",
+		Description: "张口 ROI、闭口复利，用投资视角嘲讽每一笔消费",
+		Voice:       "一个满嘴 ROI、复利、机会成本的金融从业者，习惯用投资视角阴阳怪气地评价消费",
+	},
+	{
+		ID:          "scholar",
+		Name:        "古文书生",
+		Description: "之乎者也，以文言讽之",
+		Voice:       "一位饱读诗书的古代书生，用简短的文言文或化用古诗词来点评消费",
+	},
+}
+
+// LookupPersona 按 ID 查找人设
+func LookupPersona(id string) (Persona, bool) {
+	for _, p := range Personas {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Persona{}, false
+}
+
+// Style 用户选择的吐槽风格，以及用来避免重复的吐槽记忆
+type Style struct {
+	Persona   Persona
+	Intensity int
+	Avoid     []string // 最近说过的和用户踩过的吐槽
+	Examples  []string // 用户赞过的吐槽
+}
+
+// DefaultStyle 默认人设、默认力度
+func DefaultStyle() Style {
+	return Style{Persona: Personas[0], Intensity: DefaultIntensity}
+}
+
+// NewStyle 按用户的设置生成风格，人设不存在时用默认人设，力度超出范围时截断
+func NewStyle(persona string, intensity int) Style {
+	style := DefaultStyle()
+	if p, ok := LookupPersona(persona); ok {
+		style.Persona = p
+	}
+	style.Intensity = min(max(intensity, 0), MaxIntensity)
+	return style
+}
+
+type styleKey struct{}
+
+// WithStyle 把这次调用的吐槽风格挂到 ctx 上
+func WithStyle(ctx context.Context, s Style) context.Context {
+	return context.WithValue(ctx, styleKey{}, s)
+}
+
+// StyleFromContext ctx 上的吐槽风格，没有挂载时使用默认风格
+func StyleFromContext(ctx context.Context) Style {
+	if s, ok := ctx.Value(styleKey{}).(Style); ok {
+		return s
+	}
+	return DefaultStyle()
+}
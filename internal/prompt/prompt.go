@@ -0,0 +1,102 @@
+package prompt
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// BuiltinVersion 代码内置模板的版本号，修改 builtin.tmpl 时递增
+const BuiltinVersion = "builtin-4"
+
+//go:embed builtin.tmpl
+var builtinSource string
+
+// 模板里必须定义的名字
+const (
+	System          = "system"           // 工具调用模式的 system prompt
+	JSONSystem      = "json_system"      // JSON 模式的 system prompt，没有工具定义约束时需要自己描述输出格式
+	Receipt         = "receipt"          // 图片记账的 system prompt
+	ToolDescription = "tool_description" // book_expense 工具的描述
+	FieldAmount     = "field_amount"     // 以下为 book_expense 各个参数的描述
+	FieldCategory   = "field_category"
+	FieldDate       = "field_date"
+	FieldNote       = "field_note"
+	FieldComment    = "field_comment"
+)
+
+var requiredNames = []string{System, JSONSystem, Receipt, ToolDescription, FieldAmount, FieldCategory, FieldDate, FieldNote, FieldComment}
+
+var funcs = template.FuncMap{"join": strings.Join}
+
+// Data 渲染模板时可用的字段
+type Data struct {
+	Now        string   // 用户所在时区的当前时间，带时区名
+	Categories []string // 可选分类
+	History    []string // 检索到的历史消费，已经格式化成一行一条
+	Roast      bool     // 是否开启吐槽
+	Persona    Persona  // 吐槽人设
+	Intensity  int      // 吐槽力度 1-3
+	Avoid      []string // 不要重复的吐槽：最近说过的和用户踩过的
+	Examples   []string // 用户赞过的吐槽，作为风格示例
+}
+
+// Template 一个版本的 Prompt 模板
+type Template struct {
+	Version string
+	Source  string // 模板原文，管理接口展示用
+	tmpl    *template.Template
+}
+
+// Parse 解析一个版本的模板，缺少必须的定义时报错
+func Parse(version string, source string) (*Template, error) {
+	tmpl, err := template.New(version).Funcs(funcs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range requiredNames {
+		if tmpl.Lookup(name) == nil {
+			return nil, fmt.Errorf("模板 %s 缺少 %q 的定义", version, name)
+		}
+	}
+	return &Template{Version: version, Source: source, tmpl: tmpl}, nil
+}
+
+// Render 渲染其中一个命名模板
+func (t *Template) Render(name string, data Data) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.ExecuteTemplate(&b, name, data); err != nil {
+		return "", fmt.Errorf("渲染 Prompt %s/%s 失败: %w", t.Version, name, err)
+	}
+	return b.String(), nil
+}
+
+// Builtin 代码内置的模板
+func Builtin() *Template {
+	return builtin
+}
+
+var builtin = func() *Template {
+	t, err := Parse(BuiltinVersion, builtinSource)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}()
+
+type templateKey struct{}
+
+// WithTemplate 把这次调用使用的模板挂到 ctx 上，模型后端按它生成 Prompt
+func WithTemplate(ctx context.Context, t *Template) context.Context {
+	return context.WithValue(ctx, templateKey{}, t)
+}
+
+// FromContext ctx 上的模板，没有挂载时使用内置模板
+func FromContext(ctx context.Context) *Template {
+	if t, ok := ctx.Value(templateKey{}).(*Template); ok && t != nil {
+		return t
+	}
+	return builtin
+}
@@ -0,0 +1,170 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval 检查模板文件变化的默认间隔
+const DefaultReloadInterval = 10 * time.Second
+
+// Store 从目录加载多个版本的模板，定时检查文件变化并热更新
+// 目录里每个 .tmpl 文件是一个版本，文件名 (不含扩展名) 即版本号；内置版本总是可用
+type Store struct {
+	dir            string
+	defaultVersion string
+
+	mu        sync.RWMutex
+	templates map[string]*Template
+	signature string // 上次加载时目录内容的签名 (文件名 + 大小 + 修改时间)，没变就不重新解析
+}
+
+// NewStore 创建并立即加载一次；dir 为空时只有内置版本，defaultVersion 为空时默认用内置版本
+func NewStore(dir string, defaultVersion string) *Store {
+	if defaultVersion == "" {
+		defaultVersion = BuiltinVersion
+	}
+	s := &Store{
+		dir:            dir,
+		defaultVersion: defaultVersion,
+		templates:      map[string]*Template{BuiltinVersion: builtin},
+	}
+	s.Reload()
+	if _, ok := s.Get(defaultVersion); !ok {
+		slog.Warn("默认 Prompt 版本不存在，使用内置版本", "version", defaultVersion, "dir", dir)
+	}
+	return s
+}
+
+// Watch 每隔 interval 检查一次目录，有变化时重新加载，直到 ctx 取消
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if s.dir == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Reload()
+		}
+	}
+}
+
+// Reload 重新扫描目录；解析失败的文件保留上一次加载成功的版本，不影响其他版本
+func (s *Store) Reload() {
+	if s.dir == "" {
+		return
+	}
+	files, signature, err := s.scan()
+	if err != nil {
+		slog.Error("读取 Prompt 模板目录失败", "dir", s.dir, "error", err)
+		return
+	}
+	s.mu.RLock()
+	unchanged := signature == s.signature
+	previous := s.templates
+	s.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	templates := map[string]*Template{BuiltinVersion: builtin}
+	for version, path := range files {
+		t, err := load(version, path)
+		if err != nil {
+			slog.Error("Prompt 模板解析失败，沿用上一次的版本", "version", version, "error", err)
+			if old, ok := previous[version]; ok {
+				templates[version] = old
+			}
+			continue
+		}
+		templates[version] = t
+	}
+
+	s.mu.Lock()
+	s.templates = templates
+	s.signature = signature
+	s.mu.Unlock()
+	slog.Info("Prompt 模板已加载", "dir", s.dir, "versions", s.Versions())
+}
+
+// scan 列出目录里的模板文件，返回 版本号 → 路径 和目录内容的签名
+func (s *Store) scan() (map[string]string, string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, "", err
+	}
+	files := make(map[string]string)
+	var signature strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tmpl" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, "", err
+		}
+		version := strings.TrimSuffix(entry.Name(), ".tmpl")
+		if version == BuiltinVersion {
+			slog.Warn("Prompt 模板与内置版本重名，已忽略", "file", entry.Name())
+			continue
+		}
+		files[version] = filepath.Join(s.dir, entry.Name())
+		fmt.Fprintf(&signature, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return files, signature.String(), nil
+}
+
+func load(version string, path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(version, string(data))
+}
+
+// Get 按版本号取模板
+func (s *Store) Get(version string) (*Template, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.templates[version]
+	return t, ok
+}
+
+// Default 默认版本的模板，配置的默认版本不存在时用内置版本
+func (s *Store) Default() *Template {
+	if t, ok := s.Get(s.defaultVersion); ok {
+		return t
+	}
+	return builtin
+}
+
+// DefaultVersion 配置的默认版本号
+func (s *Store) DefaultVersion() string {
+	return s.defaultVersion
+}
+
+// Versions 当前可用的版本号，按名字排序
+func (s *Store) Versions() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := make([]string, 0, len(s.templates))
+	for v := range s.templates {
+		versions = append(versions, v)
+	}
+	slices.Sort(versions)
+	return versions
+}
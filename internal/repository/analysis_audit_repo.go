@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"gorm.io/gorm"
+)
+
+// AuditFilter 审计记录的筛选条件，字段为空时不筛选
+type AuditFilter struct {
+	UserID    string
+	ExpenseID uint
+	Source    string
+	Injected  bool // 只看描述疑似提示词注入的记录
+	Page      int
+	PageSize  int
+}
+
+// AnalysisAuditRepo 模型识别审计记录的持久化
+type AnalysisAuditRepo interface {
+	Create(ctx context.Context, audit *model.AnalysisAudit) error
+	Get(ctx context.Context, id uint) (*model.AnalysisAudit, error)
+	List(ctx context.Context, filter AuditFilter) ([]model.AnalysisAudit, int64, error)
+	// LinkExpenses 把预览时写入的记录关联到确认后的账单，键为记录 ID，值为账单 ID
+	// 只关联属于该用户且还没关联过的记录，其余的忽略
+	LinkExpenses(ctx context.Context, userID string, links map[uint]uint) error
+	// MarkCorrected 用户修改了账单，记在这笔账单第一次记账时的识别记录上；已经记过的字段会合并
+	MarkCorrected(ctx context.Context, expenseID uint, fields []string, at time.Time) error
+	// MarkAuditCorrected 用户改过重新分析给出的建议，记在那次重新分析的识别记录上
+	MarkAuditCorrected(ctx context.Context, auditID uint, fields []string, at time.Time) error
+	// VariantStats 按分组汇总某个实验的识别记录，只统计第一次记账时的识别 (文字记账、批量记账、支付通知)
+	VariantStats(ctx context.Context, experiment string) ([]VariantStats, error)
+}
+
+// VariantStats 实验里一个分组的汇总
+type VariantStats struct {
+	Variant             string  `json:"variant"`
+	Calls               int64   `json:"calls"`          // 模型调用次数
+	Expenses            int64   `json:"expenses"`       // 其中落库成账单的
+	Corrected           int64   `json:"corrected"`      // 其中被用户修改过的
+	RoastLiked          int64   `json:"roast_liked"`    // 其中吐槽被赞的
+	RoastDisliked       int64   `json:"roast_disliked"` // 其中吐槽被踩的
+	AvgLatencyMS        float64 `json:"avg_latency_ms"`
+	AvgPromptTokens     float64 `json:"avg_prompt_tokens"`
+	AvgCompletionTokens float64 `json:"avg_completion_tokens"`
+}
+
+type analysisAuditRepo struct {
+	db *gorm.DB
+}
+
+// NewAnalysisAuditRepo 构造函数
+func NewAnalysisAuditRepo(db *gorm.DB) AnalysisAuditRepo {
+	return &analysisAuditRepo{db: db}
+}
+
+func (r *analysisAuditRepo) Create(ctx context.Context, audit *model.AnalysisAudit) error {
+	return r.db.WithContext(ctx).Create(audit).Error
+}
+
+func (r *analysisAuditRepo) Get(ctx context.Context, id uint) (*model.AnalysisAudit, error) {
+	var audit model.AnalysisAudit
+	err := r.db.WithContext(ctx).First(&audit, id).Error
+	return &audit, err
+}
+
+func (r *analysisAuditRepo) List(ctx context.Context, filter AuditFilter) ([]model.AnalysisAudit, int64, error) {
+	db := r.db.WithContext(ctx).Model(&model.AnalysisAudit{})
+	if filter.UserID != "" {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ExpenseID != 0 {
+		db = db.Where("expense_id = ?", filter.ExpenseID)
+	}
+	if filter.Source != "" {
+		db = db.Where("source = ?", filter.Source)
+	}
+	if filter.Injected {
+		db = db.Where("injection <> ''")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var audits []model.AnalysisAudit
+	err := db.Order("id DESC").
+		Limit(filter.PageSize).
+		Offset((filter.Page - 1) * filter.PageSize).
+		Find(&audits).Error
+	return audits, total, err
+}
+
+func (r *analysisAuditRepo) LinkExpenses(ctx context.Context, userID string, links map[uint]uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for auditID, expenseID := range links {
+			err := tx.Model(&model.AnalysisAudit{}).
+				Where("id = ? AND user_id = ? AND expense_id = 0", auditID, userID).
+				Update("expense_id", expenseID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *analysisAuditRepo) MarkCorrected(ctx context.Context, expenseID uint, fields []string, at time.Time) error {
+	// 第一次记账的识别记录 ID 最小；之后应用的重新分析也会关联到这笔账单，但它的修改单独记
+	return r.markCorrected(ctx, fields, at, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("expense_id = ? AND source IN ?", expenseID, bookingSources).Order("id ASC")
+	})
+}
+
+func (r *analysisAuditRepo) MarkAuditCorrected(ctx context.Context, auditID uint, fields []string, at time.Time) error {
+	return r.markCorrected(ctx, fields, at, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("id = ?", auditID)
+	})
+}
+
+// markCorrected 把修改过的字段合并到 scope 选出的第一条记录上
+func (r *analysisAuditRepo) markCorrected(ctx context.Context, fields []string, at time.Time, scope func(tx *gorm.DB) *gorm.DB) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var audit model.AnalysisAudit
+		err := scope(tx).First(&audit).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 手动记的账、或者审计功能上线前的账单，没有识别记录
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		merged := audit.CorrectedFields
+		for _, f := range fields {
+			if !slices.Contains(merged, f) {
+				merged = append(merged, f)
+			}
+		}
+		audit.CorrectedFields = merged
+		if audit.CorrectedAt == nil {
+			audit.CorrectedAt = &at
+		}
+		return tx.Model(&audit).Select("corrected_fields", "corrected_at").Updates(&audit).Error
+	})
+}
+
+// bookingSources 第一次记账时的识别来源，实验统计和账单修改率只看这些记录
+// 重新分析的预览确认后也会关联到同一笔账单，吐槽重新生成的调用不产生账单，算进来会重复计数
+var bookingSources = []string{model.AuditSourceAnalyze, model.AuditSourceReceipt, model.AuditSourceBulk, model.AuditSourceNotification}
+
+func (r *analysisAuditRepo) VariantStats(ctx context.Context, experiment string) ([]VariantStats, error) {
+	var stats []VariantStats
+	// 一笔账单最多一条吐槽，LEFT JOIN 不会让识别记录重复计数
+	err := r.db.WithContext(ctx).Model(&model.AnalysisAudit{}).
+		Select("analysis_audits.variant AS variant, COUNT(*) AS calls, "+
+			"SUM(CASE WHEN analysis_audits.expense_id <> 0 THEN 1 ELSE 0 END) AS expenses, "+
+			"SUM(CASE WHEN analysis_audits.expense_id <> 0 AND analysis_audits.corrected_at IS NOT NULL THEN 1 ELSE 0 END) AS corrected, "+
+			"SUM(CASE WHEN roasts.feedback = 1 THEN 1 ELSE 0 END) AS roast_liked, "+
+			"SUM(CASE WHEN roasts.feedback = -1 THEN 1 ELSE 0 END) AS roast_disliked, "+
+			"AVG(analysis_audits.latency_ms) AS avg_latency_ms, "+
+			"AVG(analysis_audits.prompt_tokens) AS avg_prompt_tokens, "+
+			"AVG(analysis_audits.completion_tokens) AS avg_completion_tokens").
+		Joins("LEFT JOIN roasts ON roasts.expense_id = analysis_audits.expense_id AND analysis_audits.expense_id <> 0").
+		Where("analysis_audits.experiment = ? AND analysis_audits.source IN ?", experiment, bookingSources).
+		Group("analysis_audits.variant").
+		Order("analysis_audits.variant").
+		Scan(&stats).Error
+	return stats, err
+}
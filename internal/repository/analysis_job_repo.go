@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"gorm.io/gorm"
+)
+
+// ErrNoJobDue 没有到期可以领取的任务
+var ErrNoJobDue = errors.New("没有待处理的任务")
+
+// 任务进度相关的列，更新时只写这些，不会覆盖提交时的请求内容
+var analysisJobProgressColumns = []string{"status", "next_run_at", "attempts", "expense_id", "result", "error_code", "error", "callback_status", "callback_attempts", "finished_at"}
+
+// AnalysisJobRepo 异步记账任务的持久化
+type AnalysisJobRepo interface {
+	Create(ctx context.Context, job *model.AnalysisJob) error
+	Get(ctx context.Context, id uint) (*model.AnalysisJob, error)
+	// ClaimNext 领取一个到期的排队任务：状态改为 running 并且 Attempts + 1
+	// 多个 worker 并发领取时同一个任务只会被一个拿到；没有任务时返回 ErrNoJobDue
+	ClaimNext(ctx context.Context, now time.Time) (*model.AnalysisJob, error)
+	UpdateProgress(ctx context.Context, job *model.AnalysisJob) error
+	// RequeueRunning 服务启动时调用，上次重启前正在处理的任务放回队列
+	RequeueRunning(ctx context.Context) (int64, error)
+	// ListUndelivered 已经结束但回调还没投递成功的任务
+	ListUndelivered(ctx context.Context) ([]model.AnalysisJob, error)
+}
+
+type analysisJobRepo struct {
+	db *gorm.DB
+}
+
+// NewAnalysisJobRepo 构造函数
+func NewAnalysisJobRepo(db *gorm.DB) AnalysisJobRepo {
+	return &analysisJobRepo{db: db}
+}
+
+func (r *analysisJobRepo) Create(ctx context.Context, job *model.AnalysisJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *analysisJobRepo) Get(ctx context.Context, id uint) (*model.AnalysisJob, error) {
+	var job model.AnalysisJob
+	err := r.db.WithContext(ctx).First(&job, id).Error
+	return &job, err
+}
+
+func (r *analysisJobRepo) ClaimNext(ctx context.Context, now time.Time) (*model.AnalysisJob, error) {
+	// 先挑候选再用带状态条件的 UPDATE 抢占，抢输了 (RowsAffected 为 0) 换下一个
+	for {
+		var job model.AnalysisJob
+		err := r.db.WithContext(ctx).
+			Where("status = ? AND next_run_at <= ?", model.AnalysisJobPending, now).
+			Order("next_run_at ASC").
+			First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoJobDue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		res := r.db.WithContext(ctx).Model(&model.AnalysisJob{}).
+			Where("id = ? AND status = ?", job.ID, model.AnalysisJobPending).
+			Updates(map[string]interface{}{
+				"status":   model.AnalysisJobRunning,
+				"attempts": gorm.Expr("attempts + 1"),
+			})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			job.Status = model.AnalysisJobRunning
+			job.Attempts++
+			return &job, nil
+		}
+	}
+}
+
+func (r *analysisJobRepo) UpdateProgress(ctx context.Context, job *model.AnalysisJob) error {
+	return r.db.WithContext(ctx).Model(job).Select(analysisJobProgressColumns).Updates(job).Error
+}
+
+func (r *analysisJobRepo) RequeueRunning(ctx context.Context) (int64, error) {
+	res := r.db.WithContext(ctx).Model(&model.AnalysisJob{}).
+		Where("status = ?", model.AnalysisJobRunning).
+		Update("status", model.AnalysisJobPending)
+	return res.RowsAffected, res.Error
+}
+
+func (r *analysisJobRepo) ListUndelivered(ctx context.Context) ([]model.AnalysisJob, error) {
+	var jobs []model.AnalysisJob
+	err := r.db.WithContext(ctx).
+		Where("callback_status = ? AND status IN ?", model.CallbackPending, []string{model.AnalysisJobSucceeded, model.AnalysisJobFailed}).
+		Order("finished_at ASC").
+		Find(&jobs).Error
+	return jobs, err
+}
@@ -11,9 +11,21 @@ import (
 // ExpenseRepo 定义接口 (为了以后方便 Mock)
 type ExpenseRepo interface {
 	Create(ctx context.Context, expense *model.ExpenseEntity) error
+	CreateBatch(ctx context.Context, expenses []*model.ExpenseEntity) error
+	ExistingExternalIDs(ctx context.Context, userID string, source string, externalIDs []string) (map[string]bool, error)
 	List(ctx context.Context, filter ExpenseFilter) ([]model.ExpenseEntity, int64, error)
+	// Each 按时间正序分批遍历所有符合条件的账单 (忽略分页参数)，用于导出，不会一次性读进内存
+	Each(ctx context.Context, filter ExpenseFilter, fn func(batch []model.ExpenseEntity) error) error
+	// CategoryUsages 按 分类 + 来源 汇总第一次出现的时间
+	CategoryUsages(ctx context.Context, filter ExpenseFilter) ([]CategoryUsage, error)
 	GetByID(ctx context.Context, id int64) (*model.ExpenseEntity, error)
+	// GetByAnalysisJob 查出异步记账任务生成的账单 (包括已删除的)，没有时返回 gorm.ErrRecordNotFound
+	GetByAnalysisJob(ctx context.Context, jobID uint) (*model.ExpenseEntity, error)
+	// ListByIDs 按 ID 查出属于该用户的账单，不存在或不属于该用户的 ID 直接忽略
+	ListByIDs(ctx context.Context, userID string, ids []uint) ([]model.ExpenseEntity, error)
 	Update(ctx context.Context, expense *model.ExpenseEntity) error
+	// UpdateBatch 在一个事务里保存多条账单，全部成功或全部失败
+	UpdateBatch(ctx context.Context, expenses []*model.ExpenseEntity) error
 	Delete(ctx context.Context, id int64) error
 }
 
@@ -33,14 +45,60 @@ func (r *expenseRepo) Create(ctx context.Context, expense *model.ExpenseEntity)
 	return r.db.WithContext(ctx).Create(expense).Error
 }
 
+// CreateBatch 批量插入，GORM 会把所有批次包在同一个事务里
+func (r *expenseRepo) CreateBatch(ctx context.Context, expenses []*model.ExpenseEntity) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(expenses, 200).Error
+}
+
+// ExistingExternalIDs 查出已经导入过的外部单号，用于去重
+func (r *expenseRepo) ExistingExternalIDs(ctx context.Context, userID string, source string, externalIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	// IN 条件过长会拖慢 MySQL，分批查
+	const chunk = 500
+	for start := 0; start < len(externalIDs); start += chunk {
+		end := min(start+chunk, len(externalIDs))
+		var ids []string
+		err := r.db.WithContext(ctx).Model(&model.ExpenseEntity{}).
+			Where("user_id = ? AND source = ? AND external_id IN ?", userID, source, externalIDs[start:end]).
+			Pluck("external_id", &ids).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			existing[id] = true
+		}
+	}
+	return existing, nil
+}
+
 func (r *expenseRepo) List(ctx context.Context, filter ExpenseFilter) ([]model.ExpenseEntity, int64, error) {
 	var expenses []model.ExpenseEntity
 	var total int64
 
-	// 1. 构建基础查询 (带上 Context 和 UserID)
-	db := r.db.WithContext(ctx).Model(&model.ExpenseEntity{}).Where("user_id = ?", filter.UserID)
+	// 1. 构建基础查询 (带上 Context 和 UserID) 并动态追加条件
+	db := r.filtered(ctx, filter)
+
+	// 2. 计算总数 (在分页之前)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 
-	// 2. 动态追加条件
+	// 3. 分页与排序 (按时间倒序)
+	offset := (filter.Page - 1) * filter.PageSize
+	err := db.Order("created_at DESC").
+		Limit(filter.PageSize).
+		Offset(offset).
+		Find(&expenses).Error
+
+	return expenses, total, err
+}
+
+// filtered 构建带筛选条件的基础查询
+func (r *expenseRepo) filtered(ctx context.Context, filter ExpenseFilter) *gorm.DB {
+	db := r.db.WithContext(ctx).Model(&model.ExpenseEntity{}).Where("user_id = ?", filter.UserID)
 	if filter.Category != "" {
 		db = db.Where("category = ?", filter.Category)
 	}
@@ -50,20 +108,54 @@ func (r *expenseRepo) List(ctx context.Context, filter ExpenseFilter) ([]model.E
 	if !filter.EndDate.IsZero() {
 		db = db.Where("created_at <= ?", filter.EndDate)
 	}
+	return db
+}
 
-	// 3. 计算总数 (在分页之前)
-	if err := db.Count(&total).Error; err != nil {
-		return nil, 0, err
+// 导出时每批读取的行数
+const eachBatchSize = 500
+
+func (r *expenseRepo) Each(ctx context.Context, filter ExpenseFilter, fn func(batch []model.ExpenseEntity) error) error {
+	// 用 (created_at, id) 做游标翻页，OFFSET 翻到几万行以后会越来越慢
+	var lastAt time.Time
+	var lastID uint
+	for {
+		db := r.filtered(ctx, filter)
+		if lastID != 0 {
+			db = db.Where("created_at > ? OR (created_at = ? AND id > ?)", lastAt, lastAt, lastID)
+		}
+		var batch []model.ExpenseEntity
+		if err := db.Order("created_at ASC, id ASC").Limit(eachBatchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < eachBatchSize {
+			return nil
+		}
+		last := batch[len(batch)-1]
+		lastAt, lastID = last.CreatedAt, last.ID
 	}
+}
 
-	// 4. 分页与排序 (按时间倒序)
-	offset := (filter.Page - 1) * filter.PageSize
-	err := db.Order("created_at DESC").
-		Limit(filter.PageSize).
-		Offset(offset).
-		Find(&expenses).Error
+// CategoryUsage 某个 分类 + 来源 组合第一次出现的时间
+type CategoryUsage struct {
+	Category string
+	Source   string
+	FirstAt  time.Time
+}
 
-	return expenses, total, err
+func (r *expenseRepo) CategoryUsages(ctx context.Context, filter ExpenseFilter) ([]CategoryUsage, error) {
+	var usages []CategoryUsage
+	err := r.filtered(ctx, filter).
+		Select("category, source, MIN(created_at) AS first_at").
+		Group("category, source").
+		Order("first_at ASC").
+		Scan(&usages).Error
+	return usages, err
 }
 
 func (r *expenseRepo) GetByID(ctx context.Context, id int64) (*model.ExpenseEntity, error) {
@@ -72,10 +164,36 @@ func (r *expenseRepo) GetByID(ctx context.Context, id int64) (*model.ExpenseEnti
 	return &expense, err
 }
 
+func (r *expenseRepo) GetByAnalysisJob(ctx context.Context, jobID uint) (*model.ExpenseEntity, error) {
+	var expense model.ExpenseEntity
+	err := r.db.WithContext(ctx).Unscoped().Where("analysis_job_id = ?", jobID).First(&expense).Error
+	return &expense, err
+}
+
+func (r *expenseRepo) ListByIDs(ctx context.Context, userID string, ids []uint) ([]model.ExpenseEntity, error) {
+	var expenses []model.ExpenseEntity
+	if len(ids) == 0 {
+		return expenses, nil
+	}
+	err := r.db.WithContext(ctx).Where("user_id = ? AND id IN ?", userID, ids).Order("created_at DESC").Find(&expenses).Error
+	return expenses, err
+}
+
 func (r *expenseRepo) Update(ctx context.Context, expense *model.ExpenseEntity) error {
 	return r.db.WithContext(ctx).Save(expense).Error
 }
 
+func (r *expenseRepo) UpdateBatch(ctx context.Context, expenses []*model.ExpenseEntity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, expense := range expenses {
+			if err := tx.Save(expense).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *expenseRepo) Delete(ctx context.Context, id int64) error {
 	return r.db.WithContext(ctx).Delete(&model.ExpenseEntity{}, id).Error
 }
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"gorm.io/gorm"
+)
+
+// ExperimentRepo A/B 实验的持久化
+type ExperimentRepo interface {
+	Create(ctx context.Context, exp *model.Experiment) error
+	Get(ctx context.Context, id uint) (*model.Experiment, error)
+	GetByName(ctx context.Context, name string) (*model.Experiment, error)
+	List(ctx context.Context) ([]model.Experiment, error)
+	// Running 正在进行的实验，没有时返回 nil, nil
+	Running(ctx context.Context) (*model.Experiment, error)
+	// Start 开始一个实验，同时停止其他正在进行的实验
+	Start(ctx context.Context, id uint, now time.Time) error
+	// Stop 停止一个正在进行的实验
+	Stop(ctx context.Context, id uint, now time.Time) error
+}
+
+type experimentRepo struct {
+	db *gorm.DB
+}
+
+// NewExperimentRepo 构造函数
+func NewExperimentRepo(db *gorm.DB) ExperimentRepo {
+	return &experimentRepo{db: db}
+}
+
+func (r *experimentRepo) Create(ctx context.Context, exp *model.Experiment) error {
+	return r.db.WithContext(ctx).Create(exp).Error
+}
+
+func (r *experimentRepo) Get(ctx context.Context, id uint) (*model.Experiment, error) {
+	var exp model.Experiment
+	err := r.db.WithContext(ctx).First(&exp, id).Error
+	return &exp, err
+}
+
+func (r *experimentRepo) GetByName(ctx context.Context, name string) (*model.Experiment, error) {
+	var exp model.Experiment
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&exp).Error
+	return &exp, err
+}
+
+func (r *experimentRepo) List(ctx context.Context) ([]model.Experiment, error) {
+	var exps []model.Experiment
+	err := r.db.WithContext(ctx).Order("id DESC").Find(&exps).Error
+	return exps, err
+}
+
+func (r *experimentRepo) Running(ctx context.Context) (*model.Experiment, error) {
+	var exp model.Experiment
+	err := r.db.WithContext(ctx).Where("status = ?", model.ExperimentRunning).Order("started_at DESC").First(&exp).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &exp, nil
+}
+
+func (r *experimentRepo) Start(ctx context.Context, id uint, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.Experiment{}).
+			Where("status = ? AND id <> ?", model.ExperimentRunning, id).
+			Updates(map[string]interface{}{"status": model.ExperimentStopped, "stopped_at": now}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.Experiment{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{"status": model.ExperimentRunning, "started_at": now, "stopped_at": nil}).Error
+	})
+}
+
+func (r *experimentRepo) Stop(ctx context.Context, id uint, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.Experiment{}).
+		Where("id = ? AND status = ?", id, model.ExperimentRunning).
+		Updates(map[string]interface{}{"status": model.ExperimentStopped, "stopped_at": now}).Error
+}
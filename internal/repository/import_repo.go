@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"gorm.io/gorm"
+)
+
+// 批次进度相关的列，更新时只写这些，避免把几 MB 的 Payload 反复写回去
+var batchProgressColumns = []string{"status", "total", "processed", "imported", "duplicates", "skipped", "error", "finished_at"}
+
+// ImportRepo 导入相关的持久化：列映射方案 + 导入批次
+type ImportRepo interface {
+	SaveProfile(ctx context.Context, profile *model.ImportProfile) error
+	GetProfile(ctx context.Context, id uint) (*model.ImportProfile, error)
+	ListProfiles(ctx context.Context, userID string) ([]model.ImportProfile, error)
+	DeleteProfile(ctx context.Context, id uint) error
+
+	CreateBatch(ctx context.Context, batch *model.ImportBatch) error
+	GetBatch(ctx context.Context, id uint) (*model.ImportBatch, error)
+	// GetBatchMeta 同 GetBatch，但不加载原始文件，轮询进度用
+	GetBatchMeta(ctx context.Context, id uint) (*model.ImportBatch, error)
+	ListBatches(ctx context.Context, userID string, limit int) ([]model.ImportBatch, error)
+	ListUnfinishedBatches(ctx context.Context) ([]model.ImportBatch, error)
+	UpdateBatchProgress(ctx context.Context, batch *model.ImportBatch) error
+	// CommitChunk 在同一个事务里写入一批账单并推进断点，保证重启后不会重复导入
+	CommitChunk(ctx context.Context, batch *model.ImportBatch, expenses []*model.ExpenseEntity) error
+	// RollbackBatch 删除该批次导入的所有账单，返回被删除的账单 ID (用于同步清理向量记忆)
+	RollbackBatch(ctx context.Context, batch *model.ImportBatch) ([]int64, error)
+}
+
+type importRepo struct {
+	db *gorm.DB
+}
+
+// NewImportRepo 构造函数
+func NewImportRepo(db *gorm.DB) ImportRepo {
+	return &importRepo{db: db}
+}
+
+func (r *importRepo) SaveProfile(ctx context.Context, profile *model.ImportProfile) error {
+	return r.db.WithContext(ctx).Save(profile).Error
+}
+
+func (r *importRepo) GetProfile(ctx context.Context, id uint) (*model.ImportProfile, error) {
+	var profile model.ImportProfile
+	err := r.db.WithContext(ctx).First(&profile, id).Error
+	return &profile, err
+}
+
+func (r *importRepo) ListProfiles(ctx context.Context, userID string) ([]model.ImportProfile, error) {
+	var profiles []model.ImportProfile
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("updated_at DESC").Find(&profiles).Error
+	return profiles, err
+}
+
+func (r *importRepo) DeleteProfile(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.ImportProfile{}, id).Error
+}
+
+func (r *importRepo) CreateBatch(ctx context.Context, batch *model.ImportBatch) error {
+	return r.db.WithContext(ctx).Create(batch).Error
+}
+
+func (r *importRepo) GetBatch(ctx context.Context, id uint) (*model.ImportBatch, error) {
+	var batch model.ImportBatch
+	err := r.db.WithContext(ctx).First(&batch, id).Error
+	return &batch, err
+}
+
+func (r *importRepo) GetBatchMeta(ctx context.Context, id uint) (*model.ImportBatch, error) {
+	var batch model.ImportBatch
+	err := r.db.WithContext(ctx).Omit("payload").First(&batch, id).Error
+	return &batch, err
+}
+
+func (r *importRepo) ListBatches(ctx context.Context, userID string, limit int) ([]model.ImportBatch, error) {
+	var batches []model.ImportBatch
+	// 列表不需要原始文件
+	err := r.db.WithContext(ctx).Omit("payload").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&batches).Error
+	return batches, err
+}
+
+func (r *importRepo) ListUnfinishedBatches(ctx context.Context) ([]model.ImportBatch, error) {
+	var batches []model.ImportBatch
+	err := r.db.WithContext(ctx).Omit("payload").
+		Where("status IN ?", []string{model.ImportBatchPending, model.ImportBatchRunning}).
+		Order("created_at ASC").
+		Find(&batches).Error
+	return batches, err
+}
+
+func (r *importRepo) UpdateBatchProgress(ctx context.Context, batch *model.ImportBatch) error {
+	return r.db.WithContext(ctx).Model(batch).Select(batchProgressColumns).Updates(batch).Error
+}
+
+func (r *importRepo) CommitChunk(ctx context.Context, batch *model.ImportBatch, expenses []*model.ExpenseEntity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(expenses) > 0 {
+			if err := tx.CreateInBatches(expenses, 200).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(batch).Select(batchProgressColumns).Updates(batch).Error
+	})
+}
+
+func (r *importRepo) RollbackBatch(ctx context.Context, batch *model.ImportBatch) ([]int64, error) {
+	var ids []int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.ExpenseEntity{}).
+			Where("user_id = ? AND import_batch_id = ?", batch.UserID, batch.ID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND import_batch_id = ?", batch.UserID, batch.ID).
+			Delete(&model.ExpenseEntity{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(batch).Select(batchProgressColumns).Updates(batch).Error
+	})
+	return ids, err
+}
@@ -6,14 +6,26 @@ import (
 )
 
 type MemoryResult struct {
+	ExpenseID uint
 	Content   string
 	Category  string
 	Timestamp int64
 }
 
+// MemoryItem 批量写入时的单条记忆
+type MemoryItem struct {
+	ExpenseID   uint
+	Description string
+	Category    string
+	Vector      []float32
+	Timestamp   int64 // 消费发生的时间，导入历史账单时不能用当前时间
+}
+
 // MemoryRepo 定义了 AI 记忆相关的接口
 type MemoryRepo interface {
 	SaveMemory(ctx context.Context, uuid string, expenseID uint, description string, category string, vector []float32) error
+	SaveMemories(ctx context.Context, uuid string, items []MemoryItem) error
 	SearchSimilar(ctx context.Context, uuid string, limit int, queryVector []float32) ([]MemoryResult, error)
 	Delete(ctx context.Context, id int64) error
+	DeleteMany(ctx context.Context, ids []int64) error
 }
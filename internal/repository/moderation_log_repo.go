@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"gorm.io/gorm"
+)
+
+// ModerationLogFilter 内容检查记录的筛选条件，字段为空时不筛选
+type ModerationLogFilter struct {
+	UserID   string
+	Category string
+	Page     int
+	PageSize int
+}
+
+// ModerationLogRepo 内容检查记录的持久化
+type ModerationLogRepo interface {
+	CreateBatch(ctx context.Context, logs []*model.ModerationLog) error
+	List(ctx context.Context, filter ModerationLogFilter) ([]model.ModerationLog, int64, error)
+}
+
+type moderationLogRepo struct {
+	db *gorm.DB
+}
+
+// NewModerationLogRepo 构造函数
+func NewModerationLogRepo(db *gorm.DB) ModerationLogRepo {
+	return &moderationLogRepo{db: db}
+}
+
+func (r *moderationLogRepo) CreateBatch(ctx context.Context, logs []*model.ModerationLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(logs).Error
+}
+
+func (r *moderationLogRepo) List(ctx context.Context, filter ModerationLogFilter) ([]model.ModerationLog, int64, error) {
+	db := r.db.WithContext(ctx).Model(&model.ModerationLog{})
+	if filter.UserID != "" {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Category != "" {
+		db = db.Where("category = ?", filter.Category)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var logs []model.ModerationLog
+	err := db.Order("id DESC").
+		Limit(filter.PageSize).
+		Offset((filter.Page - 1) * filter.PageSize).
+		Find(&logs).Error
+	return logs, total, err
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"gorm.io/gorm"
+)
+
+// RoastStats 一个 人设 + Prompt 版本 组合的反馈汇总
+type RoastStats struct {
+	Persona       string `json:"persona"`
+	PromptVersion string `json:"prompt_version"`
+	Roasts        int64  `json:"roasts"`   // 吐槽条数
+	Liked         int64  `json:"liked"`    // 被赞
+	Disliked      int64  `json:"disliked"` // 被踩
+	// 赞 / (赞 + 踩)，没有反馈时为 0
+	LikeRate float64 `gorm:"-" json:"like_rate"`
+}
+
+// RoastRepo 吐槽记录的持久化
+type RoastRepo interface {
+	Create(ctx context.Context, roast *model.Roast) error
+	GetByExpense(ctx context.Context, expenseID uint) (*model.Roast, error)
+	// SetFeedback 记录用户的反馈，feedback 为 0 表示撤销
+	SetFeedback(ctx context.Context, id uint, feedback int8, at time.Time) error
+	// Recent 用户最近的吐槽，最新的在前
+	Recent(ctx context.Context, userID string, limit int) ([]model.Roast, error)
+	// ListByFeedback 用户赞过 (1) 或踩过 (-1) 的吐槽，最近反馈的在前
+	ListByFeedback(ctx context.Context, userID string, feedback int8, limit int) ([]model.Roast, error)
+	// Stats 按人设和 Prompt 版本汇总反馈
+	Stats(ctx context.Context) ([]RoastStats, error)
+}
+
+type roastRepo struct {
+	db *gorm.DB
+}
+
+// NewRoastRepo 构造函数
+func NewRoastRepo(db *gorm.DB) RoastRepo {
+	return &roastRepo{db: db}
+}
+
+func (r *roastRepo) Create(ctx context.Context, roast *model.Roast) error {
+	return r.db.WithContext(ctx).Create(roast).Error
+}
+
+func (r *roastRepo) GetByExpense(ctx context.Context, expenseID uint) (*model.Roast, error) {
+	var roast model.Roast
+	err := r.db.WithContext(ctx).Where("expense_id = ?", expenseID).First(&roast).Error
+	return &roast, err
+}
+
+func (r *roastRepo) SetFeedback(ctx context.Context, id uint, feedback int8, at time.Time) error {
+	updates := map[string]interface{}{"feedback": feedback, "feedback_at": at}
+	if feedback == model.RoastNoVote {
+		updates["feedback_at"] = nil
+	}
+	return r.db.WithContext(ctx).Model(&model.Roast{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *roastRepo) Recent(ctx context.Context, userID string, limit int) ([]model.Roast, error) {
+	var roasts []model.Roast
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&roasts).Error
+	return roasts, err
+}
+
+func (r *roastRepo) ListByFeedback(ctx context.Context, userID string, feedback int8, limit int) ([]model.Roast, error) {
+	var roasts []model.Roast
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND feedback = ?", userID, feedback).
+		Order("feedback_at DESC").
+		Limit(limit).
+		Find(&roasts).Error
+	return roasts, err
+}
+
+func (r *roastRepo) Stats(ctx context.Context) ([]RoastStats, error) {
+	var stats []RoastStats
+	err := r.db.WithContext(ctx).Model(&model.Roast{}).
+		Select("persona, prompt_version, COUNT(*) AS roasts, " +
+			"SUM(CASE WHEN feedback = 1 THEN 1 ELSE 0 END) AS liked, " +
+			"SUM(CASE WHEN feedback = -1 THEN 1 ELSE 0 END) AS disliked").
+		Group("persona, prompt_version").
+		Order("persona, prompt_version").
+		Scan(&stats).Error
+	return stats, err
+}
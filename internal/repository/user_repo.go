@@ -27,3 +27,28 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.U
 	}
 	return &user, nil
 }
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
+	var user model.User
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateTimeZone 只更新时区，不碰其他字段
+func (r *UserRepository) UpdateTimeZone(ctx context.Context, id string, timeZone string) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("time_zone", timeZone).Error
+}
+
+// UpdatePromptVersion 只更新 Prompt 模板版本
+func (r *UserRepository) UpdatePromptVersion(ctx context.Context, id string, version string) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("prompt_version", version).Error
+}
+
+// UpdateRoast 只更新吐槽人设和力度
+func (r *UserRepository) UpdateRoast(ctx context.Context, id string, persona string, intensity int) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"roast_persona": persona, "roast_intensity": intensity}).Error
+}
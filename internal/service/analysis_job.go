@@ -0,0 +1,387 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"gorm.io/gorm"
+)
+
+// 异步任务的默认参数
+const (
+	defaultJobWorkers         = 4
+	defaultJobMaxAttempts     = 5
+	defaultJobBackoff         = 5 * time.Second
+	defaultJobMaxBackoff      = 5 * time.Minute
+	defaultJobTimeout         = 2 * time.Minute
+	defaultJobPollInterval    = 5 * time.Second
+	defaultCallbackTimeout    = 10 * time.Second
+	defaultCallbackMaxAttempt = 5
+)
+
+var (
+	// ErrJobNotFound 任务不存在或不属于该用户
+	ErrJobNotFound = errors.New("任务不存在")
+	// ErrInvalidCallbackURL 回调地址不是 http(s) 地址，或者解析到内网、本机、链路本地 (云厂商元数据) 这类非公网地址
+	ErrInvalidCallbackURL = errors.New("回调地址必须是公网可访问的 http 或 https 地址")
+)
+
+// AnalysisJobConfig 异步记账任务的参数，留空使用默认值
+type AnalysisJobConfig struct {
+	Workers        int           // 同时处理的任务数，默认 4
+	MaxAttempts    int           // 每个任务最多调用几次模型，默认 5
+	Backoff        time.Duration // 第一次重试的等待时间，之后每次翻倍，默认 5s
+	MaxBackoff     time.Duration // 重试等待的上限，默认 5m
+	Timeout        time.Duration // 单次处理的超时，默认 2m
+	PollInterval   time.Duration // 空闲 worker 检查到期重试任务的间隔，默认 5s
+	CallbackSecret string        // 回调签名的密钥，为空时不签名
+	// 允许回调内网和本机地址，只用于本地联调；默认只投递到公网地址
+	AllowPrivateCallbacks bool
+}
+
+// AnalysisJobService 异步记账：提交后立即返回任务 ID，客户端轮询或等待回调
+// 任务存在数据库里，worker 从表里领取到期的任务，失败后按指数退避重新排队
+type AnalysisJobService struct {
+	repo    repository.AnalysisJobRepo
+	expense *ExpenseService
+	conf    AnalysisJobConfig
+	client  *http.Client
+
+	wake chan struct{} // 有新任务时叫醒一个空闲 worker
+}
+
+// NewAnalysisJobService 构造函数
+func NewAnalysisJobService(repo repository.AnalysisJobRepo, expense *ExpenseService, conf AnalysisJobConfig) *AnalysisJobService {
+	if conf.Workers <= 0 {
+		conf.Workers = defaultJobWorkers
+	}
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = defaultJobMaxAttempts
+	}
+	if conf.Backoff <= 0 {
+		conf.Backoff = defaultJobBackoff
+	}
+	if conf.MaxBackoff <= 0 {
+		conf.MaxBackoff = defaultJobMaxBackoff
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultJobTimeout
+	}
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = defaultJobPollInterval
+	}
+	return &AnalysisJobService{
+		repo:    repo,
+		expense: expense,
+		conf:    conf,
+		client:  newCallbackClient(conf.AllowPrivateCallbacks),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Start 服务启动时调用：把上次重启前没跑完的任务放回队列，补发没投递成功的回调，然后启动 worker
+func (s *AnalysisJobService) Start(ctx context.Context) {
+	if n, err := s.repo.RequeueRunning(ctx); err != nil {
+		slog.Error("恢复异步记账任务失败", "error", err)
+	} else if n > 0 {
+		slog.Info("恢复异步记账任务", "count", n)
+	}
+
+	if jobs, err := s.repo.ListUndelivered(ctx); err != nil {
+		slog.Error("查询未投递的回调失败", "error", err)
+	} else {
+		for i := range jobs {
+			s.scheduleCallback(&jobs[i], 0)
+		}
+	}
+
+	for i := 0; i < s.conf.Workers; i++ {
+		go s.worker(ctx)
+	}
+}
+
+// Submit 提交一个记账任务
+func (s *AnalysisJobService) Submit(ctx context.Context, userID string, description string, timeZone string, callbackURL string) (*model.AnalysisJob, error) {
+	callbackURL = strings.TrimSpace(callbackURL)
+	if callbackURL != "" {
+		if err := s.checkCallbackURL(ctx, callbackURL); err != nil {
+			return nil, err
+		}
+	}
+
+	job := &model.AnalysisJob{
+		UserID:      userID,
+		Description: description,
+		TimeZone:    timeZone,
+		CallbackURL: callbackURL,
+		Status:      model.AnalysisJobPending,
+		NextRunAt:   time.Now(),
+	}
+	if callbackURL != "" {
+		job.CallbackStatus = model.CallbackPending
+	}
+	if err := s.repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	s.notify()
+	return job, nil
+}
+
+// checkCallbackURL 回调地址必须是 http(s)，并且主机解析出的所有地址都是公网地址
+// 提交时检查一次，尽早给出明确的错误；投递时建立连接前还会再检查一次，防止域名之后改指向内网
+func (s *AnalysisJobService) checkCallbackURL(ctx context.Context, callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidCallbackURL
+	}
+	if s.conf.AllowPrivateCallbacks {
+		return nil
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !publicAddr(addr) {
+			return ErrInvalidCallbackURL
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return ErrInvalidCallbackURL
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return ErrInvalidCallbackURL
+		}
+	}
+	return nil
+}
+
+// Get 查询任务，只能查自己的
+func (s *AnalysisJobService) Get(ctx context.Context, userID string, id uint) (*model.AnalysisJob, error) {
+	job, err := s.repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+func (s *AnalysisJobService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// worker 循环领取到期的任务；没有任务时等新任务或定时检查重试
+func (s *AnalysisJobService) worker(ctx context.Context) {
+	ticker := time.NewTicker(s.conf.PollInterval)
+	defer ticker.Stop()
+	for {
+		job, err := s.repo.ClaimNext(ctx, time.Now())
+		switch {
+		case err == nil:
+			s.run(ctx, job)
+			continue
+		case !errors.Is(err, repository.ErrNoJobDue):
+			slog.Error("领取异步记账任务失败", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// run 处理一次任务，失败时按退避时间重新排队或标记失败
+func (s *AnalysisJobService) run(ctx context.Context, job *model.AnalysisJob) {
+	runCtx, cancel := context.WithTimeout(ctx, s.conf.Timeout)
+	defer cancel()
+
+	slog.Info("开始处理异步记账任务", "job", job.ID, "uid", job.UserID, "attempt", job.Attempts)
+	// 上次已经落库但没来得及写回状态 (服务崩溃)，直接用那条账单，不重复记账
+	result, err := s.expense.JobResult(runCtx, job)
+	if err == nil && result != nil {
+		slog.Info("异步记账任务已经落库，跳过模型调用", "job", job.ID, "expense", result.Expense.ID)
+	} else if err == nil {
+		result, err = s.expense.AnalyzeExpense(runCtx, ExpenseInput{
+			UserID:      job.UserID,
+			Description: job.Description,
+			TimeZone:    job.TimeZone,
+			JobID:       job.ID,
+		})
+	}
+
+	now := time.Now()
+	if err == nil {
+		data, _ := json.Marshal(result)
+		job.Status = model.AnalysisJobSucceeded
+		job.ExpenseID = &result.Expense.ID
+		job.Result = data
+		job.ErrorCode, job.Error = "", ""
+		job.FinishedAt = &now
+	} else {
+		failure := NewStreamFailure(err)
+		job.ErrorCode, job.Error = failure.Code, failure.Message
+		switch {
+		case ctx.Err() != nil:
+			// 服务正在停止，不算一次失败，下次启动后重新处理
+			job.Status = model.AnalysisJobPending
+			job.Attempts--
+		case job.Attempts < s.conf.MaxAttempts:
+			job.Status = model.AnalysisJobPending
+			job.NextRunAt = now.Add(s.backoff(job.Attempts))
+			slog.Warn("异步记账任务失败，稍后重试", "job", job.ID, "attempt", job.Attempts, "retry_at", job.NextRunAt, "error", err)
+		default:
+			job.Status = model.AnalysisJobFailed
+			job.FinishedAt = &now
+			slog.Error("异步记账任务失败", "job", job.ID, "attempts", job.Attempts, "error", err)
+		}
+	}
+
+	// 服务停止时 ctx 已经取消，状态仍然要写回去
+	if err := s.repo.UpdateProgress(context.WithoutCancel(ctx), job); err != nil {
+		slog.Error("更新异步记账任务失败", "job", job.ID, "error", err)
+		return
+	}
+	if job.Finished() && job.CallbackStatus == model.CallbackPending {
+		s.scheduleCallback(job, 0)
+	}
+}
+
+// backoff 第 attempt 次失败后的等待时间：Backoff * 2^(attempt-1)，不超过 MaxBackoff
+func (s *AnalysisJobService) backoff(attempt int) time.Duration {
+	wait := s.conf.Backoff
+	for i := 1; i < attempt && wait < s.conf.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, s.conf.MaxBackoff)
+}
+
+// scheduleCallback delay 之后投递回调，失败按同样的退避规则重试
+func (s *AnalysisJobService) scheduleCallback(job *model.AnalysisJob, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		ctx := context.Background()
+		job.CallbackAttempts++
+		err := s.deliver(ctx, job)
+		switch {
+		case err == nil:
+			job.CallbackStatus = model.CallbackDelivered
+		case job.CallbackAttempts >= defaultCallbackMaxAttempt:
+			job.CallbackStatus = model.CallbackFailed
+			slog.Error("回调投递失败", "job", job.ID, "url", job.CallbackURL, "attempts", job.CallbackAttempts, "error", err)
+		default:
+			slog.Warn("回调投递失败，稍后重试", "job", job.ID, "url", job.CallbackURL, "attempt", job.CallbackAttempts, "error", err)
+		}
+		if err := s.repo.UpdateProgress(ctx, job); err != nil {
+			slog.Error("更新回调状态失败", "job", job.ID, "error", err)
+		}
+		if job.CallbackStatus == model.CallbackPending {
+			s.scheduleCallback(job, s.backoff(job.CallbackAttempts))
+		}
+	})
+}
+
+// deliver POST 任务 JSON 到回调地址，2xx 视为成功
+// 配置了密钥时带上 X-FaceTax-Signature: sha256=<HMAC-SHA256(body) 的十六进制>
+func (s *AnalysisJobService) deliver(ctx context.Context, job *model.AnalysisJob) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-FaceTax-Job-ID", fmt.Sprint(job.ID))
+	if s.conf.CallbackSecret != "" {
+		mac := hmac.New(sha256.New, []byte(s.conf.CallbackSecret))
+		mac.Write(body)
+		req.Header.Set("X-FaceTax-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("回调返回 HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newCallbackClient 投递回调用的 HTTP 客户端
+// 在建立连接时检查实际要连的 IP，DNS 解析结果变化或者重定向到内网地址都会被拒绝；不走环境变量里的代理，否则检查的是代理的地址
+func newCallbackClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: defaultCallbackTimeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addrPort.Addr()) {
+				return fmt.Errorf("回调地址 %s 不是公网地址", addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: defaultCallbackTimeout, Transport: transport}
+}
+
+// reservedPrefixes netip 没有覆盖、但同样不能作为回调目标的保留网段
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"), // 运营商级 NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF 协议分配
+	netip.MustParsePrefix("198.18.0.0/15"), // 网络基准测试
+	netip.MustParsePrefix("240.0.0.0/4"),   // 保留地址，包括广播地址
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64，可能转换到内网的 IPv4
+}
+
+// publicAddr 判断是否为公网地址：排除本机、内网、链路本地 (包括 169.254.169.254 元数据地址)、组播和保留网段
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
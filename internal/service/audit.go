@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrAuditNotFound 审计记录不存在
+var ErrAuditNotFound = errors.New("审计记录不存在")
+
+// analysisTrace 一次模型调用的上下文，输出结束后转成审计记录
+type analysisTrace struct {
+	source      string
+	description string
+	history     []string
+	provider    string // 组合 Provider 没有记录时的兜底名字
+	assignment  *Assignment
+	persona     string
+	injection   string // 描述命中的注入特征，见 detectInjection
+	start       time.Time
+	info        *llm.CallInfo
+}
+
+// newTrace 在调用模型之前创建，返回的 ctx 挂着 CallInfo，后端会把模型名和用量写进去
+func newTrace(ctx context.Context, source string, description string, history []string, provider string) (context.Context, *analysisTrace) {
+	ctx, info := llm.EnsureCallInfo(ctx)
+	return ctx, &analysisTrace{
+		source:      source,
+		description: description,
+		history:     history,
+		provider:    provider,
+		assignment:  assignmentFrom(ctx),
+		persona:     roastPersona(ctx),
+		start:       time.Now(),
+		info:        info,
+	}
+}
+
+// audit 生成审计记录，expenseID 为 0 表示还没有 (或不会) 落库
+func (t *analysisTrace) audit(userID string, expenseID uint, rawOutput string) *model.AnalysisAudit {
+	provider := t.info.Provider()
+	if provider == "" {
+		provider = t.provider
+	}
+	completion := t.info.Completion()
+	audit := &model.AnalysisAudit{
+		UserID:           userID,
+		ExpenseID:        expenseID,
+		Source:           t.source,
+		Description:      t.description,
+		Provider:         provider,
+		Model:            completion.Model,
+		PromptVersion:    completion.PromptVersion,
+		Persona:          t.persona,
+		Injection:        t.injection,
+		History:          t.history,
+		RawOutput:        rawOutput,
+		LatencyMS:        time.Since(t.start).Milliseconds(),
+		PromptTokens:     completion.PromptTokens,
+		CompletionTokens: completion.CompletionTokens,
+	}
+	if t.assignment != nil {
+		audit.Experiment = t.assignment.Experiment
+		audit.Variant = t.assignment.Variant
+	}
+	return audit
+}
+
+// saveAudit 写入审计记录；审计只用于排查问题，失败不影响记账
+func (s *ExpenseService) saveAudit(ctx context.Context, audit *model.AnalysisAudit) {
+	if s.audits == nil || audit == nil {
+		return
+	}
+	if err := s.audits.Create(context.WithoutCancel(ctx), audit); err != nil {
+		slog.Error("写入识别审计记录失败", "uid", audit.UserID, "expense", audit.ExpenseID, "error", err)
+	}
+}
+
+// linkAudits 把预览时写入的审计记录关联到确认后的账单，键为记录 ID，值为账单 ID
+func (s *ExpenseService) linkAudits(ctx context.Context, userID string, links map[uint]uint) {
+	if s.audits == nil || len(links) == 0 {
+		return
+	}
+	if err := s.audits.LinkExpenses(context.WithoutCancel(ctx), userID, links); err != nil {
+		slog.Error("关联识别审计记录失败", "uid", userID, "error", err)
+	}
+}
+
+// markCorrected 用户修改了模型识别出的字段，记到这笔账单第一次记账的识别记录上，实验报表据此计算修改率
+func (s *ExpenseService) markCorrected(ctx context.Context, expenseID uint, fields []string) {
+	if s.audits == nil || len(fields) == 0 {
+		return
+	}
+	if err := s.audits.MarkCorrected(context.WithoutCancel(ctx), expenseID, fields, time.Now()); err != nil {
+		slog.Error("记录账单修改失败", "expense", expenseID, "error", err)
+	}
+}
+
+// markAuditCorrected 用户改过重新分析给出的建议，记到那次重新分析的识别记录上
+func (s *ExpenseService) markAuditCorrected(ctx context.Context, auditID uint, fields []string) {
+	if s.audits == nil || len(fields) == 0 {
+		return
+	}
+	if err := s.audits.MarkAuditCorrected(context.WithoutCancel(ctx), auditID, fields, time.Now()); err != nil {
+		slog.Error("记录重新分析的修改失败", "audit", auditID, "error", err)
+	}
+}
+
+// bookingAudit 这笔账单第一次记账时的识别记录，没有写审计或查询失败时返回 nil
+func (s *ExpenseService) bookingAudit(ctx context.Context, expenseID uint) *model.AnalysisAudit {
+	if s.audits == nil {
+		return nil
+	}
+	audits, _, err := s.audits.List(ctx, repository.AuditFilter{ExpenseID: expenseID, Source: model.AuditSourceAnalyze, Page: 1, PageSize: 1})
+	if err != nil {
+		slog.Error("查询识别审计记录失败", "expense", expenseID, "error", err)
+		return nil
+	}
+	if len(audits) == 0 {
+		return nil
+	}
+	return &audits[0]
+}
+
+// AuditService 管理员查看模型识别的审计记录
+type AuditService struct {
+	repo repository.AnalysisAuditRepo
+}
+
+// NewAuditService 构造函数
+func NewAuditService(repo repository.AnalysisAuditRepo) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// List 按条件分页查询，最新的在前
+func (s *AuditService) List(ctx context.Context, filter repository.AuditFilter) ([]model.AnalysisAudit, int64, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Get 查询单条记录
+func (s *AuditService) Get(ctx context.Context, id uint) (*model.AnalysisAudit, error) {
+	audit, err := s.repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAuditNotFound
+	}
+	return audit, err
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+)
+
+const (
+	maxBulkLines    = 50 // 单次批量记账的行数上限
+	bulkConcurrency = 4  // 同时调用模型的行数
+)
+
+var (
+	// ErrBulkEmpty 没有可记账的行
+	ErrBulkEmpty = errors.New("没有可记账的内容")
+	// ErrInvalidBulkEntry 提交的某一行不合法
+	ErrInvalidBulkEntry = errors.New("记账内容不合法")
+)
+
+// 行首的列表标记：- 超市 86 / • 超市 86 / 1. 超市 86 / 2、超市 86
+var bulkListMarker = regexp.MustCompile(`^(?:[-*•·]|\d{1,2}[.、)）])\s+`)
+
+// BulkLine 批量记账中一行的预览结果
+type BulkLine struct {
+	Line       int     `json:"line"` // 在原文中的行号，从 1 开始
+	Text       string  `json:"text"` // 去掉列表标记后的原文
+	Amount     float64 `json:"amount"`
+	Category   string  `json:"category"`
+	Date       string  `json:"date"` // YYYY-MM-DD，用户所在时区
+	Note       string  `json:"note"`
+	Comment    string  `json:"comment"`
+	DateSource string  `json:"date_source,omitempty"` // text / llm / default，同 DateResolution.Source
+	AuditID    uint    `json:"audit_id,omitempty"`    // 这一行的识别审计记录，确认入账时原样带回
+	// 这一行识别失败的原因码和文案，取值同 SSE 的 error 事件；有错误时其余字段没有意义
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BulkEntry 确认入账的一行，可以是预览结果原样提交，也可以是用户修改过的
+type BulkEntry struct {
+	Text     string  `json:"text"` // 原文，写进向量记忆
+	Amount   float64 `json:"amount"`
+	Category string  `json:"category"`
+	Date     string  `json:"date"` // YYYY-MM-DD
+	Note     string  `json:"note"`
+	Comment  string  `json:"comment"`
+	AuditID  uint    `json:"audit_id,omitempty"` // 预览结果里的 audit_id，用于把审计记录关联到账单
+}
+
+// splitBulkLines 按行 (以及分号) 拆分，跳过空行，去掉列表标记
+func splitBulkLines(text string) []BulkLine {
+	var lines []BulkLine
+	for i, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		for _, part := range strings.FieldsFunc(raw, func(r rune) bool { return r == ';' || r == '；' }) {
+			part = strings.TrimSpace(bulkListMarker.ReplaceAllString(strings.TrimSpace(part), ""))
+			if part != "" {
+				lines = append(lines, BulkLine{Line: i + 1, Text: part})
+			}
+		}
+	}
+	return lines
+}
+
+// PreviewBulk 拆分粘贴的多行文本，逐行调用模型识别，不落库
+// 单行失败不影响其他行，错误写在对应的 BulkLine 里
+func (s *ExpenseService) PreviewBulk(ctx context.Context, userID string, text string, timeZone string) ([]BulkLine, error) {
+	lines := splitBulkLines(text)
+	if len(lines) == 0 {
+		return nil, ErrBulkEmpty
+	}
+	if len(lines) > maxBulkLines {
+		return nil, fmt.Errorf("%w: 单次最多 %d 条，当前 %d 条", ErrInvalidBulkEntry, maxBulkLines, len(lines))
+	}
+
+	// 每一行都用这份用户资料，不再逐行查库
+	ctx, user := s.withUser(ctx, userID)
+	now := s.userNow(user, timeZone)
+	sem := make(chan struct{}, bulkConcurrency)
+	var wg sync.WaitGroup
+	for i := range lines {
+		wg.Add(1)
+		go func(l *BulkLine) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			s.previewLine(ctx, userID, l, now)
+		}(&lines[i])
+	}
+	wg.Wait()
+
+	slog.Info("批量记账预览完成", "uid", userID, "total", len(lines))
+	return lines, nil
+}
+
+func (s *ExpenseService) previewLine(ctx context.Context, userID string, l *BulkLine, now time.Time) {
+	analysis, audit, err := s.analyzeOnce(ctx, userID, l.Text, true, now, 0, model.AuditSourceBulk)
+	if audit != nil {
+		s.saveAudit(ctx, audit)
+		l.AuditID = audit.ID
+	}
+	if err != nil {
+		slog.Warn("批量记账单行识别失败", "uid", userID, "line", l.Line, "error", err)
+		failure := NewStreamFailure(err)
+		l.ErrorCode, l.Error = failure.Code, failure.Message
+		return
+	}
+
+	l.Amount = analysis.Amount
+	l.Category = analysis.Category
+	l.Note = analysis.Note
+	l.Comment = analysis.Comment
+	// 每行各自的日期表达 ("3/2"、"上周五") 优先于模型的推断
+	_, resolution := resolveExpenseTime(l.Text, analysis.Date, now)
+	l.Date, l.DateSource = resolution.Date, resolution.Source
+}
+
+// CommitBulk 把确认的行在一个事务里入账，全部成功或全部失败；向量记忆在提交后异步写入
+func (s *ExpenseService) CommitBulk(ctx context.Context, userID string, entries []BulkEntry, timeZone string) ([]*model.ExpenseEntity, error) {
+	if len(entries) == 0 {
+		return nil, ErrBulkEmpty
+	}
+	if len(entries) > maxBulkLines {
+		return nil, fmt.Errorf("%w: 单次最多 %d 条，当前 %d 条", ErrInvalidBulkEntry, maxBulkLines, len(entries))
+	}
+
+	// 每一行都用这份用户资料，不再逐行查库
+	ctx, user := s.withUser(ctx, userID)
+	now := s.userNow(user, timeZone)
+	entities := make([]*model.ExpenseEntity, len(entries))
+	texts := make([]string, len(entries))
+	for i, e := range entries {
+		expenseTime, err := bulkEntryTime(e.Date, now)
+		if err == nil {
+			err = checkTextLength(e.Note, e.Comment)
+		}
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("%w: 第 %d 条%v", ErrInvalidBulkEntry, i+1, err)
+		case e.Amount <= 0 || e.Amount > maxExpenseAmount:
+			return nil, fmt.Errorf("%w: 第 %d 条金额不合法", ErrInvalidBulkEntry, i+1)
+		case !slices.Contains(model.PredefinedCategories, e.Category):
+			return nil, fmt.Errorf("%w: 第 %d 条分类不存在: %s", ErrInvalidBulkEntry, i+1, e.Category)
+		}
+		entities[i] = &model.ExpenseEntity{
+			UserID:      userID,
+			Amount:      e.Amount,
+			Category:    e.Category,
+			Note:        e.Note,
+			Comment:     e.Comment,
+			CreatedAt:   expenseTime,
+			Description: e.Text,
+		}
+		texts[i] = e.Text
+		if texts[i] == "" {
+			texts[i] = e.Note
+		}
+	}
+
+	// 预览时已经检查过吐槽，这里只兜住客户端改过的，不通过直接替换，不再调用模型
+	ctx = prompt.WithStyle(ctx, styleOf(user))
+	moderationLogs := make([][]*model.ModerationLog, len(entities))
+	for i, entity := range entities {
+		entity.Comment, moderationLogs[i] = s.moderateComment(ctx, userID, model.AuditSourceBulk, "", entity.Comment, false)
+	}
+
+	// 条数远小于 CreateBatch 的分批大小，一条 INSERT 写完，要么全部成功要么全部失败
+	if err := s.repo.CreateBatch(ctx, entities); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSaveFailed, err)
+	}
+	go saveMemories(s.embedder, s.memoryRepo, userID, entities, texts)
+
+	links := make(map[uint]uint)
+	for i, e := range entries {
+		if e.AuditID != 0 {
+			links[e.AuditID] = entities[i].ID
+		}
+	}
+	s.linkAudits(ctx, userID, links)
+	for i, e := range entries {
+		s.saveModerationLogs(ctx, entities[i].ID, moderationLogs[i])
+		if entities[i].Comment != "" {
+			s.recordRoast(ctx, entities[i], s.previewAudit(ctx, userID, e.AuditID))
+		}
+	}
+
+	slog.Info("批量记账完成", "uid", userID, "count", len(entities))
+	return entities, nil
+}
+
+// previewAudit 预览时写入的识别记录，用来知道吐槽是哪个人设和 Prompt 版本生成的；查不到返回 nil
+func (s *ExpenseService) previewAudit(ctx context.Context, userID string, auditID uint) *model.AnalysisAudit {
+	if s.audits == nil || auditID == 0 {
+		return nil
+	}
+	audit, err := s.audits.Get(ctx, auditID)
+	if err != nil || audit.UserID != userID {
+		return nil
+	}
+	return audit
+}
+
+// bulkEntryTime 解析确认行的日期，时分秒沿用当前时刻；不接受未来的日期
+func bulkEntryTime(date string, now time.Time) (time.Time, error) {
+	loc := now.Location()
+	day, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(date), loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式错误: %s", date)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if day.After(today) {
+		return time.Time{}, fmt.Errorf("日期在未来: %s", date)
+	}
+	if day.Equal(today) {
+		return now, nil
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), now.Hour(), now.Minute(), now.Second(), 0, loc), nil
+}
@@ -3,20 +3,27 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/leon37/FaceTaxLedger/internal/calendar"
 	"github.com/leon37/FaceTaxLedger/internal/infrastructure/embedding"
 	"github.com/leon37/FaceTaxLedger/internal/repository"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
 	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+	"gorm.io/gorm"
 )
 
 // ExpenseInput 是前端传来的原始参数 (DTO)
 type ExpenseInput struct {
 	UserID      string `json:"user_id"`
 	Description string `json:"description"` // 例如："请客吃饭"
+	TimeZone    string `json:"timezone"`    // 用户所在时区 (IANA 名字)，为空时使用服务端默认时区
+	JobID       uint   `json:"-"`           // 异步记账任务的 ID，和账单一起落库，任务重跑时不会重复记账
 }
 
 // ExpenseResult 是返回给前端的完整结果 (VO)
@@ -28,42 +35,273 @@ type ExpenseResult struct {
 
 // ExpenseService 定义业务逻辑接口
 type ExpenseService struct {
-	llmClient  llm.Provider // 依赖接口，而不是具体 struct！(关键点)
-	embedder   embedding.Provider
-	repo       repository.ExpenseRepo // 稍后我们会注入数据库仓储
-	memoryRepo repository.MemoryRepo
+	llmClient    llm.Provider // 依赖接口，而不是具体 struct！(关键点)
+	visionClient llm.VisionProvider
+	embedder     embedding.Provider
+	repo         repository.ExpenseRepo // 稍后我们会注入数据库仓储
+	memoryRepo   repository.MemoryRepo
+	audits       repository.AnalysisAuditRepo // 为空时不写审计记录
+	prompts      *PromptService               // 为空时使用内置 Prompt
+	experiments  *ExperimentService           // 为空时不参与 A/B 实验
+	roasts       *RoastService                // 为空时不记录吐槽，也不做去重
+	moderation   *ModerationService           // 为空时不检查吐槽
+	users        *UserService                 // 查询用户资料，为空时按默认时区和风格处理
 }
 
 // NewExpenseService 构造函数 (依赖注入)
-func NewExpenseService(llmClient llm.Provider, embedder embedding.Provider, repo repository.ExpenseRepo, memory repository.MemoryRepo) *ExpenseService {
+func NewExpenseService(llmClient llm.Provider, visionClient llm.VisionProvider, embedder embedding.Provider, repo repository.ExpenseRepo, memory repository.MemoryRepo, audits repository.AnalysisAuditRepo, prompts *PromptService, experiments *ExperimentService, roasts *RoastService, moderation *ModerationService, users *UserService) *ExpenseService {
 	return &ExpenseService{
-		llmClient:  llmClient,
-		embedder:   embedder,
-		repo:       repo,
-		memoryRepo: memory,
+		llmClient:    llmClient,
+		visionClient: visionClient,
+		embedder:     embedder,
+		repo:         repo,
+		memoryRepo:   memory,
+		audits:       audits,
+		prompts:      prompts,
+		experiments:  experiments,
+		roasts:       roasts,
+		moderation:   moderation,
+		users:        users,
 	}
 }
 
+// Location 用户所在时区，列表筛选的日期边界按它计算
+func (s *ExpenseService) Location(ctx context.Context, userID string) *time.Location {
+	_, user := s.withUser(ctx, userID)
+	return s.locationOf(user)
+}
+
+// requestUser 一次请求里查到的用户资料，查不到时 user 为空
+type requestUser struct {
+	id   string
+	user *model.User
+}
+
+type requestUserKey struct{}
+
+// withUser 查询用户资料并挂到 ctx 上，同一次请求里的时区、吐槽风格、Prompt 模板和实验分组都从这份资料取，不再重复查库
+// ctx 上已经有这个用户时直接返回；没有注入 UserService 或查询失败时返回 nil，按默认设置处理
+func (s *ExpenseService) withUser(ctx context.Context, userID string) (context.Context, *model.User) {
+	if u, ok := ctx.Value(requestUserKey{}).(*requestUser); ok && u.id == userID {
+		return ctx, u.user
+	}
+	var user *model.User
+	if s.users != nil {
+		user = s.users.lookup(ctx, userID)
+	}
+	return context.WithValue(ctx, requestUserKey{}, &requestUser{id: userID, user: user}), user
+}
+
+// locationOf 用户资料里的时区，没有注入 UserService 时使用 calendar.DefaultTimeZone
+func (s *ExpenseService) locationOf(user *model.User) *time.Location {
+	if s.users == nil {
+		return calendar.Location(calendar.DefaultTimeZone, time.Local)
+	}
+	return s.users.locationOf(user)
+}
+
+// withPrompt 把这个用户使用的 Prompt 模板挂到 ctx 上，模型后端按它生成 Prompt
+func (s *ExpenseService) withPrompt(ctx context.Context, user *model.User) context.Context {
+	if s.prompts == nil {
+		return ctx
+	}
+	return prompt.WithTemplate(ctx, s.prompts.ForUser(user))
+}
+
+// withStyle 把用户的吐槽人设和力度挂到 ctx 上；wantRoast 是这个场景是否需要吐槽，用户把力度调到 0 时也不吐槽
+// 需要吐槽时再带上用户最近的和评价过的吐槽，避免重复
+func (s *ExpenseService) withStyle(ctx context.Context, userID string, user *model.User, wantRoast bool) (context.Context, bool) {
+	style := styleOf(user)
+	if !wantRoast {
+		style.Intensity = 0
+	}
+	if style.Intensity > 0 && s.roasts != nil {
+		style = s.roasts.withMemory(ctx, userID, style)
+	}
+	return prompt.WithStyle(ctx, style), style.Intensity > 0
+}
+
+// route 选出这次文字记账使用的 Prompt 模板和模型后端：用户在实验中时按分到的组，否则按 withPrompt
+func (s *ExpenseService) route(ctx context.Context, userID string, user *model.User) (context.Context, llm.Provider) {
+	if s.experiments != nil {
+		if a := s.experiments.Assign(ctx, userID, user); a != nil {
+			ctx = withAssignment(ctx, a)
+			if a.Template != nil {
+				ctx = prompt.WithTemplate(ctx, a.Template)
+			} else {
+				ctx = s.withPrompt(ctx, user)
+			}
+			if a.Provider != nil {
+				return ctx, a.Provider
+			}
+			return ctx, s.llmClient
+		}
+	}
+	return s.withPrompt(ctx, user), s.llmClient
+}
+
+// userNow 用户所在时区的当前时间，override 是本次请求显式指定的时区，优先于用户资料
+func (s *ExpenseService) userNow(user *model.User, override string) time.Time {
+	if loc := calendar.Location(override, nil); loc != nil {
+		return time.Now().In(loc)
+	}
+	return time.Now().In(s.locationOf(user))
+}
+
 // StreamExpense 处理一次完整的记账请求
-func (s *ExpenseService) StreamExpense(ctx context.Context, input ExpenseInput) (<-chan string, func(fullJson string) (*model.ExpenseEntity, error), error) {
+// 调用方读完 stream 并确认 stream.Err() 为空后才能调用 commitFunc 落库，同时拿到消费日期的判定过程
+func (s *ExpenseService) StreamExpense(ctx context.Context, input ExpenseInput) (*llm.Stream[string], func(fullJson string) (*model.ExpenseEntity, *DateResolution, error), error) {
 	slog.Info("收到记账请求",
 		"uid", input.UserID,
 		"description", input.Description)
 	// 1. RAG 检索：先查历史 (比如查最近相似的 3 条)
-	// 这一步不能报错阻断流程，如果检索失败，就当没有历史
+	historyLogs, err := s.searchHistory(ctx, input.UserID, input.Description, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	preDefinedCategories := model.PredefinedCategories
+	ctx, user := s.withUser(ctx, input.UserID)
+	ctx, enableRoast := s.withStyle(ctx, input.UserID, user, true)
+	// 提示词里的当前时间按用户时区给出
+	ctx = llm.WithNow(ctx, s.userNow(user, input.TimeZone))
+	ctx, provider := s.route(ctx, input.UserID, user)
+	ctx, trace := newTrace(ctx, model.AuditSourceAnalyze, input.Description, historyLogs, provider.Name())
+	trace.injection = detectInjection(input.UserID, model.AuditSourceAnalyze, input.Description)
+	// TODO: 添加用户自定义目录
+	stream, err := provider.AnalyzeExpense(ctx, input.Description, preDefinedCategories, historyLogs, enableRoast)
+	if err != nil {
+		slog.Error("记账模型调用失败", "uid", input.UserID, "provider", provider.Name(), "error", err)
+		return nil, nil, err
+	}
+	// 组合 Provider 会自己记录实际服务的后端，单一后端在这里兜底
+	llm.RecordProvider(ctx, provider.Name())
+	served := llm.ServedBy(ctx)
+	if served == "" {
+		served = provider.Name()
+	}
+	slog.Info("记账模型开始输出", "uid", input.UserID, "provider", served)
+
+	commitFunc := func(fullJSON string) (*model.ExpenseEntity, *DateResolution, error) {
+		entity, resolution, err := s.saveAnalysis(ctx, input, fullJSON, enableRoast)
+		// 解析失败的输出也记下来，正是需要排查的情况
+		audit := trace.audit(input.UserID, 0, fullJSON)
+		if entity != nil {
+			audit.ExpenseID = entity.ID
+		}
+		s.saveAudit(ctx, audit)
+		s.recordRoast(ctx, entity, audit)
+		return entity, resolution, err
+	}
+
+	return stream, commitFunc, nil
+}
+
+// AnalyzeResult 同步记账的结果
+type AnalyzeResult struct {
+	Expense      *model.ExpenseEntity   `json:"expense"`       // 保存后的账单
+	Analysis     *model.FaceTaxAnalysis `json:"analysis"`      // 按保存后的账单给出的分析结果 (吐槽是内容检查后的)，只有 date 保留模型推断的日期
+	ResolvedDate *DateResolution        `json:"resolved_date"` // 消费日期的判定过程
+	Provider     string                 `json:"provider"`      // 实际服务的模型后端
+}
+
+// AnalyzeExpense 同步版本的 StreamExpense：读完模型输出后直接落库
+// 给不能消费 SSE 的调用方使用 (快捷指令、脚本)
+func (s *ExpenseService) AnalyzeExpense(ctx context.Context, input ExpenseInput) (*AnalyzeResult, error) {
+	ctx, callInfo := llm.WithCallInfo(ctx)
+	stream, commitFunc, err := s.StreamExpense(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	var fullJSONBuilder strings.Builder
+	for fragment := range stream.C {
+		fullJSONBuilder.WriteString(fragment)
+	}
+	// 截断的输出不能落库
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	expense, resolution, err := commitFunc(fullJSONBuilder.String())
+	if err != nil {
+		return nil, err
+	}
+	analysis := &model.FaceTaxAnalysis{
+		Amount:   expense.Amount,
+		Date:     resolution.LLMDate,
+		Note:     expense.Note,
+		Comment:  expense.Comment,
+		Category: expense.Category,
+	}
+	return &AnalyzeResult{Expense: expense, Analysis: analysis, ResolvedDate: resolution, Provider: callInfo.Provider()}, nil
+}
+
+// JobResult 查出异步记账任务已经落库的账单，还没有落库时返回 nil
+// 任务落库后、写回状态前服务崩溃的话，重跑时直接用这条账单，不再调用模型
+// 日期按任务提交时的时区换算；模型的原始日期和 Provider 从那次记账的审计记录里还原，结果和第一次处理时一致
+func (s *ExpenseService) JobResult(ctx context.Context, job *model.AnalysisJob) (*AnalyzeResult, error) {
+	expense, err := s.repo.GetByAnalysisJob(ctx, job.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	loc := calendar.Location(job.TimeZone, nil)
+	if loc == nil {
+		loc = s.Location(ctx, job.UserID)
+	}
+	date := expense.CreatedAt.In(loc).Format(time.DateOnly)
+	resolution := &DateResolution{Date: date, Source: DateSourceDefault, LLMDate: date, TimeZone: loc.String()}
+	var provider string
+	if audit := s.bookingAudit(ctx, expense.ID); audit != nil {
+		provider = audit.Provider
+		if analysis, err := parseAnalysis(audit.RawOutput, true); err == nil {
+			// 审计记录在模型输出结束时写入，按那一刻重新判定日期来源
+			_, resolution = resolveExpenseTime(job.Description, analysis.Date, audit.CreatedAt.In(loc))
+			resolution.Date = date
+		}
+	}
+	analysis := &model.FaceTaxAnalysis{
+		Amount:   expense.Amount,
+		Date:     resolution.LLMDate,
+		Note:     expense.Note,
+		Comment:  expense.Comment,
+		Category: expense.Category,
+	}
+	return &AnalyzeResult{Expense: expense, Analysis: analysis, ResolvedDate: resolution, Provider: provider}, nil
+}
+
+// searchHistory RAG 检索：查出与描述最相似的 3 条历史，格式化为 Prompt 可用的文本
+// excludeID 不为 0 时排除这笔账单自己的记忆 (重新分析时不能让模型照抄旧结果)
+func (s *ExpenseService) searchHistory(ctx context.Context, userID string, description string, excludeID uint) ([]string, error) {
 	var historyContext []repository.MemoryResult
 	var historyLogs []string
-	queryVector, err := s.embedder.GetVector(ctx, input.Description)
+	queryVector, err := s.embedder.GetVector(ctx, description)
 	if err != nil {
-		slog.Error("Embed failed: %v\n", err)
-		return nil, nil, err
+		slog.Error("Embed failed", "error", err)
+		return nil, err
 	}
-	if similarLogs, err := s.memoryRepo.SearchSimilar(ctx, input.UserID, 3, queryVector); err == nil {
-		historyContext = similarLogs
+	limit := 3
+	if excludeID != 0 {
+		limit++
+	}
+	if similarLogs, err := s.memoryRepo.SearchSimilar(ctx, userID, limit, queryVector); err == nil {
+		for _, m := range similarLogs {
+			if excludeID != 0 && m.ExpenseID == excludeID {
+				continue
+			}
+			// 早先写进去的记忆可能被注入过指令，不能放进这次的 Prompt
+			if pattern := prompt.DetectInjection(m.Content); pattern != "" {
+				slog.Warn("历史记忆疑似提示词注入，已跳过", "uid", userID, "expense", m.ExpenseID, "pattern", pattern)
+				continue
+			}
+			historyContext = append(historyContext, m)
+		}
+		historyContext = historyContext[:min(len(historyContext), 3)]
 	} else {
-		// 记录日志但不报错
-		slog.Error("RAG Search failed: %v\n", err)
-		return nil, nil, err
+		slog.Error("RAG Search failed", "error", err)
+		return nil, err
 	}
 
 	for _, log := range historyContext {
@@ -76,61 +314,207 @@ func (s *ExpenseService) StreamExpense(ctx context.Context, input ExpenseInput)
 		)
 		historyLogs = append(historyLogs, formatted)
 	}
+	return historyLogs, nil
+}
 
-	preDefinedCategories := model.PredefinedCategories
-	enableRoast := true
-	// TODO: 添加用户自定义目录，读取用户是否开启毒舌的设定
-	streamChan, err := s.llmClient.AnalyzeExpense(ctx, input.Description, preDefinedCategories, historyLogs, enableRoast)
+// analyzeOnce 同步跑一次完整的 LLM 分析：检索历史 → 调用模型 → 排空流 → 解析
+// 给不需要 SSE 的场景使用 (如通知导入、批量记账)，now 为用户所在时区的当前时间
+// excludeID 见 searchHistory，新记账传 0；source 为审计记录的来源
+func (s *ExpenseService) analyzeOnce(ctx context.Context, userID string, description string, enableRoast bool, now time.Time, excludeID uint, source string) (*model.FaceTaxAnalysis, *model.AnalysisAudit, error) {
+	historyLogs, err := s.searchHistory(ctx, userID, description, excludeID)
+	if err != nil {
+		// 同步场景下历史只是锦上添花，检索失败不阻断
+		historyLogs = nil
+	}
+	ctx, user := s.withUser(ctx, userID)
+	ctx, enableRoast = s.withStyle(ctx, userID, user, enableRoast)
+	ctx = llm.WithNow(ctx, now)
+	ctx, provider := s.route(ctx, userID, user)
+	// 批量场景会并发调用，每次调用单独挂一个 CallInfo
+	ctx, _ = llm.WithCallInfo(ctx)
+	ctx, trace := newTrace(ctx, source, description, historyLogs, provider.Name())
+	trace.injection = detectInjection(userID, source, description)
+	stream, err := provider.AnalyzeExpense(ctx, description, model.PredefinedCategories, historyLogs, enableRoast)
 	if err != nil {
 		return nil, nil, err
 	}
+	var fullJSONBuilder strings.Builder
+	for fragment := range stream.C {
+		fullJSONBuilder.WriteString(fragment)
+	}
+	if err := stream.Err(); err != nil {
+		return nil, nil, err
+	}
+	// 审计记录由调用方在知道账单 ID 后写入；解析失败时也返回，方便排查
+	audit := trace.audit(userID, 0, fullJSONBuilder.String())
+	analysis, err := parseAnalysis(audit.RawOutput, enableRoast)
+	if err != nil {
+		return nil, audit, err
+	}
+	// 批量预览、支付通知、重新分析的结果和直接记账一样要过输出校验
+	if err := validateAnalysis(analysis, model.PredefinedCategories); err != nil {
+		slog.Warn("模型输出没有通过校验", "uid", userID, "source", source, "error", err)
+		return nil, audit, err
+	}
+	// 这里还没有账单，检查日志不关联账单；审计记录保留模型的原始输出
+	var moderationLogs []*model.ModerationLog
+	analysis.Comment, moderationLogs = s.moderateComment(ctx, userID, source, description, analysis.Comment, true)
+	s.saveModerationLogs(ctx, 0, moderationLogs)
+	return analysis, audit, nil
+}
+
+// parseAnalysis 解析模型输出的 book_expense 参数
+func parseAnalysis(fullJSON string, enableRoast bool) (*model.FaceTaxAnalysis, error) {
+	var analysis model.FaceTaxAnalysis
+	if err := json.Unmarshal([]byte(fullJSON), &analysis); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOutput, err)
+	}
 
-	commitFunc := func(fullJSON string) (*model.ExpenseEntity, error) {
-		var analysis model.FaceTaxAnalysis
-		if err := json.Unmarshal([]byte(fullJSON), &analysis); err != nil {
-			return nil, err
-		}
+	// 强行清洗 comment
+	if !enableRoast {
+		analysis.Comment = ""
+	}
+	return &analysis, nil
+}
 
-		// 强行清洗 comment
-		if !enableRoast {
-			analysis.Comment = ""
-		}
+// saveAnalysis 解析模型输出的 book_expense 参数并落库，随后异步写入向量记忆
+// 用户的原始描述既用于校正日期，也是写进 Qdrant 的检索文本；吐槽没通过检查时会重新生成或替换后再落库
+// 异步任务的 ID 和账单在同一条 INSERT 里写入，任务重跑时据此跳过
+func (s *ExpenseService) saveAnalysis(ctx context.Context, input ExpenseInput, fullJSON string, enableRoast bool) (*model.ExpenseEntity, *DateResolution, error) {
+	// ctx 上已经挂着 StreamExpense 查到的用户资料
+	ctx, user := s.withUser(ctx, input.UserID)
+	now := s.userNow(user, input.TimeZone)
+	entity, resolution, moderationLogs, err := s.prepareEntity(ctx, input.UserID, model.AuditSourceAnalyze, input.Description, fullJSON, enableRoast, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if input.JobID != 0 {
+		entity.AnalysisJobID = &input.JobID
+	}
+	if err := s.createWithMemory(ctx, entity, memoryTextOf(entity)); err != nil {
+		s.saveModerationLogs(ctx, 0, moderationLogs)
+		return nil, nil, fmt.Errorf("%w: %v", ErrSaveFailed, err)
+	}
+	s.saveModerationLogs(ctx, entity.ID, moderationLogs)
+	return entity, resolution, nil
+}
+
+// prepareEntity 解析并校验模型输出，校正日期、检查吐槽，得到待落库的账单；不写库
+// memoryText 为空时 (图片记账) 日期只参考模型的结果
+func (s *ExpenseService) prepareEntity(ctx context.Context, userID string, source string, memoryText string, fullJSON string, enableRoast bool, now time.Time) (*model.ExpenseEntity, *DateResolution, []*model.ModerationLog, error) {
+	analysis, err := parseAnalysis(fullJSON, enableRoast)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := validateAnalysis(analysis, model.PredefinedCategories); err != nil {
+		slog.Warn("模型输出没有通过校验，不落库", "uid", userID, "source", source, "error", err)
+		return nil, nil, nil, err
+	}
 
-		expenseTime, err := time.Parse("2006-01-02 15:04:05", analysis.Date)
+	// 模型的日期只作参考：描述里有明确的日期表达时以规则解析为准
+	expenseTime, resolution := resolveExpenseTime(memoryText, analysis.Date, now)
+	if resolution.LLMDate != resolution.Date {
+		slog.Info("消费日期已校正", "uid", userID, "llm", resolution.LLMDate, "resolved", resolution.Date, "source", resolution.Source, "expr", resolution.Expr)
+	}
+	entity := &model.ExpenseEntity{
+		UserID:      userID,
+		Amount:      analysis.Amount,
+		Category:    analysis.Category,
+		Note:        analysis.Note,
+		CreatedAt:   expenseTime,
+		Comment:     analysis.Comment,
+		Description: memoryText,
+	}
+	var moderationLogs []*model.ModerationLog
+	entity.Comment, moderationLogs = s.moderateComment(ctx, userID, source, memoryTextOf(entity), entity.Comment, true)
+	return entity, resolution, moderationLogs, nil
+}
+
+// memoryTextOf 写进 Qdrant 的检索文本：用户的原始描述，图片记账没有描述，改用识别出的备注
+func memoryTextOf(entity *model.ExpenseEntity) string {
+	if entity.Description != "" {
+		return entity.Description
+	}
+	return entity.Note
+}
+
+// createWithMemory 账单落库，随后异步写入向量记忆；疑似提示词注入的描述不写记忆
+func (s *ExpenseService) createWithMemory(ctx context.Context, entity *model.ExpenseEntity, memoryText string) error {
+	if err := s.repo.Create(ctx, entity); err != nil {
+		return err
+	}
+	if !rememberable(entity.UserID, entity.ID, memoryText) {
+		return nil
+	}
+	go func() {
+		// 创建一个新的 context，因为外面的 ctx 可能会在请求结束时取消
+		bgCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		vector, err := s.embedder.GetVector(bgCtx, memoryText)
 		if err != nil {
-			expenseTime = time.Now() // 解析失败就兜底用当前时间
+			slog.Error("Failed to embed vector", "error", err)
 		}
-		// 实体转换 & 落库
-		entity := &model.ExpenseEntity{
-			UserID:    input.UserID,
-			Amount:    analysis.Amount,
-			Category:  analysis.Category,
-			Note:      analysis.Note,
-			CreatedAt: expenseTime,
-			Comment:   analysis.Comment,
+		if err := s.memoryRepo.SaveMemory(bgCtx, entity.UserID, entity.ID, memoryText, entity.Category, vector); err != nil {
+			slog.Error("Failed to save memory", "error", err)
 		}
+	}()
+	return nil
+}
 
-		if err := s.repo.Create(ctx, entity); err != nil {
-			return nil, err
-		}
-		go func() {
-			// 创建一个新的 context，因为外面的 ctx 可能会在请求结束时取消
-			bgCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
+// StreamReceipt 处理一次小票/支付截图记账
+// 返回的片段按 Index 区分不同的消费条目，commitFunc 接收每个条目拼接好的完整 JSON
+func (s *ExpenseService) StreamReceipt(ctx context.Context, userID string, image llm.ReceiptImage) (*llm.Stream[llm.ToolCallFragment], func(fullJSONs []string) ([]*model.ExpenseEntity, error), error) {
+	if s.visionClient == nil {
+		return nil, nil, fmt.Errorf("未配置多模态模型，无法识别图片")
+	}
+	slog.Info("收到小票识别请求", "uid", userID, "mime", image.MimeType, "size", len(image.Data))
+
+	preDefinedCategories := model.PredefinedCategories
+	ctx, user := s.withUser(ctx, userID)
+	ctx, enableRoast := s.withStyle(ctx, userID, user, true)
+	ctx = llm.WithNow(ctx, s.userNow(user, ""))
+	ctx = s.withPrompt(ctx, user)
+	ctx, trace := newTrace(ctx, model.AuditSourceReceipt, "", nil, "vision")
+	stream, err := s.visionClient.AnalyzeReceipt(ctx, image, preDefinedCategories, enableRoast)
+	if err != nil {
+		return nil, nil, err
+	}
 
-			vector, err := s.embedder.GetVector(bgCtx, input.Description)
+	// 一张小票的几笔消费在一个事务里落库，任何一笔没通过校验都不保存
+	commitFunc := func(fullJSONs []string) ([]*model.ExpenseEntity, error) {
+		now := llm.Now(ctx)
+		entities := make([]*model.ExpenseEntity, len(fullJSONs))
+		moderationLogs := make([][]*model.ModerationLog, len(fullJSONs))
+		for i, fullJSON := range fullJSONs {
+			entity, _, logs, err := s.prepareEntity(ctx, userID, model.AuditSourceReceipt, "", fullJSON, enableRoast, now)
 			if err != nil {
-				slog.Error("Failed to embed vector", "error", err)
+				for _, raw := range fullJSONs {
+					s.saveAudit(ctx, trace.audit(userID, 0, raw))
+				}
+				return nil, fmt.Errorf("第 %d 笔消费识别失败: %w", i+1, err)
 			}
-			if err := s.memoryRepo.SaveMemory(bgCtx, input.UserID, entity.ID, input.Description, analysis.Category, vector); err != nil {
-				slog.Error("Failed to save memory", "error", err)
+			entities[i], moderationLogs[i] = entity, logs
+		}
+		if err := s.repo.CreateBatch(ctx, entities); err != nil {
+			for _, raw := range fullJSONs {
+				s.saveAudit(ctx, trace.audit(userID, 0, raw))
 			}
-		}()
+			return nil, fmt.Errorf("%w: %v", ErrSaveFailed, err)
+		}
 
-		return entity, nil
+		texts := make([]string, len(entities))
+		for i, entity := range entities {
+			texts[i] = memoryTextOf(entity)
+			s.saveModerationLogs(ctx, entity.ID, moderationLogs[i])
+			audit := trace.audit(userID, entity.ID, fullJSONs[i])
+			s.saveAudit(ctx, audit)
+			s.recordRoast(ctx, entity, audit)
+		}
+		go saveMemories(s.embedder, s.memoryRepo, userID, entities, texts)
+		return entities, nil
 	}
-
-	return streamChan, commitFunc, nil
+	return stream, commitFunc, nil
 }
 
 func formatTimeAgo(timestamp int64) string {
@@ -198,6 +582,18 @@ func (s *ExpenseService) UpdateExpense(ctx context.Context, userID string, expen
 		return fmt.Errorf("无权操作此账单")
 	}
 
+	// 和模型识别的结果比，用户改了哪些字段
+	var corrected []string
+	if len(category) > 0 && category != existing.Category {
+		corrected = append(corrected, "category")
+	}
+	if amount > 0 && amount != existing.Amount {
+		corrected = append(corrected, "amount")
+	}
+	if len(note) > 0 && note != existing.Note {
+		corrected = append(corrected, "note")
+	}
+
 	// 更新字段
 	if len(category) > 0 {
 		existing.Category = category
@@ -214,11 +610,14 @@ func (s *ExpenseService) UpdateExpense(ctx context.Context, userID string, expen
 	if err != nil {
 		return err
 	}
+	s.markCorrected(ctx, existing.ID, corrected)
 
+	// 1. 重新生成文本
+	newContent := fmt.Sprintf("消费: %s, 金额: %.2f, 备注: %s", category, amount, note)
+	if !rememberable(userID, existing.ID, newContent) {
+		return nil
+	}
 	go func() {
-		// 1. 重新生成文本
-		newContent := fmt.Sprintf("消费: %s, 金额: %.2f, 备注: %s", category, amount, note)
-
 		// 2. 重新 Embedding (这一步可能耗时，所以放协程)
 		vec, err := s.embedder.GetVector(context.Background(), newContent)
 		if err != nil {
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/calendar"
+)
+
+// 消费日期的来源
+const (
+	DateSourceText    = "text"    // 描述里有明确的日期表达，按规则解析，忽略模型的结果
+	DateSourceLLM     = "llm"     // 描述里没有规则能识别的日期，采用模型推断的日期
+	DateSourceDefault = "default" // 都没有 (或模型给的日期不可信)，按今天处理
+)
+
+// 模型推断的日期最多往前信任一年，更早的多半是幻觉
+const maxLLMDateAge = 366 * 24 * time.Hour
+
+// 模型输出日期可能出现的格式，工具定义要求 YYYY-MM-DD，其余为兼容
+var llmDateLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", "2006/01/02", time.RFC3339}
+
+// DateResolution 消费日期的判定过程，随 SSE done 事件返回给前端展示
+type DateResolution struct {
+	Date     string `json:"date"`               // 最终采用的日期 YYYY-MM-DD
+	Source   string `json:"source"`             // text / llm / default
+	Expr     string `json:"expr,omitempty"`     // 描述里命中的日期表达，例如 "上周五"、"除夕"
+	LLMDate  string `json:"llm_date,omitempty"` // 模型给出的原始日期
+	TimeZone string `json:"timezone"`           // 计算所用的时区
+}
+
+// resolveExpenseTime 确定消费时间：描述里不在未来的日期表达优先，其次是校验通过的模型日期，最后是今天
+// now 必须已经转换到用户所在时区，"昨天" 是用户的昨天而不是服务器的昨天
+func resolveExpenseTime(description string, llmDate string, now time.Time) (time.Time, *DateResolution) {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	r := &DateResolution{LLMDate: llmDate, TimeZone: loc.String(), Source: DateSourceDefault}
+
+	day := today
+	// "这周日" 这类说法可能落在未来，记账不能记到未来，这时退回模型的日期
+	if m, ok := calendar.Resolve(description, now); ok && !m.Date.After(today) {
+		day, r.Source, r.Expr = m.Date, DateSourceText, m.Expr
+	} else if t, ok := parseLLMDate(llmDate, loc); ok && !t.After(today) && today.Sub(t) <= maxLLMDateAge {
+		day, r.Source = t, DateSourceLLM
+	}
+	r.Date = day.Format("2006-01-02")
+
+	if day.Equal(today) {
+		return now, r
+	}
+	// 只知道是哪一天，时分秒沿用当前时刻，保证同一天内的先后顺序和记账顺序一致
+	return time.Date(day.Year(), day.Month(), day.Day(), now.Hour(), now.Minute(), now.Second(), 0, loc), r
+}
+
+// parseLLMDate 解析模型给出的日期，只保留日期部分
+func parseLLMDate(s string, loc *time.Location) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range llmDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			t = t.In(loc)
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc), true
+		}
+	}
+	return time.Time{}, false
+}
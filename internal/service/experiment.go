@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrExperimentNotFound 实验不存在
+	ErrExperimentNotFound = errors.New("实验不存在")
+	// ErrInvalidExperiment 实验配置不合法
+	ErrInvalidExperiment = errors.New("实验配置不合法")
+)
+
+// ExperimentService Prompt / 模型的 A/B 实验：给用户分组、汇总各组的效果
+// 管理员单独指定了 Prompt 版本的用户不参与实验；图片记账走多模态模型，也不参与
+type ExperimentService struct {
+	repo      repository.ExperimentRepo
+	audits    repository.AnalysisAuditRepo
+	prompts   *PromptService
+	providers map[string]llm.Provider // llm.providers 里配置的后端，按名字 (小写) 索引
+}
+
+// NewExperimentService 构造函数
+func NewExperimentService(repo repository.ExperimentRepo, audits repository.AnalysisAuditRepo, prompts *PromptService, providers map[string]llm.Provider) *ExperimentService {
+	return &ExperimentService{repo: repo, audits: audits, prompts: prompts, providers: providers}
+}
+
+// Assignment 用户在实验中分到的组；Template / Provider 为空的沿用平时的配置
+type Assignment struct {
+	Experiment string
+	Variant    string
+	Template   *prompt.Template
+	Provider   llm.Provider
+}
+
+type assignmentKey struct{}
+
+// withAssignment 把分组挂到 ctx 上，写审计记录时带上
+func withAssignment(ctx context.Context, a *Assignment) context.Context {
+	return context.WithValue(ctx, assignmentKey{}, a)
+}
+
+func assignmentFrom(ctx context.Context) *Assignment {
+	a, _ := ctx.Value(assignmentKey{}).(*Assignment)
+	return a
+}
+
+// Assign 用户在当前实验中分到的组，没有进行中的实验或用户不参与时返回 nil
+// 同一个用户在同一个实验里总是分到同一组；user 为这次请求已经查到的用户资料，可能为空
+func (s *ExperimentService) Assign(ctx context.Context, userID string, user *model.User) *Assignment {
+	exp, err := s.repo.Running(ctx)
+	if err != nil {
+		slog.Error("查询进行中的实验失败", "error", err)
+		return nil
+	}
+	if exp == nil || len(exp.Variants) == 0 {
+		return nil
+	}
+	if s.prompts != nil && s.prompts.pinned(user) != nil {
+		return nil
+	}
+
+	v := pickVariant(exp.Name, userID, exp.Variants)
+	a := &Assignment{Experiment: exp.Name, Variant: v.Name}
+	if v.PromptVersion != "" && s.prompts != nil {
+		if t, ok := s.prompts.store.Get(v.PromptVersion); ok {
+			a.Template = t
+		} else {
+			slog.Warn("实验分组的 Prompt 版本不存在，使用默认版本", "experiment", exp.Name, "variant", v.Name, "version", v.PromptVersion)
+		}
+	}
+	if v.Provider != "" {
+		if p, ok := s.providers[strings.ToLower(v.Provider)]; ok {
+			a.Provider = p
+		} else {
+			slog.Warn("实验分组的模型后端不存在，使用默认后端", "experiment", exp.Name, "variant", v.Name, "provider", v.Provider)
+		}
+	}
+	return a
+}
+
+// pickVariant 按 实验名 + 用户 ID 的哈希和权重选组，换一个实验会重新打散
+func pickVariant(experiment string, userID string, variants []model.ExperimentVariant) model.ExperimentVariant {
+	total := 0
+	for _, v := range variants {
+		total += variantWeight(v)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(experiment + ":" + userID))
+	n := int(h.Sum32() % uint32(total))
+	for _, v := range variants {
+		n -= variantWeight(v)
+		if n < 0 {
+			return v
+		}
+	}
+	return variants[len(variants)-1]
+}
+
+func variantWeight(v model.ExperimentVariant) int {
+	if v.Weight <= 0 {
+		return 1
+	}
+	return v.Weight
+}
+
+// ExperimentInput 创建实验的参数
+type ExperimentInput struct {
+	Name        string                    `json:"name" binding:"required"`
+	Description string                    `json:"description"`
+	Variants    []model.ExperimentVariant `json:"variants" binding:"required"`
+}
+
+// Create 创建实验，创建后是草稿状态，需要再调用 Start 开始分流
+func (s *ExperimentService) Create(ctx context.Context, input ExperimentInput) (*model.Experiment, error) {
+	if err := s.validate(input); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(input.Name)
+	// 实验名会写进审计记录用来汇总，不能重复
+	if _, err := s.repo.GetByName(ctx, name); err == nil {
+		return nil, fmt.Errorf("%w: 实验 %s 已存在", ErrInvalidExperiment, name)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	exp := &model.Experiment{
+		Name:        name,
+		Description: input.Description,
+		Status:      model.ExperimentDraft,
+		Variants:    input.Variants,
+	}
+	if err := s.repo.Create(ctx, exp); err != nil {
+		return nil, err
+	}
+	return exp, nil
+}
+
+func (s *ExperimentService) validate(input ExperimentInput) error {
+	if strings.TrimSpace(input.Name) == "" {
+		return fmt.Errorf("%w: 缺少实验名", ErrInvalidExperiment)
+	}
+	if len(input.Variants) < 2 {
+		return fmt.Errorf("%w: 至少需要两个分组", ErrInvalidExperiment)
+	}
+	seen := make(map[string]bool, len(input.Variants))
+	for _, v := range input.Variants {
+		if v.Name == "" {
+			return fmt.Errorf("%w: 分组缺少名字", ErrInvalidExperiment)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%w: 分组 %s 重名", ErrInvalidExperiment, v.Name)
+		}
+		seen[v.Name] = true
+		if v.Weight < 0 {
+			return fmt.Errorf("%w: 分组 %s 的权重不能为负", ErrInvalidExperiment, v.Name)
+		}
+		if v.PromptVersion != "" {
+			if s.prompts == nil {
+				return fmt.Errorf("%w: 没有启用 Prompt 模板", ErrInvalidExperiment)
+			}
+			if _, ok := s.prompts.store.Get(v.PromptVersion); !ok {
+				return fmt.Errorf("%w: 分组 %s 的 Prompt 版本 %s 不存在", ErrInvalidExperiment, v.Name, v.PromptVersion)
+			}
+		}
+		if v.Provider != "" {
+			if _, ok := s.providers[strings.ToLower(v.Provider)]; !ok {
+				return fmt.Errorf("%w: 分组 %s 的模型后端 %s 不存在", ErrInvalidExperiment, v.Name, v.Provider)
+			}
+		}
+	}
+	return nil
+}
+
+// List 所有实验，最新的在前
+func (s *ExperimentService) List(ctx context.Context) ([]model.Experiment, error) {
+	return s.repo.List(ctx)
+}
+
+// Start 开始分流；同一时间只有一个实验在进行，其他进行中的会被停止
+// 停止后重新开始的实验沿用原来的分组，数据累计
+func (s *ExperimentService) Start(ctx context.Context, id uint) error {
+	if _, err := s.get(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Start(ctx, id, time.Now())
+}
+
+// Stop 停止分流，之后的记账回到平时的配置
+func (s *ExperimentService) Stop(ctx context.Context, id uint) error {
+	if _, err := s.get(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Stop(ctx, id, time.Now())
+}
+
+func (s *ExperimentService) get(ctx context.Context, id uint) (*model.Experiment, error) {
+	exp, err := s.repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExperimentNotFound
+	}
+	return exp, err
+}
+
+// VariantReport 一个分组的效果
+type VariantReport struct {
+	Variant        model.ExperimentVariant `json:"variant"`
+	Stats          repository.VariantStats `json:"stats"`
+	CorrectionRate float64                 `json:"correction_rate"` // 落库的账单中被用户修改过的比例
+}
+
+// ExperimentReport 实验报表
+type ExperimentReport struct {
+	Experiment *model.Experiment `json:"experiment"`
+	Variants   []VariantReport   `json:"variants"`
+}
+
+// Report 按分组对比调用量、修改率、耗时和 token 用量
+func (s *ExperimentService) Report(ctx context.Context, id uint) (*ExperimentReport, error) {
+	exp, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.audits.VariantStats(ctx, exp.Name)
+	if err != nil {
+		return nil, err
+	}
+	byVariant := make(map[string]repository.VariantStats, len(stats))
+	for _, st := range stats {
+		byVariant[st.Variant] = st
+	}
+
+	report := &ExperimentReport{Experiment: exp, Variants: make([]VariantReport, 0, len(exp.Variants))}
+	for _, v := range exp.Variants {
+		st, ok := byVariant[v.Name]
+		if !ok {
+			st = repository.VariantStats{Variant: v.Name}
+		}
+		vr := VariantReport{Variant: v, Stats: st}
+		if st.Expenses > 0 {
+			vr.CorrectionRate = float64(st.Corrected) / float64(st.Expenses)
+		}
+		report.Variants = append(report.Variants, vr)
+	}
+	return report, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/ledger"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"github.com/leon37/FaceTaxLedger/internal/sheet"
+)
+
+// ExportService 账单导出
+type ExportService struct {
+	repo   repository.ExpenseRepo
+	scheme ledger.AccountScheme
+	users  *UserService
+}
+
+// NewExportService 构造函数，scheme 为默认的账户命名规则
+func NewExportService(repo repository.ExpenseRepo, scheme ledger.AccountScheme, users *UserService) *ExportService {
+	return &ExportService{repo: repo, scheme: scheme, users: users}
+}
+
+// Location 用户所在时区，筛选的日期边界和导出的日期都按它计算
+func (s *ExportService) Location(ctx context.Context, userID string) *time.Location {
+	return s.users.Location(ctx, userID)
+}
+
+// Scheme 默认的账户命名规则，调用方可以在此基础上按请求覆盖
+func (s *ExportService) Scheme() ledger.AccountScheme {
+	return s.scheme
+}
+
+// ExportJournal 把筛选出的账单渲染成 Beancount / hledger 日记账，边查边写
+func (s *ExportService) ExportJournal(ctx context.Context, w io.Writer, filter repository.ExpenseFilter, format string, scheme ledger.AccountScheme) error {
+	jw, err := ledger.NewWriter(w, format, scheme, s.Location(ctx, filter.UserID))
+	if err != nil {
+		return err
+	}
+
+	// 1. 先汇总用到的账户，生成开户指令
+	usages, err := s.repo.CategoryUsages(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("统计账户失败: %w", err)
+	}
+	accounts := make([]ledger.AccountUsage, len(usages))
+	for i, u := range usages {
+		accounts[i] = ledger.AccountUsage{Category: u.Category, Source: u.Source, FirstAt: u.FirstAt}
+	}
+	if err := jw.WriteHeader(accounts); err != nil {
+		return err
+	}
+
+	// 2. 分批写交易
+	count := 0
+	err = s.repo.Each(ctx, filter, func(batch []model.ExpenseEntity) error {
+		for i := range batch {
+			if err := jw.WriteExpense(&batch[i]); err != nil {
+				return err
+			}
+		}
+		count += len(batch)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("读取账单失败: %w", err)
+	}
+	if err := jw.Flush(); err != nil {
+		return err
+	}
+
+	slog.Info("日记账导出完成", "uid", filter.UserID, "format", format, "count", count)
+	return nil
+}
+
+// 表格导出的列
+var tableHeader = []any{"日期", "分类", "金额", "备注", "吐槽"}
+
+// ExportTable 把筛选出的全部账单导出为 CSV / XLSX，边查边写，不受分页限制
+func (s *ExportService) ExportTable(ctx context.Context, w io.Writer, filter repository.ExpenseFilter, format string) error {
+	rw, err := sheet.NewWriter(w, format, "账单")
+	if err != nil {
+		return err
+	}
+	if err := rw.WriteRow(tableHeader...); err != nil {
+		return err
+	}
+
+	loc := s.Location(ctx, filter.UserID)
+	count := 0
+	err = s.repo.Each(ctx, filter, func(batch []model.ExpenseEntity) error {
+		for _, e := range batch {
+			if err := rw.WriteRow(e.CreatedAt.In(loc), e.Category, e.Amount, e.Note, e.Comment); err != nil {
+				return err
+			}
+		}
+		count += len(batch)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("读取账单失败: %w", err)
+	}
+	if err := rw.Close(); err != nil {
+		return err
+	}
+
+	slog.Info("表格导出完成", "uid", filter.UserID, "format", format, "count", count)
+	return nil
+}
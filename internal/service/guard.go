@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+)
+
+const (
+	maxExpenseAmount  = 1000000 // 单笔账单的金额上限，手动确认 (批量、重新分析) 和模型输出都按它校验
+	maxNoteRunes      = 200     // 备注长度上限，模型输出和用户确认的提交都按它校验
+	maxCommentRunes   = 500     // 吐槽长度上限，模型输出和用户确认的提交都按它校验
+	injectionLogRunes = 80      // 日志里记录的描述长度
+)
+
+// validateAnalysis 模型输出落库前的严格校验：金额在合理范围内、分类在可选列表里、文本长度正常
+// 描述里被注入了指令时，模型可能给出离谱的金额或编造的分类，宁可报 invalid_output 也不落库；金额按分取整
+func validateAnalysis(analysis *model.FaceTaxAnalysis, categories []string) error {
+	switch {
+	case math.IsNaN(analysis.Amount) || math.IsInf(analysis.Amount, 0) || analysis.Amount <= 0:
+		return fmt.Errorf("%w: 没有识别到金额", ErrInvalidOutput)
+	case analysis.Amount > maxExpenseAmount:
+		return fmt.Errorf("%w: 金额 %.2f 超出上限 %d", ErrInvalidOutput, analysis.Amount, maxExpenseAmount)
+	case !slices.Contains(categories, analysis.Category):
+		return fmt.Errorf("%w: 分类不在可选列表里: %q", ErrInvalidOutput, analysis.Category)
+	case utf8.RuneCountInString(analysis.Note) > maxNoteRunes:
+		return fmt.Errorf("%w: 备注过长", ErrInvalidOutput)
+	case utf8.RuneCountInString(analysis.Comment) > maxCommentRunes:
+		return fmt.Errorf("%w: 吐槽过长", ErrInvalidOutput)
+	}
+	analysis.Amount = math.Round(analysis.Amount*100) / 100
+	return nil
+}
+
+// checkTextLength 用户确认提交的备注和吐槽 (批量记账、重新分析) 按模型输出同样的上限校验
+func checkTextLength(note string, comment string) error {
+	switch {
+	case utf8.RuneCountInString(note) > maxNoteRunes:
+		return fmt.Errorf("备注超过 %d 字", maxNoteRunes)
+	case utf8.RuneCountInString(comment) > maxCommentRunes:
+		return fmt.Errorf("吐槽超过 %d 字", maxCommentRunes)
+	}
+	return nil
+}
+
+// rememberable 这段文本能否写进向量记忆：疑似提示词注入的不写，否则会被检索出来放进以后的 Prompt
+func rememberable(userID string, expenseID uint, text string) bool {
+	if pattern := prompt.DetectInjection(text); pattern != "" {
+		slog.Warn("描述疑似提示词注入，不写入向量记忆", "uid", userID, "expense", expenseID, "pattern", pattern, "text", truncateRunes(text, injectionLogRunes))
+		return false
+	}
+	return true
+}
+
+// detectInjection 记账描述疑似提示词注入时记一条日志，返回命中的特征名；本次识别照常进行，由输出校验兜底
+func detectInjection(userID string, source string, description string) string {
+	pattern := prompt.DetectInjection(description)
+	if pattern != "" {
+		slog.Warn("记账描述疑似提示词注入", "uid", userID, "source", source, "pattern", pattern, "text", truncateRunes(description, injectionLogRunes))
+	}
+	return pattern
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/importer"
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/embedding"
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+)
+
+const (
+	// 每次送给 LLM 批量分类的条数
+	classifyBatchSize = 40
+	// 每次批量生成向量/写入 Qdrant 的条数
+	memoryBatchSize = 100
+)
+
+// 导入条目状态
+const (
+	ImportStatusNew       = "new"       // dry-run 预览：将会导入
+	ImportStatusImported  = "imported"  // 已导入
+	ImportStatusDuplicate = "duplicate" // 重复，跳过
+	ImportStatusSkipped   = "skipped"   // 非支出或交易未成功，跳过
+)
+
+// 分类来源
+const (
+	CategoryByRule     = "rule"
+	CategoryByLLM      = "llm"
+	CategoryByFallback = "fallback"
+)
+
+// ImportItem 导入报告中的单行
+type ImportItem struct {
+	Row            int       `json:"row"`
+	Time           time.Time `json:"time"`
+	Amount         float64   `json:"amount"`
+	Counterparty   string    `json:"counterparty"`
+	Description    string    `json:"description"`
+	Category       string    `json:"category,omitempty"`
+	CategorySource string    `json:"category_source,omitempty"` // rule / llm / fallback
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason,omitempty"`
+	ExpenseID      uint      `json:"expense_id,omitempty"`
+}
+
+// ImportReport 导入报告，dry-run 时同样返回，只是不落库
+type ImportReport struct {
+	Source     string       `json:"source"` // alipay / wechat
+	DryRun     bool         `json:"dry_run"`
+	Total      int          `json:"total"`
+	Imported   int          `json:"imported"`
+	Duplicates int          `json:"duplicates"`
+	Skipped    int          `json:"skipped"`
+	ByRule     int          `json:"by_rule"`
+	ByLLM      int          `json:"by_llm"`
+	ByFallback int          `json:"by_fallback"`
+	Items      []ImportItem `json:"items"`
+}
+
+// ImportService 第三方账单 / 银行流水导入
+type ImportService struct {
+	repo       repository.ExpenseRepo
+	importRepo repository.ImportRepo
+	memoryRepo repository.MemoryRepo
+	embedder   embedding.Provider
+	classifier llm.Classifier
+	mapper     *importer.CategoryMapper
+	users      *UserService // 账单里没有时区的时间按用户所在时区解释
+
+	workers chan struct{} // 限制同时运行的异步批次数
+	running sync.Map      // 正在运行的批次 ID
+}
+
+// NewImportService 构造函数
+func NewImportService(repo repository.ExpenseRepo, importRepo repository.ImportRepo, memoryRepo repository.MemoryRepo, embedder embedding.Provider, classifier llm.Classifier, mapper *importer.CategoryMapper, users *UserService) *ImportService {
+	return &ImportService{
+		repo:       repo,
+		importRepo: importRepo,
+		memoryRepo: memoryRepo,
+		embedder:   embedder,
+		classifier: classifier,
+		mapper:     mapper,
+		users:      users,
+		workers:    make(chan struct{}, statementWorkers),
+	}
+}
+
+// ImportBill 导入支付宝/微信支付账单 CSV
+// 流程：解析 → 过滤非支出 → 去重 → 规则分类 → LLM 批量分类 → 落库 → 异步写记忆
+func (s *ImportService) ImportBill(ctx context.Context, userID string, data []byte, dryRun bool) (*ImportReport, error) {
+	bill, err := importer.ParseBill(data, s.users.Location(ctx, userID))
+	if err != nil {
+		return nil, err
+	}
+	report := &ImportReport{Source: bill.Source, DryRun: dryRun, Total: len(bill.Records)}
+	slog.Info("收到账单导入请求", "uid", userID, "source", bill.Source, "rows", len(bill.Records), "dryRun", dryRun)
+
+	// 1. 过滤 & 收集待去重的单号
+	var candidates []importer.Record
+	var tradeNos []string
+	for _, rec := range bill.Records {
+		if reason := rec.SkipReason(); reason != "" {
+			report.Items = append(report.Items, newImportItem(rec, ImportStatusSkipped, reason))
+			report.Skipped++
+			continue
+		}
+		candidates = append(candidates, rec)
+		if rec.TradeNo != "" {
+			tradeNos = append(tradeNos, rec.TradeNo)
+		}
+	}
+
+	// 2. 去重：库里已有的 + 同一文件里重复的
+	existing, err := s.repo.ExistingExternalIDs(ctx, userID, bill.Source, tradeNos)
+	if err != nil {
+		return nil, fmt.Errorf("查询已导入记录失败: %w", err)
+	}
+	var fresh []importer.Record
+	for _, rec := range candidates {
+		if rec.TradeNo != "" && existing[rec.TradeNo] {
+			report.Items = append(report.Items, newImportItem(rec, ImportStatusDuplicate, "交易单号已导入"))
+			report.Duplicates++
+			continue
+		}
+		if rec.TradeNo != "" {
+			existing[rec.TradeNo] = true
+		}
+		fresh = append(fresh, rec)
+	}
+
+	// 3. 分类
+	items := s.categorize(ctx, fresh)
+	for _, item := range items {
+		switch item.CategorySource {
+		case CategoryByRule:
+			report.ByRule++
+		case CategoryByLLM:
+			report.ByLLM++
+		default:
+			report.ByFallback++
+		}
+	}
+
+	if dryRun {
+		for i := range items {
+			items[i].Status = ImportStatusNew
+		}
+		report.Items = append(report.Items, items...)
+		return report, nil
+	}
+
+	// 4. 批量落库 (单事务)
+	entities := make([]*model.ExpenseEntity, len(fresh))
+	for i, rec := range fresh {
+		entities[i] = &model.ExpenseEntity{
+			UserID:     userID,
+			Amount:     rec.Amount,
+			Category:   items[i].Category,
+			Note:       billNote(rec),
+			CreatedAt:  rec.Time,
+			Source:     rec.Source,
+			ExternalID: rec.TradeNo,
+		}
+	}
+	if err := s.repo.CreateBatch(ctx, entities); err != nil {
+		return nil, fmt.Errorf("批量保存失败: %w", err)
+	}
+	for i := range items {
+		items[i].Status = ImportStatusImported
+		items[i].ExpenseID = entities[i].ID
+	}
+	report.Items = append(report.Items, items...)
+	report.Imported = len(entities)
+
+	// 5. 记忆写入比较慢，放到后台
+	go s.saveMemories(userID, entities)
+
+	slog.Info("账单导入完成", "uid", userID, "imported", report.Imported, "duplicates", report.Duplicates, "skipped", report.Skipped)
+	return report, nil
+}
+
+// categorize 规则优先，规则命不中的按商户去重后交给 LLM 批量分类
+func (s *ImportService) categorize(ctx context.Context, records []importer.Record) []ImportItem {
+	items := make([]ImportItem, len(records))
+	pending := map[string][]int{} // 描述 -> 行下标，相同商户只问一次
+	var keys []string
+	for i, rec := range records {
+		items[i] = newImportItem(rec, "", "")
+		if category, ok := s.mapper.Map(rec.Counterparty, rec.Description, rec.PlatformCategory); ok {
+			items[i].Category, items[i].CategorySource = category, CategoryByRule
+			continue
+		}
+		key := strings.TrimSpace(rec.Counterparty + " " + rec.Description)
+		if _, ok := pending[key]; !ok {
+			keys = append(keys, key)
+		}
+		pending[key] = append(pending[key], i)
+	}
+
+	for start := 0; start < len(keys); start += classifyBatchSize {
+		batch := keys[start:min(start+classifyBatchSize, len(keys))]
+		var categories []string
+		if s.classifier != nil {
+			var err error
+			categories, err = s.classifier.ClassifyBatch(ctx, batch, model.PredefinedCategories)
+			if err != nil {
+				slog.Warn("LLM 批量分类失败，使用兜底分类", "size", len(batch), "error", err)
+				categories = nil
+			}
+		}
+		for j, key := range batch {
+			category, source := fallbackCategory, CategoryByFallback
+			if j < len(categories) && categories[j] != "" {
+				category, source = categories[j], CategoryByLLM
+			}
+			for _, i := range pending[key] {
+				items[i].Category, items[i].CategorySource = category, source
+			}
+		}
+	}
+	return items
+}
+
+// saveMemories 批量生成向量并写入 Qdrant，失败只记日志
+func (s *ImportService) saveMemories(userID string, entities []*model.ExpenseEntity) {
+	texts := make([]string, len(entities))
+	for i, e := range entities {
+		texts[i] = e.Note
+	}
+	saveMemories(s.embedder, s.memoryRepo, userID, entities, texts)
+}
+
+// saveMemories 分批生成向量并写入 Qdrant，失败只记日志
+// texts 与 entities 一一对应，是写进向量库的检索文本
+func saveMemories(embedder embedding.Provider, memoryRepo repository.MemoryRepo, userID string, entities []*model.ExpenseEntity, texts []string) {
+	// 疑似提示词注入的描述不写记忆
+	kept, keptTexts := entities[:0:0], texts[:0:0]
+	for i, e := range entities {
+		if rememberable(userID, e.ID, texts[i]) {
+			kept, keptTexts = append(kept, e), append(keptTexts, texts[i])
+		}
+	}
+	entities, texts = kept, keptTexts
+	for start := 0; start < len(entities); start += memoryBatchSize {
+		end := min(start+memoryBatchSize, len(entities))
+		batch, batchTexts := entities[start:end], texts[start:end]
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		vectors, err := embedder.GetVectors(ctx, batchTexts)
+		if err != nil {
+			cancel()
+			slog.Error("记忆向量生成失败", "uid", userID, "error", err)
+			continue
+		}
+		memories := make([]repository.MemoryItem, len(batch))
+		for i, e := range batch {
+			memories[i] = repository.MemoryItem{
+				ExpenseID:   e.ID,
+				Description: batchTexts[i],
+				Category:    e.Category,
+				Vector:      vectors[i],
+				Timestamp:   e.CreatedAt.Unix(),
+			}
+		}
+		if err := memoryRepo.SaveMemories(ctx, userID, memories); err != nil {
+			slog.Error("记忆写入失败", "uid", userID, "error", err)
+		}
+		cancel()
+	}
+}
+
+func newImportItem(rec importer.Record, status string, reason string) ImportItem {
+	return ImportItem{
+		Row:          rec.Row,
+		Time:         rec.Time,
+		Amount:       rec.Amount,
+		Counterparty: rec.Counterparty,
+		Description:  rec.Description,
+		Status:       status,
+		Reason:       reason,
+	}
+}
+
+// billNote 账单没有用户手写的描述，用交易对方 + 商品说明拼一个备注
+func billNote(rec importer.Record) string {
+	desc := strings.Trim(rec.Description, "/ ")
+	if desc == "" || desc == rec.Counterparty {
+		return rec.Counterparty
+	}
+	if rec.Counterparty == "" {
+		return desc
+	}
+	return rec.Counterparty + " " + desc
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/moderation"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+)
+
+// defaultModerationReplacement 重新生成也没通过时写进账单的兜底文案
+const defaultModerationReplacement = "这笔先记下了。花钱图个开心，也记得量力而行。"
+
+// ModerationConfig 吐槽没通过检查时的处理，留空使用默认值
+type ModerationConfig struct {
+	RegenerateAttempts int    // 重新生成的次数，默认 1，负数表示不重新生成
+	Replacement        string // 兜底文案
+}
+
+// ModerationService 吐槽落库前的内容检查：屏蔽词 + 可选的外部分类器
+// 没通过的先让模型重新生成，仍没通过就换成兜底文案，每条没通过的吐槽都记日志
+// SSE 记账时吐槽不随模型输出流式推送，等这里检查完、落库后才推给前端
+type ModerationService struct {
+	checker     *moderation.Checker
+	logs        repository.ModerationLogRepo
+	attempts    int
+	replacement string
+}
+
+// NewModerationService 构造函数
+func NewModerationService(checker *moderation.Checker, logs repository.ModerationLogRepo, cfg ModerationConfig) *ModerationService {
+	attempts := cfg.RegenerateAttempts
+	switch {
+	case attempts == 0:
+		attempts = 1
+	case attempts < 0:
+		attempts = 0
+	}
+	replacement := strings.TrimSpace(cfg.Replacement)
+	if replacement == "" {
+		replacement = defaultModerationReplacement
+	}
+	return &ModerationService{checker: checker, logs: logs, attempts: attempts, replacement: replacement}
+}
+
+// regenerateFunc 重新生成一条吐槽，flagged 是之前没通过的几条，新的吐槽要避开
+type regenerateFunc func(ctx context.Context, flagged []string) (string, error)
+
+// review 检查吐槽，返回最终可以落库的吐槽和没通过的记录 (还没写库)；regenerate 为空时不通过就直接替换
+func (s *ModerationService) review(ctx context.Context, userID string, source string, persona string, comment string, regenerate regenerateFunc) (string, []*model.ModerationLog) {
+	var logs []*model.ModerationLog
+	var flagged []string
+	candidate := comment
+	for attempt := 0; ; attempt++ {
+		v := s.checker.Check(ctx, candidate)
+		if !v.Flagged {
+			break
+		}
+		entry := &model.ModerationLog{
+			UserID:     userID,
+			Source:     source,
+			Persona:    persona,
+			Comment:    candidate,
+			Category:   v.Category,
+			Reason:     v.Reason,
+			Classifier: v.Classifier,
+			Action:     model.ModerationReplace,
+		}
+		logs = append(logs, entry)
+		flagged = append(flagged, candidate)
+		slog.Warn("吐槽没通过内容检查", "uid", userID, "source", source, "category", v.Category, "classifier", v.Classifier, "attempt", attempt)
+
+		if regenerate == nil || attempt >= s.attempts {
+			candidate = s.replacement
+			break
+		}
+		next, err := regenerate(ctx, flagged)
+		if err != nil {
+			slog.Warn("重新生成吐槽失败，使用兜底文案", "uid", userID, "error", err)
+			candidate = s.replacement
+			break
+		}
+		entry.Action = model.ModerationRegenerate
+		candidate = next
+	}
+	for _, l := range logs {
+		l.Final = candidate
+	}
+	return candidate, logs
+}
+
+// save 写入检查日志，expenseID 为 0 表示没有落库；只用于事后排查，失败不影响记账
+func (s *ModerationService) save(ctx context.Context, expenseID uint, logs []*model.ModerationLog) {
+	if len(logs) == 0 {
+		return
+	}
+	for _, l := range logs {
+		l.ExpenseID = expenseID
+	}
+	if err := s.logs.CreateBatch(context.WithoutCancel(ctx), logs); err != nil {
+		slog.Error("写入内容检查日志失败", "uid", logs[0].UserID, "expense", expenseID, "error", err)
+	}
+}
+
+// List 管理员查看检查日志，最新的在前
+func (s *ModerationService) List(ctx context.Context, filter repository.ModerationLogFilter) ([]model.ModerationLog, int64, error) {
+	return s.logs.List(ctx, filter)
+}
+
+// moderateComment 检查模型生成的吐槽，返回可以落库的吐槽和要写的日志
+// description 是重新生成时交给模型的描述；regenerate 为 false 时不调用模型，不通过直接替换
+func (s *ExpenseService) moderateComment(ctx context.Context, userID string, source string, description string, comment string, regenerate bool) (string, []*model.ModerationLog) {
+	if s.moderation == nil || comment == "" {
+		return comment, nil
+	}
+	var regen regenerateFunc
+	if regenerate && description != "" {
+		regen = func(ctx context.Context, flagged []string) (string, error) {
+			return s.regenerateComment(ctx, userID, description, flagged)
+		}
+	}
+	return s.moderation.review(ctx, userID, source, roastPersona(ctx), comment, regen)
+}
+
+// saveModerationLogs 见 ModerationService.save，没有启用内容检查时忽略
+func (s *ExpenseService) saveModerationLogs(ctx context.Context, expenseID uint, logs []*model.ModerationLog) {
+	if s.moderation != nil {
+		s.moderation.save(ctx, expenseID, logs)
+	}
+}
+
+// regenerateComment 用同样的描述再调用一次模型，只取新的吐槽，金额分类等沿用第一次的结果
+// ctx 上已经挂着这次记账的模板、人设和实验分组；没通过的吐槽放进 "不要重复" 里
+func (s *ExpenseService) regenerateComment(ctx context.Context, userID string, description string, flagged []string) (string, error) {
+	style := prompt.StyleFromContext(ctx)
+	style.Avoid = slices.Concat(style.Avoid, flagged)
+	ctx = prompt.WithStyle(ctx, style)
+	ctx, user := s.withUser(ctx, userID)
+	ctx, provider := s.route(ctx, userID, user)
+	ctx, _ = llm.WithCallInfo(ctx)
+	ctx, trace := newTrace(ctx, model.AuditSourceModeration, description, nil, provider.Name())
+
+	stream, err := provider.AnalyzeExpense(ctx, description, model.PredefinedCategories, nil, true)
+	if err != nil {
+		return "", err
+	}
+	var fullJSON strings.Builder
+	for fragment := range stream.C {
+		fullJSON.WriteString(fragment)
+	}
+	if err := stream.Err(); err != nil {
+		return "", err
+	}
+	s.saveAudit(ctx, trace.audit(userID, 0, fullJSON.String()))
+	analysis, err := parseAnalysis(fullJSON.String(), true)
+	if err != nil {
+		return "", err
+	}
+	return analysis.Comment, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/notify"
+)
+
+const (
+	// 单次最多导入的通知条数，防止一次粘贴把 LLM 配额打爆
+	maxNotificationsPerImport = 100
+	// 同时进行分类的 LLM 请求数
+	notificationConcurrency = 4
+	// AI 不可用时的兜底分类
+	fallbackCategory = "其他消费"
+)
+
+// NotificationResult 单条通知的导入结果
+type NotificationResult struct {
+	Raw          string               `json:"raw"`
+	Notification *notify.Notification `json:"notification,omitempty"`
+	Expense      *model.ExpenseEntity `json:"expense,omitempty"`
+	Warning      string               `json:"warning,omitempty"`
+	Error        string               `json:"error,omitempty"`
+}
+
+// NotificationService 银行短信/支付通知导入
+// 金额、商户、尾号、时间全部由确定性模板提取，LLM 只负责分类和吐槽
+type NotificationService struct {
+	parser  *notify.Parser
+	expense *ExpenseService
+}
+
+// NewNotificationService 构造函数
+func NewNotificationService(parser *notify.Parser, expense *ExpenseService) *NotificationService {
+	return &NotificationService{
+		parser:  parser,
+		expense: expense,
+	}
+}
+
+// Import 解析用户粘贴的一批通知并逐条记账
+// 单条失败不影响其他条目，错误写在对应的 NotificationResult 里
+func (s *NotificationService) Import(ctx context.Context, userID string, text string) ([]NotificationResult, error) {
+	// 通知里只有 "10:30" 这类时刻时，按用户时区的今天补全日期
+	ctx, user := s.expense.withUser(ctx, userID)
+	now := s.expense.userNow(user, "")
+	messages := s.parser.Split(text, now)
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("没有可导入的通知")
+	}
+	if len(messages) > maxNotificationsPerImport {
+		return nil, fmt.Errorf("单次最多导入 %d 条通知，当前 %d 条", maxNotificationsPerImport, len(messages))
+	}
+
+	results := make([]NotificationResult, len(messages))
+	sem := make(chan struct{}, notificationConcurrency)
+	var wg sync.WaitGroup
+	for i, msg := range messages {
+		results[i].Raw = msg
+		n, err := s.parser.Parse(msg, now)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Notification = n
+
+		wg.Add(1)
+		go func(r *NotificationResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			s.importOne(ctx, userID, r, now)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	slog.Info("通知导入完成", "uid", userID, "total", len(messages))
+	return results, nil
+}
+
+func (s *NotificationService) importOne(ctx context.Context, userID string, r *NotificationResult, now time.Time) {
+	n := r.Notification
+	description := describeNotification(n)
+
+	// 1. 只让 LLM 做分类和吐槽，失败时兜底为"其他消费"，照样入账
+	category, note, comment := fallbackCategory, n.Merchant, ""
+	analysis, audit, err := s.expense.analyzeOnce(ctx, userID, description, true, now, 0, model.AuditSourceNotification)
+	if err != nil {
+		slog.Warn("通知分类失败，使用兜底分类", "uid", userID, "error", err)
+		r.Warning = "AI 分类失败，已归入" + fallbackCategory
+	} else {
+		category = analysis.Category
+		if analysis.Note != "" {
+			note = analysis.Note
+		}
+		comment = analysis.Comment
+	}
+
+	// 2. 金额和时间以通知为准，不信任模型的结果
+	expenseTime := n.Time
+	if expenseTime.IsZero() {
+		expenseTime = now
+	}
+	entity := &model.ExpenseEntity{
+		UserID:      userID,
+		Amount:      n.Amount,
+		Category:    category,
+		Note:        note,
+		Comment:     comment,
+		CreatedAt:   expenseTime,
+		Description: description,
+	}
+	if err := s.expense.createWithMemory(ctx, entity, description); err != nil {
+		r.Error = "保存失败: " + err.Error()
+		s.expense.saveAudit(ctx, audit)
+		return
+	}
+	if audit != nil {
+		audit.ExpenseID = entity.ID
+	}
+	s.expense.saveAudit(ctx, audit)
+	s.expense.recordRoast(ctx, entity, audit)
+	r.Expense = entity
+}
+
+// describeNotification 把结构化通知还原成一句自然语言，作为 LLM 输入和向量记忆文本
+func describeNotification(n *notify.Notification) string {
+	merchant := n.Merchant
+	if merchant == "" {
+		merchant = "未知商户"
+	}
+	return fmt.Sprintf("在%s消费%.2f元", merchant, n.Amount)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownPromptVersion 模板目录里没有这个版本
+	ErrUnknownPromptVersion = errors.New("Prompt 模板版本不存在")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+)
+
+// PromptService 决定每个用户使用哪个版本的 Prompt 模板
+type PromptService struct {
+	store *prompt.Store
+	users *repository.UserRepository
+}
+
+// NewPromptService 构造函数
+func NewPromptService(store *prompt.Store, users *repository.UserRepository) *PromptService {
+	return &PromptService{store: store, users: users}
+}
+
+// ForUser 这个用户使用的模板：管理员给他指定了版本且版本存在时用指定的，否则用默认版本
+// user 为这次请求已经查到的用户资料，为空时使用默认版本
+func (s *PromptService) ForUser(user *model.User) *prompt.Template {
+	if t := s.pinned(user); t != nil {
+		return t
+	}
+	return s.store.Default()
+}
+
+// pinned 管理员给这个用户指定的模板，没有指定时返回 nil
+func (s *PromptService) pinned(user *model.User) *prompt.Template {
+	if user == nil || user.PromptVersion == "" {
+		return nil
+	}
+	if t, ok := s.store.Get(user.PromptVersion); ok {
+		return t
+	}
+	// 模板文件被删掉了，不能因此记不了账
+	slog.Warn("用户指定的 Prompt 版本不存在，使用默认版本", "uid", user.ID, "version", user.PromptVersion)
+	return nil
+}
+
+// PromptVersions 可用的模板版本
+type PromptVersions struct {
+	Default  string   `json:"default"`
+	Versions []string `json:"versions"`
+}
+
+// Versions 列出可用的版本和默认版本
+func (s *PromptService) Versions() PromptVersions {
+	return PromptVersions{Default: s.store.Default().Version, Versions: s.store.Versions()}
+}
+
+// Get 查询某个版本的模板原文
+func (s *PromptService) Get(version string) (*prompt.Template, error) {
+	t, ok := s.store.Get(version)
+	if !ok {
+		return nil, ErrUnknownPromptVersion
+	}
+	return t, nil
+}
+
+// AssignUser 给用户指定模板版本，传空串恢复为默认版本
+func (s *PromptService) AssignUser(ctx context.Context, userID string, version string) error {
+	if version != "" {
+		if _, ok := s.store.Get(version); !ok {
+			return ErrUnknownPromptVersion
+		}
+	}
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return s.users.UpdatePromptVersion(ctx, userID, version)
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+)
+
+// 单次重新分析的账单数上限，每条都要调用一次模型
+const maxReanalyzeExpenses = 50
+
+// ReasonNoDescription 账单没有保存原始描述 (图片记账、账单导入或旧数据)，无法重新分析
+const ReasonNoDescription = "no_description"
+
+var (
+	// ErrReanalyzeEmpty 没有要重新分析或要应用的账单
+	ErrReanalyzeEmpty = errors.New("没有要重新分析的账单")
+	// ErrInvalidReanalyze 请求的账单或提交的修改不合法
+	ErrInvalidReanalyze = errors.New("重新分析的内容不合法")
+)
+
+// ReanalyzeValues 可以被重新分析改动的字段
+type ReanalyzeValues struct {
+	Amount   float64 `json:"amount"`
+	Category string  `json:"category"`
+	Note     string  `json:"note"`
+}
+
+// ReanalyzeDiff 一条账单重新分析的结果，Before 是库里的值，After 是模型这次给出的值
+// 日期不重新推断："昨天" 这类相对表达要以记账当时为准，库里的时间已经是解析好的结果
+type ReanalyzeDiff struct {
+	ExpenseID   uint            `json:"expense_id"`
+	Description string          `json:"description"`
+	Before      ReanalyzeValues `json:"before"`
+	After       ReanalyzeValues `json:"after"`
+	Changed     []string        `json:"changed"`            // 有变化的字段：amount / category / note
+	AuditID     uint            `json:"audit_id,omitempty"` // 这次识别的审计记录，应用时原样带回
+	// 识别失败的原因码和文案，取值同 SSE 的 error 事件，另有 no_description；有错误时 After 没有意义
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReanalyzeChange 用户确认要应用的修改，可以是预览结果原样提交，也可以是改过的
+type ReanalyzeChange struct {
+	ExpenseID uint    `json:"expense_id"`
+	Amount    float64 `json:"amount"`
+	Category  string  `json:"category"`
+	Note      string  `json:"note"`
+	AuditID   uint    `json:"audit_id,omitempty"` // 预览结果里的 audit_id，用于把审计记录关联到账单
+}
+
+// PreviewReanalyze 把账单的原始描述重新交给模型分析，返回与库里数据的差异，不落库
+// ids 不为空时按 ID 重新分析，否则按 filter 筛选 (忽略分页参数)；单条失败不影响其他条
+func (s *ExpenseService) PreviewReanalyze(ctx context.Context, userID string, ids []uint, filter repository.ExpenseFilter) ([]ReanalyzeDiff, error) {
+	expenses, err := s.reanalyzeTargets(ctx, userID, ids, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每一条都用这份用户资料，不再逐条查库
+	ctx, user := s.withUser(ctx, userID)
+	loc := s.locationOf(user)
+	diffs := make([]ReanalyzeDiff, len(expenses))
+	sem := make(chan struct{}, bulkConcurrency)
+	var wg sync.WaitGroup
+	for i := range expenses {
+		e := &expenses[i]
+		diffs[i] = ReanalyzeDiff{
+			ExpenseID:   e.ID,
+			Description: e.Description,
+			Before:      ReanalyzeValues{Amount: e.Amount, Category: e.Category, Note: e.Note},
+			Changed:     []string{},
+		}
+		if e.Description == "" {
+			diffs[i].ErrorCode, diffs[i].Error = ReasonNoDescription, "这笔账单没有保存原始描述，无法重新分析"
+			continue
+		}
+
+		wg.Add(1)
+		go func(d *ReanalyzeDiff, e *model.ExpenseEntity) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// 以记账当时为"今天"，描述里的相对日期才和当初一致
+			s.reanalyzeOne(ctx, userID, d, e.CreatedAt.In(loc))
+		}(&diffs[i], e)
+	}
+	wg.Wait()
+
+	slog.Info("重新分析预览完成", "uid", userID, "total", len(diffs))
+	return diffs, nil
+}
+
+// reanalyzeTargets 查出要重新分析的账单
+func (s *ExpenseService) reanalyzeTargets(ctx context.Context, userID string, ids []uint, filter repository.ExpenseFilter) ([]model.ExpenseEntity, error) {
+	if len(ids) > 0 {
+		if len(ids) > maxReanalyzeExpenses {
+			return nil, fmt.Errorf("%w: 单次最多 %d 条，当前 %d 条", ErrInvalidReanalyze, maxReanalyzeExpenses, len(ids))
+		}
+		expenses, err := s.repo.ListByIDs(ctx, userID, ids)
+		if err != nil {
+			return nil, err
+		}
+		if len(expenses) == 0 {
+			return nil, ErrReanalyzeEmpty
+		}
+		return expenses, nil
+	}
+
+	filter.UserID = userID
+	filter.Page, filter.PageSize = 1, maxReanalyzeExpenses
+	expenses, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, ErrReanalyzeEmpty
+	}
+	if total > maxReanalyzeExpenses {
+		return nil, fmt.Errorf("%w: 筛选结果共 %d 条，单次最多 %d 条，请缩小范围", ErrInvalidReanalyze, total, maxReanalyzeExpenses)
+	}
+	return expenses, nil
+}
+
+func (s *ExpenseService) reanalyzeOne(ctx context.Context, userID string, d *ReanalyzeDiff, now time.Time) {
+	// 排除这笔账单自己的记忆，否则模型会照抄旧的分类
+	analysis, audit, err := s.analyzeOnce(ctx, userID, d.Description, false, now, d.ExpenseID, model.AuditSourceReanalyze)
+	if audit != nil {
+		s.saveAudit(ctx, audit)
+		d.AuditID = audit.ID
+	}
+	if err != nil {
+		slog.Warn("重新分析失败", "uid", userID, "expense", d.ExpenseID, "error", err)
+		failure := NewStreamFailure(err)
+		d.ErrorCode, d.Error = failure.Code, failure.Message
+		return
+	}
+
+	d.After = ReanalyzeValues{Amount: analysis.Amount, Category: analysis.Category, Note: analysis.Note}
+	if math.Abs(d.After.Amount-d.Before.Amount) >= 0.005 {
+		d.Changed = append(d.Changed, "amount")
+	}
+	if d.After.Category != d.Before.Category {
+		d.Changed = append(d.Changed, "category")
+	}
+	if d.After.Note != d.Before.Note {
+		d.Changed = append(d.Changed, "note")
+	}
+}
+
+// ApplyReanalyze 在一个事务里应用用户确认的修改，全部成功或全部失败；向量记忆在提交后异步刷新
+func (s *ExpenseService) ApplyReanalyze(ctx context.Context, userID string, changes []ReanalyzeChange) ([]*model.ExpenseEntity, error) {
+	if len(changes) == 0 {
+		return nil, ErrReanalyzeEmpty
+	}
+	if len(changes) > maxReanalyzeExpenses {
+		return nil, fmt.Errorf("%w: 单次最多 %d 条，当前 %d 条", ErrInvalidReanalyze, maxReanalyzeExpenses, len(changes))
+	}
+
+	ids := make([]uint, len(changes))
+	for i, c := range changes {
+		// 同一笔账单出现两次时 UpdateBatch 会写两遍，以哪一条为准说不清
+		if j := slices.Index(ids[:i], c.ExpenseID); j >= 0 {
+			return nil, fmt.Errorf("%w: 第 %d 条和第 %d 条是同一笔账单: %d", ErrInvalidReanalyze, j+1, i+1, c.ExpenseID)
+		}
+		switch err := checkTextLength(c.Note, ""); {
+		case err != nil:
+			return nil, fmt.Errorf("%w: 第 %d 条%v", ErrInvalidReanalyze, i+1, err)
+		case c.Amount <= 0 || c.Amount > maxExpenseAmount:
+			return nil, fmt.Errorf("%w: 第 %d 条金额不合法", ErrInvalidReanalyze, i+1)
+		case !slices.Contains(model.PredefinedCategories, c.Category):
+			return nil, fmt.Errorf("%w: 第 %d 条分类不存在: %s", ErrInvalidReanalyze, i+1, c.Category)
+		}
+		ids[i] = c.ExpenseID
+	}
+
+	existing, err := s.repo.ListByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*model.ExpenseEntity, len(existing))
+	for i := range existing {
+		byID[existing[i].ID] = &existing[i]
+	}
+
+	entities := make([]*model.ExpenseEntity, len(changes))
+	texts := make([]string, len(changes))
+	for i, c := range changes {
+		e, ok := byID[c.ExpenseID]
+		if !ok {
+			// 不存在和不属于该用户不做区分
+			return nil, fmt.Errorf("%w: 第 %d 条账单不存在: %d", ErrInvalidReanalyze, i+1, c.ExpenseID)
+		}
+		e.Amount, e.Category, e.Note = c.Amount, c.Category, c.Note
+		entities[i] = e
+		texts[i] = e.Description
+		if texts[i] == "" {
+			texts[i] = e.Note
+		}
+	}
+
+	if err := s.repo.UpdateBatch(ctx, entities); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSaveFailed, err)
+	}
+	// 分类变了，记忆里的分类也要跟着变，否则之后的检索还会给模型旧答案
+	go saveMemories(s.embedder, s.memoryRepo, userID, entities, texts)
+
+	links := make(map[uint]uint)
+	for _, c := range changes {
+		if c.AuditID != 0 {
+			links[c.AuditID] = c.ExpenseID
+		}
+	}
+	s.linkAudits(ctx, userID, links)
+	// 用户改过预览给出的建议时记到这次重新分析的识别记录上，第一次记账的记录不受影响
+	for _, c := range changes {
+		if c.AuditID != 0 {
+			s.markAuditCorrected(ctx, c.AuditID, s.editedSuggestion(ctx, userID, c))
+		}
+	}
+
+	slog.Info("重新分析已应用", "uid", userID, "count", len(entities))
+	return entities, nil
+}
+
+// editedSuggestion 对比用户提交的值和预览时模型给出的建议，返回用户改过的字段
+// 审计记录不存在、不属于该用户或者没有关联到这笔账单时不比较
+func (s *ExpenseService) editedSuggestion(ctx context.Context, userID string, c ReanalyzeChange) []string {
+	if s.audits == nil {
+		return nil
+	}
+	audit, err := s.audits.Get(ctx, c.AuditID)
+	if err != nil || audit.UserID != userID || audit.ExpenseID != c.ExpenseID {
+		return nil
+	}
+	analysis, err := parseAnalysis(audit.RawOutput, false)
+	if err != nil {
+		return nil
+	}
+
+	var fields []string
+	if math.Abs(c.Amount-analysis.Amount) >= 0.005 {
+		fields = append(fields, "amount")
+	}
+	if c.Category != analysis.Category {
+		fields = append(fields, "category")
+	}
+	if c.Note != analysis.Note {
+		fields = append(fields, "note")
+	}
+	return fields
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+	"gorm.io/gorm"
+)
+
+// 放进 Prompt 的吐槽条数，多了费 token，模型也抓不住重点
+const (
+	recentRoastLimit   = 5 // 最近的吐槽，避免重复
+	dislikedRoastLimit = 5 // 被踩的吐槽，避免重复
+	likedRoastLimit    = 3 // 被赞的吐槽，作为风格示例
+)
+
+var (
+	// ErrExpenseNotFound 账单不存在或不属于该用户
+	ErrExpenseNotFound = errors.New("账单不存在")
+	// ErrNoComment 账单没有吐槽，无法反馈
+	ErrNoComment = errors.New("这笔账单没有吐槽")
+	// ErrInvalidFeedback 反馈只能是 1 (赞)、-1 (踩) 或 0 (撤销)
+	ErrInvalidFeedback = errors.New("反馈只能是 1 (赞)、-1 (踩) 或 0 (撤销)")
+)
+
+// RoastService 记录每条吐槽和用户的反馈，生成吐槽时用来避免重复、参考用户喜欢的风格
+type RoastService struct {
+	repo     repository.RoastRepo
+	expenses repository.ExpenseRepo
+}
+
+// NewRoastService 构造函数
+func NewRoastService(repo repository.RoastRepo, expenses repository.ExpenseRepo) *RoastService {
+	return &RoastService{repo: repo, expenses: expenses}
+}
+
+// roastPersona 这次调用的吐槽人设，不吐槽时为空
+func roastPersona(ctx context.Context) string {
+	style := prompt.StyleFromContext(ctx)
+	if style.Intensity == 0 {
+		return ""
+	}
+	return style.Persona.ID
+}
+
+// record 账单落库后记下它的吐槽；audit 是生成这笔账单的识别记录，用来取人设和 Prompt 版本
+// 只影响后续吐槽的去重，失败不影响记账
+func (s *RoastService) record(ctx context.Context, entity *model.ExpenseEntity, audit *model.AnalysisAudit) {
+	if entity == nil || entity.Comment == "" {
+		return
+	}
+	roast := &model.Roast{UserID: entity.UserID, ExpenseID: entity.ID, Comment: entity.Comment}
+	if audit != nil {
+		roast.Persona = audit.Persona
+		roast.PromptVersion = audit.PromptVersion
+	}
+	if err := s.repo.Create(context.WithoutCancel(ctx), roast); err != nil {
+		slog.Error("记录吐槽失败", "uid", entity.UserID, "expense", entity.ID, "error", err)
+	}
+}
+
+// recordRoast 见 RoastService.record，没有启用吐槽记录时忽略
+func (s *ExpenseService) recordRoast(ctx context.Context, entity *model.ExpenseEntity, audit *model.AnalysisAudit) {
+	if s.roasts != nil {
+		s.roasts.record(ctx, entity, audit)
+	}
+}
+
+// withMemory 给吐槽风格补上用户最近的、踩过的和赞过的吐槽
+func (s *RoastService) withMemory(ctx context.Context, userID string, style prompt.Style) prompt.Style {
+	liked, err := s.repo.ListByFeedback(ctx, userID, model.RoastLiked, likedRoastLimit)
+	if err != nil {
+		slog.Warn("查询赞过的吐槽失败", "uid", userID, "error", err)
+	}
+	recent, err := s.repo.Recent(ctx, userID, recentRoastLimit)
+	if err != nil {
+		slog.Warn("查询最近的吐槽失败", "uid", userID, "error", err)
+	}
+	disliked, err := s.repo.ListByFeedback(ctx, userID, model.RoastDisliked, dislikedRoastLimit)
+	if err != nil {
+		slog.Warn("查询踩过的吐槽失败", "uid", userID, "error", err)
+	}
+
+	for _, r := range liked {
+		style.Examples = append(style.Examples, r.Comment)
+	}
+	// 赞过的已经作为示例，不再要求避开；同一句只出现一次
+	for _, r := range slices.Concat(recent, disliked) {
+		if !slices.Contains(style.Examples, r.Comment) && !slices.Contains(style.Avoid, r.Comment) {
+			style.Avoid = append(style.Avoid, r.Comment)
+		}
+	}
+	return style
+}
+
+// Feedback 用户给某笔账单的吐槽点赞 (1)、点踩 (-1) 或撤销 (0)
+func (s *RoastService) Feedback(ctx context.Context, userID string, expenseID uint, feedback int) error {
+	if feedback < -1 || feedback > 1 {
+		return ErrInvalidFeedback
+	}
+	expense, err := s.expenses.GetByID(ctx, int64(expenseID))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && expense.UserID != userID) {
+		return ErrExpenseNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if expense.Comment == "" {
+		return ErrNoComment
+	}
+
+	roast, err := s.repo.GetByExpense(ctx, expenseID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 功能上线前的账单没有吐槽记录，补一条，人设和 Prompt 版本未知
+		roast = &model.Roast{UserID: userID, ExpenseID: expense.ID, Comment: expense.Comment, CreatedAt: expense.CreatedAt}
+		err = s.repo.Create(ctx, roast)
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.SetFeedback(ctx, roast.ID, int8(feedback), time.Now())
+}
+
+// Stats 按人设和 Prompt 版本汇总吐槽反馈
+func (s *RoastService) Stats(ctx context.Context) ([]repository.RoastStats, error) {
+	stats, err := s.repo.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		if voted := stats[i].Liked + stats[i].Disliked; voted > 0 {
+			stats[i].LikeRate = float64(stats[i].Liked) / float64(voted)
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/importer"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+)
+
+const (
+	// 每处理多少条记录提交一次 (同时也是断点粒度)
+	statementChunkSize = 200
+	// 同时运行的导入批次数
+	statementWorkers = 2
+)
+
+// ErrBatchBusy 批次正在运行，不能重复启动或回滚
+var ErrBatchBusy = errors.New("导入正在进行中，请稍后再试")
+
+// SaveProfile 新建或更新列映射方案
+func (s *ImportService) SaveProfile(ctx context.Context, userID string, profile *model.ImportProfile) error {
+	if profile.ID != 0 {
+		existing, err := s.importRepo.GetProfile(ctx, profile.ID)
+		if err != nil {
+			return fmt.Errorf("映射方案不存在: %w", err)
+		}
+		if existing.UserID != userID {
+			return fmt.Errorf("无权操作此映射方案")
+		}
+		profile.CreatedAt = existing.CreatedAt
+	}
+	if err := validateMapping(profile.Mapping); err != nil {
+		return err
+	}
+	profile.UserID = userID
+	return s.importRepo.SaveProfile(ctx, profile)
+}
+
+// ListProfiles 列出用户保存的映射方案
+func (s *ImportService) ListProfiles(ctx context.Context, userID string) ([]model.ImportProfile, error) {
+	return s.importRepo.ListProfiles(ctx, userID)
+}
+
+// DeleteProfile 删除映射方案 (带归属权校验)
+func (s *ImportService) DeleteProfile(ctx context.Context, userID string, id uint) error {
+	existing, err := s.importRepo.GetProfile(ctx, id)
+	if err != nil {
+		return fmt.Errorf("映射方案不存在: %w", err)
+	}
+	if existing.UserID != userID {
+		return fmt.Errorf("无权操作此映射方案")
+	}
+	return s.importRepo.DeleteProfile(ctx, id)
+}
+
+// StartStatementImport 创建一个异步导入批次
+// CSV 需要列映射：优先用 inline mapping，其次用已保存的 profileID；OFX/QIF 不需要
+func (s *ImportService) StartStatementImport(ctx context.Context, userID string, fileName string, data []byte, profileID uint, mapping *model.ColumnMapping) (*model.ImportBatch, error) {
+	format, err := importer.DetectFormat(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved model.ColumnMapping
+	switch {
+	case mapping != nil:
+		resolved = *mapping
+	case profileID != 0:
+		profile, err := s.importRepo.GetProfile(ctx, profileID)
+		if err != nil || profile.UserID != userID {
+			return nil, fmt.Errorf("映射方案不存在")
+		}
+		resolved = profile.Mapping
+	case format == importer.FormatCSV:
+		return nil, fmt.Errorf("CSV 导入需要指定列映射或映射方案")
+	}
+
+	// 先同步解析一遍，映射写错了立刻告诉用户，而不是等后台任务失败
+	records, err := importer.ParseStatement(format, data, resolved, s.users.Location(ctx, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &model.ImportBatch{
+		UserID:   userID,
+		Format:   format,
+		FileName: fileName,
+		Mapping:  resolved,
+		Payload:  data,
+		Status:   model.ImportBatchPending,
+		Total:    len(records),
+	}
+	if err := s.importRepo.CreateBatch(ctx, batch); err != nil {
+		return nil, fmt.Errorf("创建导入批次失败: %w", err)
+	}
+	slog.Info("创建导入批次", "uid", userID, "batch", batch.ID, "format", format, "total", batch.Total)
+
+	s.enqueue(batch.ID)
+	return batch, nil
+}
+
+// GetBatch 查询批次进度 (带归属权校验)
+func (s *ImportService) GetBatch(ctx context.Context, userID string, id uint) (*model.ImportBatch, error) {
+	batch, err := s.importRepo.GetBatchMeta(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("导入批次不存在: %w", err)
+	}
+	if batch.UserID != userID {
+		return nil, fmt.Errorf("无权查看此导入批次")
+	}
+	return batch, nil
+}
+
+// ListBatches 最近的导入批次
+func (s *ImportService) ListBatches(ctx context.Context, userID string) ([]model.ImportBatch, error) {
+	return s.importRepo.ListBatches(ctx, userID, 50)
+}
+
+// ResumeBatch 从断点继续一个失败的批次
+func (s *ImportService) ResumeBatch(ctx context.Context, userID string, id uint) (*model.ImportBatch, error) {
+	batch, err := s.GetBatch(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, running := s.running.Load(id); running {
+		return nil, ErrBatchBusy
+	}
+	if batch.Status != model.ImportBatchFailed && batch.Status != model.ImportBatchPending {
+		return nil, fmt.Errorf("当前状态 %s 不能继续导入", batch.Status)
+	}
+
+	batch.Status = model.ImportBatchPending
+	batch.Error = ""
+	if err := s.importRepo.UpdateBatchProgress(ctx, batch); err != nil {
+		return nil, err
+	}
+	s.enqueue(batch.ID)
+	return batch, nil
+}
+
+// RollbackBatch 撤销整个批次：删除这批导入的所有账单和对应的记忆
+func (s *ImportService) RollbackBatch(ctx context.Context, userID string, id uint) (*model.ImportBatch, error) {
+	batch, err := s.GetBatch(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, running := s.running.Load(id); running {
+		return nil, ErrBatchBusy
+	}
+	if batch.Status == model.ImportBatchRolledBack {
+		return nil, fmt.Errorf("该批次已经回滚过了")
+	}
+
+	batch.Status = model.ImportBatchRolledBack
+	now := time.Now()
+	batch.FinishedAt = &now
+	ids, err := s.importRepo.RollbackBatch(ctx, batch)
+	if err != nil {
+		return nil, fmt.Errorf("回滚失败: %w", err)
+	}
+	slog.Info("导入批次已回滚", "uid", userID, "batch", id, "deleted", len(ids))
+
+	go func() {
+		bgCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.memoryRepo.DeleteMany(bgCtx, ids); err != nil {
+			slog.Error("回滚时删除记忆失败", "batch", id, "error", err)
+		}
+	}()
+	return batch, nil
+}
+
+// ResumeUnfinished 服务启动时调用，把上次没跑完的批次重新排队
+func (s *ImportService) ResumeUnfinished(ctx context.Context) {
+	batches, err := s.importRepo.ListUnfinishedBatches(ctx)
+	if err != nil {
+		slog.Error("查询未完成的导入批次失败", "error", err)
+		return
+	}
+	for _, b := range batches {
+		slog.Info("恢复导入批次", "batch", b.ID, "processed", b.Processed, "total", b.Total)
+		s.enqueue(b.ID)
+	}
+}
+
+// enqueue 异步执行批次，同一批次同时只会有一个 goroutine 在跑
+func (s *ImportService) enqueue(batchID uint) {
+	if _, loaded := s.running.LoadOrStore(batchID, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer s.running.Delete(batchID)
+		s.workers <- struct{}{}
+		defer func() { <-s.workers }()
+		s.runBatch(batchID)
+	}()
+}
+
+func (s *ImportService) runBatch(batchID uint) {
+	ctx := context.Background()
+	batch, err := s.importRepo.GetBatch(ctx, batchID)
+	if err != nil {
+		slog.Error("读取导入批次失败", "batch", batchID, "error", err)
+		return
+	}
+	if batch.Status != model.ImportBatchPending && batch.Status != model.ImportBatchRunning {
+		return
+	}
+
+	fail := func(err error) {
+		slog.Error("导入批次失败", "batch", batchID, "processed", batch.Processed, "error", err)
+		batch.Status = model.ImportBatchFailed
+		batch.Error = err.Error()
+		if err := s.importRepo.UpdateBatchProgress(ctx, batch); err != nil {
+			slog.Error("更新导入批次状态失败", "batch", batchID, "error", err)
+		}
+	}
+
+	records, err := importer.ParseStatement(batch.Format, batch.Payload, batch.Mapping, s.users.Location(ctx, batch.UserID))
+	if err != nil {
+		fail(err)
+		return
+	}
+	batch.Status = model.ImportBatchRunning
+	batch.Total = len(records)
+	if err := s.importRepo.UpdateBatchProgress(ctx, batch); err != nil {
+		fail(err)
+		return
+	}
+
+	for start := batch.Processed; start < len(records); start += statementChunkSize {
+		chunk := records[start:min(start+statementChunkSize, len(records))]
+		next, entities, err := s.processChunk(ctx, *batch, chunk)
+		if err != nil {
+			fail(err)
+			return
+		}
+		next.Processed = start + len(chunk)
+		// 账单和断点在同一个事务里提交
+		if err := s.importRepo.CommitChunk(ctx, &next, entities); err != nil {
+			fail(err)
+			return
+		}
+		*batch = next
+		if len(entities) > 0 {
+			go s.saveMemories(batch.UserID, entities)
+		}
+	}
+
+	now := time.Now()
+	batch.Status = model.ImportBatchCompleted
+	batch.FinishedAt = &now
+	if err := s.importRepo.UpdateBatchProgress(ctx, batch); err != nil {
+		slog.Error("更新导入批次状态失败", "batch", batchID, "error", err)
+	}
+	slog.Info("导入批次完成", "batch", batchID, "imported", batch.Imported, "duplicates", batch.Duplicates, "skipped", batch.Skipped)
+}
+
+// processChunk 过滤、去重、分类一段记录，返回更新后的批次计数和待写入的账单
+func (s *ImportService) processChunk(ctx context.Context, batch model.ImportBatch, chunk []importer.Record) (model.ImportBatch, []*model.ExpenseEntity, error) {
+	var candidates []importer.Record
+	var fingerprints []string
+	for _, rec := range chunk {
+		if rec.SkipReason() != "" {
+			batch.Skipped++
+			continue
+		}
+		candidates = append(candidates, rec)
+		fingerprints = append(fingerprints, rec.TradeNo)
+	}
+
+	existing, err := s.repo.ExistingExternalIDs(ctx, batch.UserID, importer.SourceStatement, fingerprints)
+	if err != nil {
+		return batch, nil, fmt.Errorf("查询已导入记录失败: %w", err)
+	}
+	var fresh []importer.Record
+	for _, rec := range candidates {
+		if existing[rec.TradeNo] {
+			batch.Duplicates++
+			continue
+		}
+		fresh = append(fresh, rec)
+	}
+
+	items := s.categorize(ctx, fresh)
+	entities := make([]*model.ExpenseEntity, len(fresh))
+	for i, rec := range fresh {
+		entities[i] = &model.ExpenseEntity{
+			UserID:        batch.UserID,
+			Amount:        rec.Amount,
+			Category:      items[i].Category,
+			Note:          billNote(rec),
+			CreatedAt:     rec.Time,
+			Source:        rec.Source,
+			ExternalID:    rec.TradeNo,
+			ImportBatchID: batch.ID,
+		}
+	}
+	batch.Imported += len(entities)
+	return batch, entities, nil
+}
+
+// validateMapping CSV 映射至少要有日期列和金额列
+func validateMapping(m model.ColumnMapping) error {
+	if m.DateColumn == "" {
+		return fmt.Errorf("列映射缺少日期列")
+	}
+	if m.AmountColumn == "" && m.DebitColumn == "" {
+		return fmt.Errorf("列映射缺少金额列或支出列")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/leon37/FaceTaxLedger/internal/infrastructure/llm"
+)
+
+// 记账失败的原因码，随 SSE error 事件返回，前端据此决定提示文案和是否允许重试
+const (
+	ReasonTimeout             = "timeout"              // 模型响应超时
+	ReasonCanceled            = "canceled"             // 请求被取消
+	ReasonProviderUnavailable = "provider_unavailable" // 所有模型后端都不可用 (失败或熔断中)
+	ReasonProviderError       = "provider_error"       // 模型输出到一半断了
+	ReasonInvalidOutput       = "invalid_output"       // 模型输出不是合法的记账结果
+	ReasonSaveFailed          = "save_failed"          // 落库失败
+)
+
+var reasonMessages = map[string]string{
+	ReasonTimeout:             "AI 想太久了，请稍后再试",
+	ReasonCanceled:            "请求已取消",
+	ReasonProviderUnavailable: "AI 大脑短路了，请稍后再试",
+	ReasonProviderError:       "AI 说到一半掉线了，这笔没有记上，请重试",
+	ReasonInvalidOutput:       "AI 没看懂这笔账，换个说法试试",
+	ReasonSaveFailed:          "账单保存失败，请稍后再试",
+}
+
+var (
+	// ErrInvalidOutput 模型输出的 JSON 无法解析
+	ErrInvalidOutput = errors.New("模型输出无法解析")
+	// ErrSaveFailed 账单落库失败
+	ErrSaveFailed = errors.New("账单保存失败")
+)
+
+// StreamFailure SSE error 事件的数据
+type StreamFailure struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewStreamFailure 把错误归类为原因码，Message 是给用户看的文案，不暴露内部错误
+func NewStreamFailure(err error) StreamFailure {
+	code := ReasonProviderError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		code = ReasonTimeout
+	case errors.Is(err, context.Canceled):
+		code = ReasonCanceled
+	case errors.Is(err, llm.ErrNoProviderAvailable):
+		code = ReasonProviderUnavailable
+	case errors.Is(err, ErrInvalidOutput), errors.Is(err, llm.ErrEmptyOutput):
+		code = ReasonInvalidOutput
+	case errors.Is(err, ErrSaveFailed):
+		code = ReasonSaveFailed
+	}
+	return StreamFailure{Code: code, Message: reasonMessages[code]}
+}
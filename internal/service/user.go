@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/leon37/FaceTaxLedger/internal/calendar"
+	"github.com/leon37/FaceTaxLedger/internal/model"
+	"github.com/leon37/FaceTaxLedger/internal/prompt"
+	"github.com/leon37/FaceTaxLedger/internal/repository"
+)
+
+var (
+	// ErrInvalidTimeZone 不是合法的 IANA 时区名
+	ErrInvalidTimeZone = errors.New("无效的时区，请使用 IANA 时区名，例如 Asia/Shanghai")
+	// ErrUnknownPersona 没有这个吐槽人设
+	ErrUnknownPersona = errors.New("吐槽人设不存在")
+	// ErrInvalidIntensity 吐槽力度超出范围
+	ErrInvalidIntensity = fmt.Errorf("吐槽力度必须在 0 到 %d 之间", prompt.MaxIntensity)
+)
+
+// UserService 用户资料：时区、吐槽人设和力度
+type UserService struct {
+	userRepo *repository.UserRepository
+	loc      *time.Location // 用户没有设置时区时使用
+}
+
+// NewUserService 构造函数，loc 为空时使用 calendar.DefaultTimeZone
+func NewUserService(userRepo *repository.UserRepository, loc *time.Location) *UserService {
+	if loc == nil {
+		loc = calendar.Location(calendar.DefaultTimeZone, time.Local)
+	}
+	return &UserService{userRepo: userRepo, loc: loc}
+}
+
+// GetProfile 查询用户资料，未设置时区时返回生效的默认时区
+func (s *UserService) GetProfile(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TimeZone == "" {
+		user.TimeZone = s.loc.String()
+	}
+	if user.RoastPersona == "" {
+		user.RoastPersona = prompt.DefaultPersona
+	}
+	return user, nil
+}
+
+// UpdateTimeZone 设置用户时区，传空串恢复为服务端默认时区
+func (s *UserService) UpdateTimeZone(ctx context.Context, userID string, timeZone string) error {
+	if timeZone != "" {
+		if _, err := time.LoadLocation(timeZone); err != nil {
+			return ErrInvalidTimeZone
+		}
+	}
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.userRepo.UpdateTimeZone(ctx, userID, timeZone)
+}
+
+// Location 用户所在时区，查不到用户或没有设置时返回默认时区
+// 当天、当月的边界都按它计算
+func (s *UserService) Location(ctx context.Context, userID string) *time.Location {
+	return s.locationOf(s.lookup(ctx, userID))
+}
+
+// lookup 查询用户资料，查不到时返回 nil，调用方按默认设置处理
+func (s *UserService) lookup(ctx context.Context, userID string) *model.User {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		slog.Warn("查询用户资料失败，使用默认设置", "uid", userID, "error", err)
+		return nil
+	}
+	return user
+}
+
+// locationOf 用户资料里的时区，user 为空或没有设置时返回默认时区
+func (s *UserService) locationOf(user *model.User) *time.Location {
+	if user == nil {
+		return s.loc
+	}
+	return calendar.Location(user.TimeZone, s.loc)
+}
+
+// UpdateRoast 设置吐槽人设和力度，persona 传空串恢复默认人设，intensity 为 0 表示不吐槽
+func (s *UserService) UpdateRoast(ctx context.Context, userID string, persona string, intensity int) error {
+	if persona != "" {
+		if _, ok := prompt.LookupPersona(persona); !ok {
+			return ErrUnknownPersona
+		}
+	}
+	if intensity < 0 || intensity > prompt.MaxIntensity {
+		return ErrInvalidIntensity
+	}
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.userRepo.UpdateRoast(ctx, userID, persona, intensity)
+}
+
+// styleOf 用户的吐槽风格，user 为空时使用默认风格
+func styleOf(user *model.User) prompt.Style {
+	if user == nil {
+		return prompt.DefaultStyle()
+	}
+	return prompt.NewStyle(user.RoastPersona, user.RoastIntensity)
+}
@@ -0,0 +1,44 @@
+package sheet
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	// 不带 BOM 的话 Excel 会按 GBK 打开，中文全是乱码
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) WriteRow(cells ...any) error {
+	record := make([]string, len(cells))
+	for i, v := range cells {
+		record[i] = cellText(v)
+		if _, ok := v.(string); ok {
+			record[i] = escapeFormula(record[i])
+		}
+	}
+	return c.w.Write(record)
+}
+
+// escapeFormula 备注、吐槽来自用户和模型，以 = + - @ 开头的文本会被 Excel 当成公式执行，前面加一个 ' 按文本显示
+// 只处理字符串单元格，金额等数字照常输出
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,56 @@
+package sheet
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// RowWriter 逐行写表格，写完必须 Close 才会把尾部数据刷出去
+// 单元格支持 string / float64 / int / uint / time.Time，其它类型按 fmt.Sprint 转成文本
+type RowWriter interface {
+	WriteRow(cells ...any) error
+	Close() error
+}
+
+// NewWriter 按格式创建 RowWriter，sheetName 只对 XLSX 生效
+func NewWriter(w io.Writer, format string, sheetName string) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatXLSX:
+		return newXLSXWriter(w, sheetName)
+	}
+	return nil, fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+// ContentType 各格式的 MIME 类型
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// 表格里的时间统一用这个格式显示
+const timeLayout = "2006-01-02 15:04:05"
+
+func cellText(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case time.Time:
+		return x.Format(timeLayout)
+	case float64:
+		return fmt.Sprintf("%.2f", x)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,197 @@
+// Package sse 把一次流式分析放到服务端作为任务运行，事件缓存在内存里
+// 客户端网络抖动断开后可以带着 Last-Event-ID 重连，补发错过的事件和最终结果
+package sse
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 默认参数
+const (
+	DefaultHeartbeat   = 15 * time.Second
+	DefaultReplayTTL   = 5 * time.Minute
+	DefaultResumeGrace = 30 * time.Second
+)
+
+// ErrJobNotFound 任务不存在、已过期或不属于该用户
+var ErrJobNotFound = errors.New("任务不存在或已过期")
+
+// Config 留空使用默认值
+type Config struct {
+	Heartbeat   time.Duration // 心跳注释的间隔
+	ReplayTTL   time.Duration // 任务结束后事件保留多久
+	ResumeGrace time.Duration // 所有客户端都断开后，任务继续跑多久等待重连
+}
+
+// Event 一条缓存的事件，Seq 从 1 开始在任务内递增
+type Event struct {
+	Seq  int
+	Name string
+	Data string
+}
+
+// Emit 任务产出事件的回调
+type Emit func(name string, data string)
+
+// Hub 管理进行中和刚结束的任务
+type Hub struct {
+	conf Config
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func NewHub(conf Config) *Hub {
+	if conf.Heartbeat <= 0 {
+		conf.Heartbeat = DefaultHeartbeat
+	}
+	if conf.ReplayTTL <= 0 {
+		conf.ReplayTTL = DefaultReplayTTL
+	}
+	if conf.ResumeGrace <= 0 {
+		conf.ResumeGrace = DefaultResumeGrace
+	}
+	return &Hub{conf: conf, jobs: make(map[string]*Job)}
+}
+
+// Heartbeat 心跳间隔
+func (h *Hub) Heartbeat() time.Duration {
+	return h.conf.Heartbeat
+}
+
+// Start 启动一个任务
+// run 使用的 ctx 与发起请求的连接解绑 (保留其中的值)，客户端断开不会立刻取消；
+// 只有所有客户端断开超过 ResumeGrace 仍没有重连时才会取消
+// 返回的任务已经有一个订阅者，调用方读完后必须 Detach
+func (h *Hub) Start(parent context.Context, userID string, run func(ctx context.Context, emit Emit)) *Job {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	job := &Job{
+		ID:       uuid.NewString(),
+		UserID:   userID,
+		cancel:   cancel,
+		changed:  make(chan struct{}),
+		watchers: 1,
+	}
+
+	h.mu.Lock()
+	h.jobs[job.ID] = job
+	h.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		run(ctx, job.append)
+		job.finish()
+		// 结束后保留一段时间供重连补发，timer 不占 goroutine
+		time.AfterFunc(h.conf.ReplayTTL, func() {
+			h.mu.Lock()
+			delete(h.jobs, job.ID)
+			h.mu.Unlock()
+		})
+	}()
+	return job
+}
+
+// Attach 重连时取回任务并登记订阅者，用完后必须 Detach
+func (h *Hub) Attach(jobID string, userID string) (*Job, error) {
+	h.mu.Lock()
+	job, ok := h.jobs[jobID]
+	h.mu.Unlock()
+	if !ok || job.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.watchers++
+	if job.idle != nil {
+		job.idle.Stop()
+		job.idle = nil
+	}
+	return job, nil
+}
+
+// Detach 订阅者离开；最后一个离开且任务还没结束时，开始等待重连
+func (h *Hub) Detach(job *Job) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.watchers--
+	if job.watchers > 0 || job.finished {
+		return
+	}
+	job.idle = time.AfterFunc(h.conf.ResumeGrace, job.cancel)
+}
+
+// Job 一次服务端分析任务
+type Job struct {
+	ID     string
+	UserID string
+
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	events   []Event
+	finished bool
+	changed  chan struct{} // 有新事件或任务结束时关闭并换一个新的
+	watchers int
+	idle     *time.Timer
+}
+
+// Since 返回 after 之后的事件、任务是否已经结束，以及下一次变化的通知
+// 任务结束时返回的事件就是全部剩余事件
+func (j *Job) Since(after int) ([]Event, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var events []Event
+	if after < len(j.events) {
+		events = append(events, j.events[max(after, 0):]...)
+	}
+	return events, j.finished, j.changed
+}
+
+// EventID SSE id 字段的值，格式为 任务ID:序号，重连时原样放进 Last-Event-ID
+func (j *Job) EventID(seq int) string {
+	return j.ID + ":" + strconv.Itoa(seq)
+}
+
+func (j *Job) append(name string, data string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.finished {
+		return
+	}
+	j.events = append(j.events, Event{Seq: len(j.events) + 1, Name: name, Data: data})
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func (j *Job) finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished = true
+	if j.idle != nil {
+		j.idle.Stop()
+		j.idle = nil
+	}
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// ParseEventID 解析 Last-Event-ID，返回任务ID和已经收到的最后序号
+func ParseEventID(id string) (string, int, bool) {
+	jobID, seqStr, ok := strings.Cut(strings.TrimSpace(id), ":")
+	if !ok || jobID == "" {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(seqStr)
+	if err != nil || seq < 0 {
+		return "", 0, false
+	}
+	return jobID, seq, true
+}
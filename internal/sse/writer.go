@@ -0,0 +1,31 @@
+package sse
+
+import (
+	"io"
+	"strings"
+)
+
+// WriteEvent 按 SSE 格式写一条带 id 的事件，格式与 gin 的 SSEvent 保持一致 (冒号后不加空格)
+// data 中的换行拆成多行 data，客户端按规范会重新用 \n 拼回去
+func WriteEvent(w io.Writer, id string, name string, data string) error {
+	var b strings.Builder
+	b.WriteString("id:")
+	b.WriteString(id)
+	b.WriteString("\nevent:")
+	b.WriteString(name)
+	b.WriteString("\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data:")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteComment 写一行注释，客户端会忽略，用作心跳防止代理和移动网络断开空闲连接
+func WriteComment(w io.Writer, text string) error {
+	_, err := io.WriteString(w, ": "+text+"\n\n")
+	return err
+}